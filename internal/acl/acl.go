@@ -0,0 +1,138 @@
+package acl
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// one line of a list, prefix is unset for * which matches every address
+type rule struct {
+	allow  bool
+	any    bool
+	prefix netip.Prefix
+}
+
+// ordered allow and deny rules, the first rule matching an address decides and nothing matching denies
+type List struct {
+	rules []rule
+}
+
+// compiles rules written as an ip, a cidr range, @group or *, a leading ! turns a rule into a deny
+// groups name lists of ips and cidr ranges so the same addresses can be shared between lists
+func Parse(entries []string, groups map[string][]string) (*List, error) {
+	list := &List{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		allow := !strings.HasPrefix(entry, "!")
+		target := strings.TrimSpace(strings.TrimPrefix(entry, "!"))
+
+		switch {
+		case target == "*":
+			list.rules = append(list.rules, rule{allow: allow, any: true})
+		case strings.HasPrefix(target, "@"):
+			members, ok := groups[target[1:]]
+			if !ok {
+				return nil, fmt.Errorf("unknown group %q", target[1:])
+			}
+			for _, member := range members {
+				prefix, err := parsePrefix(member)
+				if err != nil {
+					return nil, fmt.Errorf("group %s: %w", target[1:], err)
+				}
+				list.rules = append(list.rules, rule{allow: allow, prefix: prefix})
+			}
+		default:
+			prefix, err := parsePrefix(target)
+			if err != nil {
+				return nil, err
+			}
+			list.rules = append(list.rules, rule{allow: allow, prefix: prefix})
+		}
+	}
+	return list, nil
+}
+
+// reports whether the first rule matching addr allows it, invalid addresses are always denied
+func (l *List) Allows(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, rule := range l.rules {
+		if rule.any || rule.prefix.Contains(addr) {
+			return rule.allow
+		}
+	}
+	return false
+}
+
+// accepts an ip, which matches only itself, or a cidr range of either family
+func parsePrefix(raw string) (netip.Prefix, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.Contains(raw, "/") {
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid cidr range %q", raw)
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			// ::ffff:10.0.0.0/104 is how some tools write 10.0.0.0/8
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid ip %q", raw)
+	}
+	addr = addr.Unmap().WithZone("")
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// the lists of every path prefix that is restricted, routes without a list are open to everyone
+type Policy struct {
+	// longest prefix first so the most specific list wins
+	scopes []scope
+}
+
+type scope struct {
+	prefix string
+	list   *List
+}
+
+// compiles a list for every path prefix, prefixes with no rules are left unrestricted
+func ParsePolicy(lists map[string][]string, groups map[string][]string) (*Policy, error) {
+	policy := &Policy{}
+	for prefix, entries := range lists {
+		if len(entries) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("%q must be a path starting with /", prefix)
+		}
+		list, err := Parse(entries, groups)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", prefix, err)
+		}
+		policy.scopes = append(policy.scopes, scope{prefix: strings.TrimSuffix(prefix, "/"), list: list})
+	}
+	sort.Slice(policy.scopes, func(i, j int) bool {
+		return len(policy.scopes[i].prefix) > len(policy.scopes[j].prefix)
+	})
+	return policy, nil
+}
+
+// returns the list that applies to path or nil when the path isn't restricted
+// prefixes match whole segments, /admin covers /admin/jobs but not /administrator
+func (p *Policy) For(path string) *List {
+	if p == nil {
+		return nil
+	}
+	for _, scope := range p.scopes {
+		if path == scope.prefix || strings.HasPrefix(path, scope.prefix+"/") || scope.prefix == "" {
+			return scope.list
+		}
+	}
+	return nil
+}
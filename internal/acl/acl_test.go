@@ -0,0 +1,86 @@
+package acl
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListAllows_WhenRulesMixFamiliesAndDenies_FirstMatchDecides(t *testing.T) {
+	// Arrange
+	list, err := Parse([]string{"!10.0.0.13", "10.0.0.0/8", "2001:db8::/32", "::1"}, nil)
+	require.NoError(t, err)
+
+	// Act
+	allowed := map[string]bool{}
+	for _, addr := range []string{"10.0.0.13", "10.1.2.3", "::ffff:10.1.2.3", "2001:db8::7", "::1", "192.168.1.1", "2001:db9::1"} {
+		allowed[addr] = list.Allows(netip.MustParseAddr(addr))
+	}
+
+	// Assert
+	assert.Equal(t, map[string]bool{
+		"10.0.0.13":       false,
+		"10.1.2.3":        true,
+		"::ffff:10.1.2.3": true,
+		"2001:db8::7":     true,
+		"::1":             true,
+		"192.168.1.1":     false,
+		"2001:db9::1":     false,
+	}, allowed)
+	assert.False(t, list.Allows(netip.Addr{}))
+}
+
+func TestParse_WhenGroupReferenced_ExpandsItsMembers(t *testing.T) {
+	// Arrange
+	groups := map[string][]string{"office": {"198.51.100.0/24", "2001:db8:1::/48"}}
+
+	// Act
+	list, err := Parse([]string{"!@office", "*"}, groups)
+
+	// Assert
+	require.NoError(t, err)
+	assert.False(t, list.Allows(netip.MustParseAddr("198.51.100.4")))
+	assert.False(t, list.Allows(netip.MustParseAddr("2001:db8:1::9")))
+	assert.True(t, list.Allows(netip.MustParseAddr("203.0.113.1")))
+}
+
+func TestParse_WhenRuleInvalid_ReturnsError(t *testing.T) {
+	// Arrange
+	cases := [][]string{{"10.0.0.0/33"}, {"not-an-ip"}, {"@missing"}}
+
+	for _, entries := range cases {
+		// Act
+		_, err := Parse(entries, map[string][]string{})
+
+		// Assert
+		assert.Error(t, err, entries)
+	}
+}
+
+func TestPolicyFor_WhenPrefixesNest_UsesLongestOnSegmentBoundaries(t *testing.T) {
+	// Arrange
+	policy, err := ParsePolicy(map[string][]string{
+		"/admin":          {"10.0.0.0/8"},
+		"/admin/api-keys": {"10.0.0.1"},
+		"/api/v2/":        {"*"},
+		"/metrics":        nil,
+	}, nil)
+	require.NoError(t, err)
+	office := netip.MustParseAddr("10.0.0.2")
+
+	// Act
+	jobs := policy.For("/admin/jobs")
+	keys := policy.For("/admin/api-keys/abc/usage")
+
+	// Assert
+	require.NotNil(t, jobs)
+	require.NotNil(t, keys)
+	assert.True(t, jobs.Allows(office))
+	assert.False(t, keys.Allows(office))
+	assert.NotNil(t, policy.For("/api/v2/reforges"))
+	assert.Nil(t, policy.For("/administrator"))
+	assert.Nil(t, policy.For("/metrics"))
+	assert.Nil(t, (*Policy)(nil).For("/admin"))
+}
@@ -0,0 +1,109 @@
+package apikeys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"yard-backend/internal/models"
+)
+
+// request header clients send their key in
+const Header = "X-API-Key"
+
+// tiers decide the rate limit budget, requests without a key are anonymous
+const (
+	TierAnonymous = "anonymous"
+	TierFree      = "free"
+	TierPartner   = "partner"
+)
+
+// usage is kept this long and reports can't reach further back
+const UsageRetention = 90 * 24 * time.Hour
+
+// every token starts with this so leaked keys are easy to recognise in logs and scanners
+const tokenPrefix = "yard_"
+
+// returned for unknown keys and for keys that were revoked
+var ErrNotFound = errors.New("api key not found")
+
+// keeps issued keys and their usage, tokens are only ever stored as their hash
+type Store interface {
+	// saves a new key under the hash of its token
+	Create(ctx context.Context, key models.APIKey, hash string) error
+	// returns the active key whose token has the given hash
+	Lookup(ctx context.Context, hash string) (models.APIKey, error)
+	// returns a key by id, revoked keys included
+	Get(ctx context.Context, id string) (models.APIKey, error)
+	// returns every key oldest first
+	List(ctx context.Context) ([]models.APIKey, error)
+	// marks a key revoked, revoking it again keeps the first time
+	Revoke(ctx context.Context, id string, at time.Time) (models.APIKey, error)
+	// counts one request of a key, rejected requests don't use any units
+	RecordUsage(ctx context.Context, id, route string, units int, rejected bool, at time.Time) error
+	// returns the usage of the days between from and to newest first, days without requests are left out
+	Usage(ctx context.Context, id string, from, to time.Time) ([]models.APIKeyUsage, error)
+}
+
+// reports whether keys can be issued for tier, anonymous is what requests without a key get
+func Issuable(tier string) bool {
+	return tier == TierFree || tier == TierPartner
+}
+
+// creates a key and returns it with its token, the token can't be recovered afterwards
+func Issue(ctx context.Context, store Store, name, tier string, now time.Time) (models.APIKey, string, error) {
+	key := models.APIKey{
+		ID:        randomHex(8),
+		Name:      name,
+		Tier:      tier,
+		CreatedAt: now.UTC(),
+	}
+	token := tokenPrefix + randomHex(24)
+	if err := store.Create(ctx, key, Hash(token)); err != nil {
+		return models.APIKey{}, "", fmt.Errorf("saving api key: %w", err)
+	}
+	return key, token, nil
+}
+
+// returns the hex sha-256 of a token, tokens are long and random so no salt or slow hash is needed
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// utc dates between from and to newest first, usage is bucketed by day
+func days(from, to time.Time) []string {
+	from = from.UTC().Truncate(24 * time.Hour)
+	var dates []string
+	for day := to.UTC().Truncate(24 * time.Hour); !day.Before(from); day = day.AddDate(0, 0, -1) {
+		dates = append(dates, day.Format(time.DateOnly))
+	}
+	return dates
+}
+
+// adds one request to a day of usage
+func addUsage(day *models.APIKeyUsage, route string, units int, rejected bool) {
+	if rejected {
+		day.Rejected++
+		return
+	}
+	if day.Routes == nil {
+		day.Routes = make(map[string]models.RouteUsage)
+	}
+	day.Requests++
+	day.Units += int64(units)
+	routeUsage := day.Routes[route]
+	routeUsage.Requests++
+	routeUsage.Units += int64(units)
+	day.Routes[route] = routeUsage
+}
@@ -0,0 +1,73 @@
+package apikeys
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssue_WhenKeyCreated_OnlyItsHashIsStored(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewMemory()
+
+	// Act
+	key, token, err := Issue(ctx, store, "community site", TierFree, time.Now())
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "yard_"))
+	assert.NotContains(t, store.hashes, token)
+	found, err := store.Lookup(ctx, Hash(token))
+	require.NoError(t, err)
+	assert.Equal(t, key, found)
+}
+
+func TestMemoryRevoke_WhenRevokedTwice_KeepsFirstTimeAndRejectsLookups(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewMemory()
+	key, token, err := Issue(ctx, store, "leaked", TierPartner, time.Now())
+	require.NoError(t, err)
+	first := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Act
+	_, err = store.Revoke(ctx, key.ID, first)
+	require.NoError(t, err)
+	revoked, err := store.Revoke(ctx, key.ID, first.Add(time.Hour))
+
+	// Assert
+	require.NoError(t, err)
+	require.NotNil(t, revoked.RevokedAt)
+	assert.Equal(t, first, *revoked.RevokedAt)
+	_, err = store.Lookup(ctx, Hash(token))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMemoryUsage_WhenRequestsSpanDays_ReportsEachDayNewestFirst(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewMemory()
+	day := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	store.RecordUsage(ctx, "k1", "/api/item/{itemId}", 5, false, day)
+	store.RecordUsage(ctx, "k1", "/api/reforges", 1, false, day)
+	store.RecordUsage(ctx, "k1", "/api/reforges", 1, true, day.Add(2*time.Hour))
+
+	// Act
+	usage, err := store.Usage(ctx, "k1", day.AddDate(0, 0, -7), day.AddDate(0, 0, 1))
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, "2026-03-02", usage[0].Date)
+	assert.Equal(t, int64(1), usage[0].Rejected)
+	assert.Equal(t, int64(0), usage[0].Units)
+	assert.Equal(t, "2026-03-01", usage[1].Date)
+	assert.Equal(t, int64(2), usage[1].Requests)
+	assert.Equal(t, int64(6), usage[1].Units)
+	assert.Equal(t, int64(5), usage[1].Routes["/api/item/{itemId}"].Units)
+}
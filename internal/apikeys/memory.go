@@ -0,0 +1,138 @@
+package apikeys
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"yard-backend/internal/models"
+)
+
+// keeps keys in process memory, used when storage isn't redis so keys are lost on restart
+type Memory struct {
+	mu     sync.Mutex
+	keys   map[string]models.APIKey
+	hashes map[string]string
+	// key id to date to usage
+	usage map[string]map[string]*models.APIKeyUsage
+}
+
+// creates an empty in memory store
+func NewMemory() *Memory {
+	return &Memory{
+		keys:   make(map[string]models.APIKey),
+		hashes: make(map[string]string),
+		usage:  make(map[string]map[string]*models.APIKeyUsage),
+	}
+}
+
+func (m *Memory) Create(ctx context.Context, key models.APIKey, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[key.ID] = key
+	m.hashes[hash] = key.ID
+	return nil
+}
+
+func (m *Memory) Lookup(ctx context.Context, hash string) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.keys[m.hashes[hash]]
+	if !ok || key.RevokedAt != nil {
+		return models.APIKey{}, ErrNotFound
+	}
+	return key, nil
+}
+
+func (m *Memory) Get(ctx context.Context, id string) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.keys[id]
+	if !ok {
+		return models.APIKey{}, ErrNotFound
+	}
+	return key, nil
+}
+
+func (m *Memory) List(ctx context.Context) ([]models.APIKey, error) {
+	m.mu.Lock()
+	keys := make([]models.APIKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	m.mu.Unlock()
+
+	sortKeys(keys)
+	return keys, nil
+}
+
+func (m *Memory) Revoke(ctx context.Context, id string, at time.Time) (models.APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key, ok := m.keys[id]
+	if !ok {
+		return models.APIKey{}, ErrNotFound
+	}
+	if key.RevokedAt == nil {
+		revokedAt := at.UTC()
+		key.RevokedAt = &revokedAt
+		m.keys[id] = key
+	}
+	return key, nil
+}
+
+func (m *Memory) RecordUsage(ctx context.Context, id, route string, units int, rejected bool, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	byDate := m.usage[id]
+	if byDate == nil {
+		byDate = make(map[string]*models.APIKeyUsage)
+		m.usage[id] = byDate
+	}
+
+	date := at.UTC().Format(time.DateOnly)
+	day := byDate[date]
+	if day == nil {
+		day = &models.APIKeyUsage{Date: date}
+		byDate[date] = day
+		// a new day is the only time anything can have aged out
+		oldest := at.Add(-UsageRetention).UTC().Format(time.DateOnly)
+		for date := range byDate {
+			if date < oldest {
+				delete(byDate, date)
+			}
+		}
+	}
+	addUsage(day, route, units, rejected)
+	return nil
+}
+
+func (m *Memory) Usage(ctx context.Context, id string, from, to time.Time) ([]models.APIKeyUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var usage []models.APIKeyUsage
+	for _, date := range days(from, to) {
+		day, ok := m.usage[id][date]
+		if !ok {
+			continue
+		}
+		copied := *day
+		copied.Routes = make(map[string]models.RouteUsage, len(day.Routes))
+		for route, routeUsage := range day.Routes {
+			copied.Routes[route] = routeUsage
+		}
+		usage = append(usage, copied)
+	}
+	return usage, nil
+}
+
+// oldest first, ids break ties between keys created in the same instant
+func sortKeys(keys []models.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+}
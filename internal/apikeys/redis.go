@@ -0,0 +1,187 @@
+package apikeys
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"yard-backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// key id to json key
+	redisKeysKey = "apikeys:keys"
+	// token hash to key id
+	redisHashesKey = "apikeys:hashes"
+	// one hash per key and utc day, totals next to requests:{route} and units:{route} fields
+	redisUsagePrefix = "apikeys:usage:"
+)
+
+// keeps keys in redis so every replica sees the same keys and adds to the same usage
+type Redis struct {
+	client *redis.Client
+}
+
+// uses an already connected client, normally the one of the redis storage backend
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Create(ctx context.Context, key models.APIKey, hash string) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("encoding api key: %w", err)
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisKeysKey, key.ID, data)
+		pipe.HSet(ctx, redisHashesKey, hash, key.ID)
+		return nil
+	})
+	return err
+}
+
+func (r *Redis) Lookup(ctx context.Context, hash string) (models.APIKey, error) {
+	id, err := r.client.HGet(ctx, redisHashesKey, hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return models.APIKey{}, ErrNotFound
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	key, err := r.Get(ctx, id)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	if key.RevokedAt != nil {
+		return models.APIKey{}, ErrNotFound
+	}
+	return key, nil
+}
+
+func (r *Redis) Get(ctx context.Context, id string) (models.APIKey, error) {
+	data, err := r.client.HGet(ctx, redisKeysKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return models.APIKey{}, ErrNotFound
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	var key models.APIKey
+	if err := json.Unmarshal(data, &key); err != nil {
+		return models.APIKey{}, fmt.Errorf("decoding api key %s: %w", id, err)
+	}
+	return key, nil
+}
+
+func (r *Redis) List(ctx context.Context) ([]models.APIKey, error) {
+	all, err := r.client.HGetAll(ctx, redisKeysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]models.APIKey, 0, len(all))
+	for id, data := range all {
+		var key models.APIKey
+		if err := json.Unmarshal([]byte(data), &key); err != nil {
+			return nil, fmt.Errorf("decoding api key %s: %w", id, err)
+		}
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	return keys, nil
+}
+
+func (r *Redis) Revoke(ctx context.Context, id string, at time.Time) (models.APIKey, error) {
+	key, err := r.Get(ctx, id)
+	if err != nil || key.RevokedAt != nil {
+		return key, err
+	}
+
+	revokedAt := at.UTC()
+	key.RevokedAt = &revokedAt
+	data, err := json.Marshal(key)
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("encoding api key: %w", err)
+	}
+	if err := r.client.HSet(ctx, redisKeysKey, id, data).Err(); err != nil {
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *Redis) RecordUsage(ctx context.Context, id, route string, units int, rejected bool, at time.Time) error {
+	key := redisUsageKey(id, at.UTC().Format(time.DateOnly))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		if rejected {
+			pipe.HIncrBy(ctx, key, "rejected", 1)
+		} else {
+			pipe.HIncrBy(ctx, key, "requests", 1)
+			pipe.HIncrBy(ctx, key, "units", int64(units))
+			pipe.HIncrBy(ctx, key, "requests:"+route, 1)
+			pipe.HIncrBy(ctx, key, "units:"+route, int64(units))
+		}
+		pipe.Expire(ctx, key, UsageRetention)
+		return nil
+	})
+	return err
+}
+
+func (r *Redis) Usage(ctx context.Context, id string, from, to time.Time) ([]models.APIKeyUsage, error) {
+	dates := days(from, to)
+	cmds := make([]*redis.MapStringStringCmd, len(dates))
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, date := range dates {
+			cmds[i] = pipe.HGetAll(ctx, redisUsageKey(id, date))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var usage []models.APIKeyUsage
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		day := models.APIKeyUsage{Date: dates[i], Routes: make(map[string]models.RouteUsage)}
+		for field, raw := range fields {
+			n, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("decoding usage field %s of key %s: %w", field, id, err)
+			}
+			// route templates may contain colons, the counter names never do
+			counter, route, perRoute := strings.Cut(field, ":")
+			routeUsage := day.Routes[route]
+			switch {
+			case !perRoute && counter == "requests":
+				day.Requests = n
+			case !perRoute && counter == "units":
+				day.Units = n
+			case !perRoute && counter == "rejected":
+				day.Rejected = n
+			case counter == "requests":
+				routeUsage.Requests = n
+				day.Routes[route] = routeUsage
+			case counter == "units":
+				routeUsage.Units = n
+				day.Routes[route] = routeUsage
+			}
+		}
+		usage = append(usage, day)
+	}
+	return usage, nil
+}
+
+func redisUsageKey(id, date string) string {
+	return redisUsagePrefix + id + ":" + date
+}
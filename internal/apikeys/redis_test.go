@@ -0,0 +1,66 @@
+package apikeys
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedis_WhenKeyIssuedAndUsed_SharesKeyAndUsageBetweenInstances(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	first := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	second := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	key, token, err := Issue(ctx, first, "partner site", TierPartner, now)
+	require.NoError(t, err)
+
+	// Act
+	found, lookupErr := second.Lookup(ctx, Hash(token))
+	require.NoError(t, first.RecordUsage(ctx, key.ID, "/api/item/{itemId}", 5, false, now))
+	require.NoError(t, second.RecordUsage(ctx, key.ID, "/api/item/{itemId}", 5, false, now))
+	require.NoError(t, second.RecordUsage(ctx, key.ID, "/api/reforges", 1, true, now))
+	usage, usageErr := first.Usage(ctx, key.ID, now.AddDate(0, 0, -1), now)
+
+	// Assert
+	require.NoError(t, lookupErr)
+	assert.Equal(t, key, found)
+	assert.Empty(t, mr.HGet("apikeys:hashes", token))
+	require.NoError(t, usageErr)
+	require.Len(t, usage, 1)
+	assert.Equal(t, "2026-03-01", usage[0].Date)
+	assert.Equal(t, int64(2), usage[0].Requests)
+	assert.Equal(t, int64(10), usage[0].Units)
+	assert.Equal(t, int64(1), usage[0].Rejected)
+	assert.Equal(t, int64(2), usage[0].Routes["/api/item/{itemId}"].Requests)
+	assert.NotContains(t, usage[0].Routes, "/api/reforges")
+}
+
+func TestRedisRevoke_WhenKeyRevoked_ListsItButRejectsLookups(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := NewRedis(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}))
+	kept, _, err := Issue(ctx, store, "kept", TierFree, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	revoked, token, err := Issue(ctx, store, "revoked", TierFree, time.Now())
+	require.NoError(t, err)
+
+	// Act
+	_, err = store.Revoke(ctx, revoked.ID, time.Now())
+
+	// Assert
+	require.NoError(t, err)
+	_, err = store.Lookup(ctx, Hash(token))
+	assert.ErrorIs(t, err, ErrNotFound)
+	keys, err := store.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, kept.ID, keys[0].ID)
+	assert.NotNil(t, keys[1].RevokedAt)
+}
@@ -0,0 +1,103 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// swapped on config reload while requests are being resolved, nil trusts no proxy
+var trusted atomic.Pointer[[]netip.Prefix]
+
+// parses proxy ips and cidr ranges, a bare ip trusts exactly that address
+func ParsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr range %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ip %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// replaces the proxies whose X-Forwarded-For entries are believed
+func SetTrustedProxies(prefixes []netip.Prefix) {
+	trusted.Store(&prefixes)
+}
+
+// returns the address of the client behind any trusted proxies
+// X-Forwarded-For is walked from the right, where the proxy closest to us appended its peer, and the first
+// hop that isn't a trusted proxy is the client, so whatever the client wrote on the left is never believed
+// the result is invalid only when RemoteAddr isn't an address, which real connections always have
+func FromRequest(r *http.Request) netip.Addr {
+	peer := parseAddr(r.RemoteAddr)
+	if !peer.IsValid() || !isTrusted(peer) {
+		return peer
+	}
+
+	hops := forwardedHops(r)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseAddr(hops[i])
+		if !hop.IsValid() {
+			// a trusted proxy vouched for garbage, the last address we can vouch for is that proxy
+			return peer
+		}
+		if !isTrusted(hop) {
+			return hop
+		}
+		peer = hop
+	}
+	// every hop is a trusted proxy, so the request started at the leftmost one
+	return peer
+}
+
+func isTrusted(addr netip.Addr) bool {
+	prefixes := trusted.Load()
+	if prefixes == nil {
+		return false
+	}
+	for _, prefix := range *prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// every X-Forwarded-For entry in order, proxies may append a header instead of extending the last one
+func forwardedHops(r *http.Request) []string {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// accepts ip, ip:port and [ipv6]:port, ipv4 mapped ipv6 addresses are treated as the ipv4 address
+func parseAddr(raw string) netip.Addr {
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap().WithZone("")
+}
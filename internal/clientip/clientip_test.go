@@ -0,0 +1,74 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func trust(t *testing.T, entries ...string) {
+	prefixes, err := ParsePrefixes(entries)
+	require.NoError(t, err)
+	SetTrustedProxies(prefixes)
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+}
+
+func TestFromRequest_WhenPeerNotTrusted_IgnoresForwardedFor(t *testing.T) {
+	// Arrange
+	trust(t, "10.0.0.0/8")
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:51000"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	// Act
+	addr := FromRequest(req)
+
+	// Assert
+	assert.Equal(t, netip.MustParseAddr("203.0.113.7"), addr)
+}
+
+func TestFromRequest_WhenBehindTrustedProxies_ReturnsFirstUntrustedHopFromTheRight(t *testing.T) {
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		expected  string
+	}{
+		{"spoofed left entry is skipped", "10.0.0.2:443", []string{"1.1.1.1, 198.51.100.9"}, "198.51.100.9"},
+		{"chain of trusted proxies", "10.0.0.2:443", []string{"198.51.100.9, 10.0.0.5", "10.0.0.3"}, "198.51.100.9"},
+		{"ipv6 client and proxy", "[2001:db8::1]:443", []string{"2001:db8:ffff::42"}, "2001:db8:ffff::42"},
+		{"ipv4 mapped client", "10.0.0.2:443", []string{"::ffff:198.51.100.9"}, "198.51.100.9"},
+		{"every hop trusted", "10.0.0.2:443", []string{"10.0.0.9"}, "10.0.0.9"},
+		{"garbage hop", "10.0.0.2:443", []string{"198.51.100.9, not-an-ip"}, "10.0.0.2"},
+		{"no header", "10.0.0.2:443", nil, "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			trust(t, "10.0.0.0/8", "2001:db8::1")
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, header := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", header)
+			}
+
+			// Act
+			addr := FromRequest(req)
+
+			// Assert
+			assert.Equal(t, tt.expected, addr.String())
+		})
+	}
+}
+
+func TestParsePrefixes_WhenEntryInvalid_ReturnsError(t *testing.T) {
+	// Act
+	_, err := ParsePrefixes([]string{"10.0.0.0/8", "10.0.0.0/33"})
+
+	// Assert
+	assert.ErrorContains(t, err, `invalid cidr range "10.0.0.0/33"`)
+}
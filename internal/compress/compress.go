@@ -0,0 +1,112 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// content encodings in order of preference when the client rates several the same
+var Encodings = []string{"zstd", "gzip"}
+
+var gzipPool = sync.Pool{New: func() interface{} {
+	w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+	return w
+}}
+
+var zstdPool = sync.Pool{New: func() interface{} {
+	w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+	return w
+}}
+
+// picks the preferred encoding with the highest quality in an Accept-Encoding header, empty means identity
+func Negotiate(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	quality := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		quality[name] = q
+	}
+
+	best, bestQuality := "", 0.0
+	for _, encoding := range Encodings {
+		q, ok := quality[encoding]
+		if !ok {
+			q, ok = quality["*"]
+		}
+		if ok && q > bestQuality {
+			best, bestQuality = encoding, q
+		}
+	}
+	return best
+}
+
+// returns a pooled encoder writing to w, hand it back with Release once closed
+func NewWriter(encoding string, w io.Writer) io.WriteCloser {
+	switch encoding {
+	case "zstd":
+		encoder := zstdPool.Get().(*zstd.Encoder)
+		encoder.Reset(w)
+		return encoder
+	default:
+		encoder := gzipPool.Get().(*gzip.Writer)
+		encoder.Reset(w)
+		return encoder
+	}
+}
+
+// returns an encoder from NewWriter to its pool
+func Release(encoder io.WriteCloser) {
+	switch e := encoder.(type) {
+	case *zstd.Encoder:
+		zstdPool.Put(e)
+	case *gzip.Writer:
+		gzipPool.Put(e)
+	}
+}
+
+// compresses data in one go
+func Bytes(encoding string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := NewWriter(encoding, &buf)
+	defer Release(encoder)
+	if _, err := encoder.Write(data); err != nil {
+		return nil, fmt.Errorf("compressing with %s: %w", encoding, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("compressing with %s: %w", encoding, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// marks an entity tag as belonging to the encoded representation, e.g. "abc" becomes "abc-gzip"
+func ETag(etag, encoding string) string {
+	if encoding == "" || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// removes the encoding marks ETag added from a list of entity tags
+func StripETags(header string) string {
+	for _, encoding := range Encodings {
+		header = strings.ReplaceAll(header, "-"+encoding+`"`, `"`)
+	}
+	return header
+}
@@ -0,0 +1,57 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiate_WhenWildcardGiven_PicksPreferredEncoding(t *testing.T) {
+	// Arrange
+	header := "*;q=0.8, gzip;q=0.9"
+
+	// Act
+	encoding := Negotiate(header)
+
+	// Assert
+	assert.Equal(t, "gzip", encoding)
+}
+
+func TestNegotiate_WhenEncodingsRejected_ReturnsIdentity(t *testing.T) {
+	// Arrange
+	header := "gzip;q=0, zstd;q=0, identity"
+
+	// Act
+	encoding := Negotiate(header)
+
+	// Assert
+	assert.Empty(t, encoding)
+}
+
+func TestBytes_WhenCompressed_DecodesToInput(t *testing.T) {
+	// Arrange
+	data := bytes.Repeat([]byte(`{"id":"AMBER"}`), 100)
+
+	// Act
+	gzipped, gzipErr := Bytes("gzip", data)
+	zstded, zstdErr := Bytes("zstd", data)
+
+	// Assert
+	require.NoError(t, gzipErr)
+	require.NoError(t, zstdErr)
+	reader, err := gzip.NewReader(bytes.NewReader(gzipped))
+	require.NoError(t, err)
+	unzipped, _ := io.ReadAll(reader)
+	assert.Equal(t, data, unzipped)
+	decoder, err := zstd.NewReader(nil)
+	require.NoError(t, err)
+	defer decoder.Close()
+	unzstded, err := decoder.DecodeAll(zstded, nil)
+	require.NoError(t, err)
+	assert.Equal(t, data, unzstded)
+}
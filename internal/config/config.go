@@ -1,71 +1,480 @@
 package config
 
 import (
-	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
-	"sync"
+	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
+	"yard-backend/internal/acl"
+	"yard-backend/internal/clientip"
+
 	"github.com/joho/godotenv"
-	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
 )
 
-var (
-	RDB           *redis.Client
-	Ctx           = context.Background()
-	APIURL        = "https://api.hypixel.net/v2/resources/skyblock/items"
-	SkyCoflURL    = "https://sky.coflnet.com"
-	NEURepoPath   = "NotEnoughUpdates-REPO"
-	AllowedOrigin = "*"
+// typed configuration loaded from a yaml file with environment overrides
+// every field can be set in the file, fields with an env tag can also be overridden from the environment
+// fields tagged reload:"live" are applied on reload, everything else needs a restart
+type Config struct {
+	Server    ServerConfig    `yaml:"server"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Redis     RedisConfig     `yaml:"redis"`
+	Upstream  UpstreamConfig  `yaml:"upstream"`
+	Scheduler SchedulerConfig `yaml:"scheduler"`
+	API       APIConfig       `yaml:"api"`
+	GraphQL   GraphQLConfig   `yaml:"graphql"`
+	Images    ImagesConfig    `yaml:"images"`
+	NEU       NEUConfig       `yaml:"neu"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	GeoIP     GeoIPConfig     `yaml:"geoip"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Admin     AdminConfig     `yaml:"admin"`
+	ACL       ACLConfig       `yaml:"acl"`
+}
 
-	RateLimiterMutex sync.Mutex
-	LastRequestTime  time.Time
-	// coflnet rate limit: 30 req/10s = 333ms
-	MinRequestDelay = 350 * time.Millisecond
+type ServerConfig struct {
+	ListenAddr      string        `yaml:"listen_addr" env:"LISTEN_ADDR"`
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// ips and cidr ranges of the proxies in front, only their X-Forwarded-For entries are believed
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES" reload:"live"`
+}
 
-	NEUReforgeStones      map[string]interface{}
-	NEUReforgeStonesMutex sync.RWMutex
+// storage backends selectable with storage.backend
+const (
+	StorageRedis  = "redis"
+	StorageMemory = "memory"
+	StorageBolt   = "bolt"
+)
 
-	NEUReforges      map[string]interface{}
-	NEUReforgesMutex sync.RWMutex
+type StorageConfig struct {
+	// redis is shared between replicas, memory is lost on restart, bolt keeps everything in one local file
+	Backend string `yaml:"backend" env:"STORAGE_BACKEND"`
+	// database file used by the bolt backend
+	Path string `yaml:"path" env:"STORAGE_PATH"`
+	// previous snapshots kept next to the current one for rollback
+	KeepVersions int `yaml:"keep_versions" env:"STORAGE_KEEP_VERSIONS" reload:"live"`
+	// how long a stone missing from the hypixel list stays listed as delisted before it is dropped
+	DelistedGrace time.Duration `yaml:"delisted_grace" env:"STORAGE_DELISTED_GRACE" reload:"live"`
+	// how long price points are kept, older ones are dropped whenever a stone gets a new one, 0 keeps them forever
+	PriceHistoryMaxAge time.Duration `yaml:"price_history_max_age" env:"STORAGE_PRICE_HISTORY_MAX_AGE" reload:"live"`
+}
 
-	APIRateLimitMutex       sync.Mutex
-	APIRateLimitMap         = make(map[string]time.Time)
-	APIRateLimitWindow      = 1 * time.Minute
-	APIRateLimitMaxRequests = 60
+type RedisConfig struct {
+	Host     string `yaml:"host" env:"REDIS_HOST"`
+	Port     int    `yaml:"port" env:"REDIS_PORT"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD"`
+	DB       int    `yaml:"db" env:"REDIS_DB"`
+}
 
-	MetricsEnabled     = false
-	MetricsIPWhitelist = ""
-)
+type UpstreamConfig struct {
+	HypixelURL       string        `yaml:"hypixel_url" env:"API_URL"`
+	CoflnetURL       string        `yaml:"coflnet_url" env:"SKYCOFL_URL"`
+	RequestTimeout   time.Duration `yaml:"request_timeout" env:"UPSTREAM_REQUEST_TIMEOUT"`
+	CoflnetMinDelay  time.Duration `yaml:"coflnet_min_delay" env:"COFLNET_MIN_DELAY"`
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+}
+
+type SchedulerConfig struct {
+	HypixelCheckInterval time.Duration `yaml:"hypixel_check_interval" env:"HYPIXEL_CHECK_INTERVAL" reload:"live"`
+	HypixelStaleAfter    time.Duration `yaml:"hypixel_stale_after" env:"HYPIXEL_STALE_AFTER" reload:"live"`
+	PriceInterval        time.Duration `yaml:"price_interval" env:"PRICE_REFRESH_INTERVAL" reload:"live"`
+	// how often every instance checks storage for data published by the leader and rebuilds its responses
+	ReadModelCheckInterval time.Duration `yaml:"read_model_check_interval" env:"READ_MODEL_CHECK_INTERVAL" reload:"live"`
+	LeaseTTL               time.Duration `yaml:"lease_ttl"`
+	LeaseRenewInterval     time.Duration `yaml:"lease_renew_interval"`
+}
+
+type APIConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" env:"ALLOWED_ORIGIN" reload:"live"`
+	// budget per window of clients without an api key, a request uses the cost of its route
+	RateLimitRequests int           `yaml:"rate_limit_requests" env:"API_RATE_LIMIT_REQUESTS" reload:"live"`
+	RateLimitWindow   time.Duration `yaml:"rate_limit_window" env:"API_RATE_LIMIT_WINDOW" reload:"live"`
+	// budgets per window of requests carrying an api key of the free and partner tier
+	RateLimitFree    int `yaml:"rate_limit_free" env:"API_RATE_LIMIT_FREE" reload:"live"`
+	RateLimitPartner int `yaml:"rate_limit_partner" env:"API_RATE_LIMIT_PARTNER" reload:"live"`
+	// budget used by one request keyed by route path as registered, routes not listed cost 1
+	RouteCosts     map[string]int `yaml:"route_costs" reload:"live"`
+	OrderBookDepth int            `yaml:"order_book_depth" env:"ORDER_BOOK_DEPTH" reload:"live"`
+	// how long browsers and cdns may reuse a json response before revalidating it
+	CacheMaxAge time.Duration `yaml:"cache_max_age" env:"API_CACHE_MAX_AGE" reload:"live"`
+}
+
+type GraphQLConfig struct {
+	// queries costing more than this are rejected before they run, list fields cost their limit times their selection
+	MaxComplexity int `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" reload:"live"`
+	MaxDepth      int `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH" reload:"live"`
+	// how many automatic persisted queries each instance remembers
+	PersistedQueries int `yaml:"persisted_queries" env:"GRAPHQL_PERSISTED_QUERIES"`
+}
+
+type ImagesConfig struct {
+	Size int `yaml:"size" env:"IMAGE_SIZE" reload:"live"`
+}
+
+type NEUConfig struct {
+	RepoPath string `yaml:"repo_path" env:"NEU_REPO_PATH"`
+}
+
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled" env:"METRICS_ENABLED"`
+	// acl rules for /metrics, empty lets everyone scrape
+	IPWhitelist []string `yaml:"ip_whitelist" env:"METRICS_IP_WHITELIST" reload:"live"`
+	// distinct asns labelled by the asn metric, the rest are counted as other
+	MaxASNs int `yaml:"max_asns" env:"METRICS_MAX_ASNS" reload:"live"`
+	// stones exported with per stone market gauges, 0 keeps only the totals
+	MarketStones int `yaml:"market_stones" env:"METRICS_MARKET_STONES" reload:"live"`
+}
+
+// local maxmind format databases for the country and asn metrics, lookups are skipped without them
+type GeoIPConfig struct {
+	CountryDB string `yaml:"country_db" env:"GEOIP_COUNTRY_DB" reload:"live"`
+	// may be the same file as country_db when it carries both
+	ASNDB     string `yaml:"asn_db" env:"GEOIP_ASN_DB" reload:"live"`
+	CacheSize int    `yaml:"cache_size" env:"GEOIP_CACHE_SIZE"`
+}
+
+// opentelemetry traces exported over otlp/http, tracing is off without an endpoint
+type TracingConfig struct {
+	// otlp/http traces url of a collector, e.g. http://localhost:4318/v1/traces
+	Endpoint    string `yaml:"endpoint" env:"TRACING_ENDPOINT"`
+	ServiceName string `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+	// share of new traces recorded, requests arriving with a sampled parent are always recorded
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+type AdminConfig struct {
+	// admin api is disabled unless a token is configured
+	Token string `yaml:"token" env:"ADMIN_TOKEN"`
+	// acl rules for /admin checked before the token, empty allows any address
+	ACL []string `yaml:"acl" env:"ADMIN_ACL" reload:"live"`
+}
+
+// rules are an ip, a cidr range, @group or *, a leading ! denies, the first matching rule decides and no match denies
+type ACLConfig struct {
+	// named lists of ips and cidr ranges, rules refer to them as @name
+	Groups map[string][]string `yaml:"groups" reload:"live"`
+	// rules for every route under a path prefix, the longest matching prefix applies
+	Routes map[string][]string `yaml:"routes" reload:"live"`
+}
+
+// returns the built in defaults, matching the values the backend always shipped with
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			ListenAddr:      ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    15 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 30 * time.Second,
+		},
+		Storage: StorageConfig{
+			Backend:       StorageRedis,
+			Path:          "yard.db",
+			KeepVersions:  5,
+			DelistedGrace: 72 * time.Hour,
+			// a month of points at one per refresh is under 9000 per stone
+			PriceHistoryMaxAge: 30 * 24 * time.Hour,
+		},
+		Redis: RedisConfig{
+			Host: "localhost",
+			Port: 6379,
+		},
+		Upstream: UpstreamConfig{
+			HypixelURL:     "https://api.hypixel.net/v2/resources/skyblock/items",
+			CoflnetURL:     "https://sky.coflnet.com",
+			RequestTimeout: 10 * time.Second,
+			// coflnet rate limit: 30 req/10s = 333ms
+			CoflnetMinDelay:  350 * time.Millisecond,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		Scheduler: SchedulerConfig{
+			HypixelCheckInterval:   1 * time.Hour,
+			HypixelStaleAfter:      5 * time.Hour,
+			PriceInterval:          5 * time.Minute,
+			ReadModelCheckInterval: 10 * time.Second,
+			LeaseTTL:               15 * time.Second,
+			LeaseRenewInterval:     5 * time.Second,
+		},
+		API: APIConfig{
+			AllowedOrigins:    []string{"*"},
+			RateLimitRequests: 60,
+			RateLimitWindow:   1 * time.Minute,
+			RateLimitFree:     300,
+			RateLimitPartner:  3000,
+			// upscaling a texture is far more work than serving json, item-data may also fetch a skin from mojang
+			RouteCosts: map[string]int{
+				"/api/item/{itemId}":         5,
+				"/api/item-data/{itemId}":    10,
+				"/api/v2/item/{itemId}":      5,
+				"/api/v2/item-data/{itemId}": 10,
+			},
+			OrderBookDepth: 3,
+			CacheMaxAge:    30 * time.Second,
+		},
+		GraphQL: GraphQLConfig{
+			MaxComplexity:    10000,
+			MaxDepth:         10,
+			PersistedQueries: 1000,
+		},
+		Images: ImagesConfig{
+			Size: 256,
+		},
+		NEU: NEUConfig{
+			RepoPath: "NotEnoughUpdates-REPO",
+		},
+		Metrics: MetricsConfig{
+			MaxASNs:      100,
+			MarketStones: 200,
+		},
+		GeoIP: GeoIPConfig{
+			CacheSize: 10000,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "yard-backend",
+			SampleRatio: 0.1,
+		},
+	}
+}
+
+// builds a config from defaults, then the yaml file at path if given, then environment overrides
+// a missing file is an error only when the path was given explicitly
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem()); err != nil {
+		return nil, err
+	}
 
-// reads env vars from file or system with defaults
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loads the .env file into the process environment if one exists
 func LoadEnv() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables or defaults")
 	}
+}
 
-	if apiURL := os.Getenv("API_URL"); apiURL != "" {
-		APIURL = apiURL
+// checks every setting and returns all problems at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
 	}
 
-	if skyCoflURL := os.Getenv("SKYCOFL_URL"); skyCoflURL != "" {
-		SkyCoflURL = skyCoflURL
+	check(c.Server.ListenAddr != "", "server.listen_addr must not be empty")
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	if _, err := clientip.ParsePrefixes(c.Server.TrustedProxies); err != nil {
+		check(false, "server.trusted_proxies: %v", err)
 	}
 
-	if neuRepoPath := os.Getenv("NEU_REPO_PATH"); neuRepoPath != "" {
-		NEURepoPath = neuRepoPath
+	switch c.Storage.Backend {
+	case StorageRedis, StorageMemory:
+	case StorageBolt:
+		check(c.Storage.Path != "", "storage.path must not be empty for the bolt backend")
+	default:
+		check(false, "storage.backend must be one of redis, memory or bolt, got %q", c.Storage.Backend)
+	}
+	check(c.Storage.KeepVersions >= 1, "storage.keep_versions must be at least 1, got %d", c.Storage.KeepVersions)
+	check(c.Storage.DelistedGrace >= 0, "storage.delisted_grace must not be negative")
+	check(c.Storage.PriceHistoryMaxAge >= 0, "storage.price_history_max_age must not be negative")
+
+	check(c.Redis.Host != "", "redis.host must not be empty")
+	check(c.Redis.Port > 0 && c.Redis.Port < 65536, "redis.port must be between 1 and 65535, got %d", c.Redis.Port)
+	check(c.Redis.DB >= 0, "redis.db must not be negative")
+
+	check(isHTTPURL(c.Upstream.HypixelURL), "upstream.hypixel_url must be an http(s) url, got %q", c.Upstream.HypixelURL)
+	check(isHTTPURL(c.Upstream.CoflnetURL), "upstream.coflnet_url must be an http(s) url, got %q", c.Upstream.CoflnetURL)
+	check(c.Upstream.RequestTimeout > 0, "upstream.request_timeout must be positive")
+	check(c.Upstream.CoflnetMinDelay >= 0, "upstream.coflnet_min_delay must not be negative")
+	check(c.Upstream.BreakerThreshold > 0, "upstream.breaker_threshold must be positive")
+	check(c.Upstream.BreakerCooldown > 0, "upstream.breaker_cooldown must be positive")
+
+	check(c.Scheduler.HypixelCheckInterval > 0, "scheduler.hypixel_check_interval must be positive")
+	check(c.Scheduler.HypixelStaleAfter > 0, "scheduler.hypixel_stale_after must be positive")
+	check(c.Scheduler.PriceInterval > 0, "scheduler.price_interval must be positive")
+	check(c.Scheduler.ReadModelCheckInterval > 0, "scheduler.read_model_check_interval must be positive")
+	check(c.Scheduler.LeaseTTL > 0, "scheduler.lease_ttl must be positive")
+	check(c.Scheduler.LeaseRenewInterval > 0 && c.Scheduler.LeaseRenewInterval < c.Scheduler.LeaseTTL,
+		"scheduler.lease_renew_interval must be positive and shorter than scheduler.lease_ttl")
+
+	check(len(c.API.AllowedOrigins) > 0, "api.allowed_origins must list at least one origin or *")
+	check(c.API.RateLimitRequests > 0, "api.rate_limit_requests must be positive")
+	check(c.API.RateLimitWindow > 0, "api.rate_limit_window must be positive")
+	check(c.API.RateLimitFree > 0, "api.rate_limit_free must be positive")
+	check(c.API.RateLimitPartner > 0, "api.rate_limit_partner must be positive")
+	// a route costing more than a budget could never be called by that tier
+	smallestBudget := min(c.API.RateLimitRequests, c.API.RateLimitFree, c.API.RateLimitPartner)
+	for route, cost := range c.API.RouteCosts {
+		check(strings.HasPrefix(route, "/"), "api.route_costs key %q must be a route path starting with /", route)
+		check(cost >= 1 && cost <= smallestBudget, "api.route_costs of %s must be between 1 and the smallest rate limit %d, got %d", route, smallestBudget, cost)
 	}
+	check(c.API.OrderBookDepth > 0, "api.order_book_depth must be positive")
+	check(c.API.CacheMaxAge >= 0, "api.cache_max_age must not be negative")
+
+	check(c.GraphQL.MaxComplexity > 0, "graphql.max_complexity must be positive")
+	check(c.GraphQL.MaxDepth > 0, "graphql.max_depth must be positive")
+	check(c.GraphQL.PersistedQueries > 0, "graphql.persisted_queries must be positive")
+
+	check(c.Images.Size >= 16 && c.Images.Size <= 4096, "images.size must be between 16 and 4096, got %d", c.Images.Size)
+
+	check(c.NEU.RepoPath != "", "neu.repo_path must not be empty")
+
+	check(c.Metrics.MaxASNs >= 0, "metrics.max_asns must not be negative")
+	check(c.Metrics.MarketStones >= 0, "metrics.market_stones must not be negative")
+	check(c.GeoIP.CacheSize > 0, "geoip.cache_size must be positive")
 
-	if allowedOrigin := os.Getenv("ALLOWED_ORIGIN"); allowedOrigin != "" {
-		AllowedOrigin = allowedOrigin
+	check(c.Tracing.Endpoint == "" || isHTTPURL(c.Tracing.Endpoint), "tracing.endpoint must be an http(s) url, got %q", c.Tracing.Endpoint)
+	check(c.Tracing.ServiceName != "", "tracing.service_name must not be empty")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	for prefix := range c.ACL.Routes {
+		check(prefix != "/metrics" && prefix != "/admin", "acl.routes must not list %s, set metrics.ip_whitelist or admin.acl instead", prefix)
+	}
+	if _, err := acl.ParsePolicy(c.ACLLists(), c.ACL.Groups); err != nil {
+		check(false, "acl: %v", err)
 	}
 
-	if metricsEnabled := os.Getenv("METRICS_ENABLED"); metricsEnabled == "true" || metricsEnabled == "1" {
-		MetricsEnabled = true
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
 	}
+	return nil
+}
+
+// renders the effective config as yaml with secrets masked, used by --print-config
+func (c *Config) Dump() ([]byte, error) {
+	masked := *c
+	if masked.Redis.Password != "" {
+		masked.Redis.Password = "********"
+	}
+	if masked.Admin.Token != "" {
+		masked.Admin.Token = "********"
+	}
+	return yaml.Marshal(&masked)
+}
+
+// returns the acl rules of every restricted path prefix
+func (c *Config) ACLLists() map[string][]string {
+	lists := map[string][]string{
+		"/metrics": c.Metrics.IPWhitelist,
+		"/admin":   c.Admin.ACL,
+	}
+	for prefix, rules := range c.ACL.Routes {
+		if prefix != "/metrics" && prefix != "/admin" {
+			lists[prefix] = rules
+		}
+	}
+	return lists
+}
+
+// returns the redis address as host:port
+func (r RedisConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", r.Host, r.Port)
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// walks the config struct and overrides any field whose env tag names a set variable
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnv(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := t.Field(i).Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw, ok := os.LookupEnv(name)
+		if !ok || raw == "" {
+			continue
+		}
+
+		if err := setFromString(field, raw); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+	}
+	return nil
+}
 
-	if metricsIPWhitelist := os.Getenv("METRICS_IP_WHITELIST"); metricsIPWhitelist != "" {
-		MetricsIPWhitelist = metricsIPWhitelist
+func setFromString(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Bool:
+		// still accepts "1" like the old METRICS_ENABLED parsing did, a typo fails instead of disabling
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
 	}
+	return nil
 }
@@ -2,37 +2,220 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestLoadEnv_WhenEnvVarsSet_LoadsFromEnvironment(t *testing.T) {
+func TestLoad_WhenNoFileOrEnv_ReturnsDefaults(t *testing.T) {
 	// Arrange
-	originalAPIURL := APIURL
-	defer func() { APIURL = originalAPIURL }()
+	os.Clearenv()
+
+	// Act
+	cfg, err := Load("")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+	assert.Equal(t, ":8080", cfg.Server.ListenAddr)
+	assert.Equal(t, 5*time.Minute, cfg.Scheduler.PriceInterval)
+}
 
-	os.Setenv("API_URL", "https://test-api.example.com")
+func TestLoad_WhenEnvVarsSet_OverridesDefaults(t *testing.T) {
+	// Arrange
+	t.Setenv("API_URL", "https://test-api.example.com")
+	t.Setenv("ALLOWED_ORIGIN", "https://a.example.com, https://b.example.com")
+	t.Setenv("PRICE_REFRESH_INTERVAL", "90s")
+	t.Setenv("REDIS_PORT", "6380")
+	t.Setenv("METRICS_ENABLED", "1")
 
 	// Act
-	LoadEnv()
+	cfg, err := Load("")
 
 	// Assert
-	assert.Equal(t, "https://test-api.example.com", APIURL)
+	require.NoError(t, err)
+	assert.Equal(t, "https://test-api.example.com", cfg.Upstream.HypixelURL)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.API.AllowedOrigins)
+	assert.Equal(t, 90*time.Second, cfg.Scheduler.PriceInterval)
+	assert.Equal(t, "localhost:6380", cfg.Redis.Addr())
+	assert.True(t, cfg.Metrics.Enabled)
+}
+
+func TestLoad_WhenEnvValueMalformed_ReturnsError(t *testing.T) {
+	// Arrange
+	t.Setenv("REDIS_PORT", "not-a-port")
 
-	os.Unsetenv("API_URL")
+	// Act
+	_, err := Load("")
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "REDIS_PORT")
 }
 
-func TestLoadEnv_WhenNoEnvVars_KeepsDefaults(t *testing.T) {
+func TestLoad_WhenBoolEnvMisspelled_ReturnsError(t *testing.T) {
 	// Arrange
-	originalAPIURL := APIURL
-	defer func() { APIURL = originalAPIURL }()
+	t.Setenv("METRICS_ENABLED", "ture")
 
-	os.Clearenv()
+	// Act
+	_, err := Load("")
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "METRICS_ENABLED")
+}
+
+func TestLoad_WhenFileGiven_AppliesFileThenEnv(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+server:
+  listen_addr: ":9090"
+scheduler:
+  price_interval: 10m
+images:
+  size: 128
+`), 0o644)
+	require.NoError(t, err)
+	t.Setenv("IMAGE_SIZE", "64")
+
+	// Act
+	cfg, err := Load(path)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.Server.ListenAddr)
+	assert.Equal(t, 10*time.Minute, cfg.Scheduler.PriceInterval)
+	assert.Equal(t, 64, cfg.Images.Size)
+	assert.Equal(t, 60, cfg.API.RateLimitRequests)
+}
+
+func TestLoad_WhenRouteCostsGiven_KeepsDefaultsOfUnlistedRoutes(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+api:
+  route_costs:
+    /api/item/{itemId}: 2
+    /graphql: 3
+`), 0o644)
+	require.NoError(t, err)
+
+	// Act
+	cfg, err := Load(path)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.API.RouteCosts["/api/item/{itemId}"])
+	assert.Equal(t, 3, cfg.API.RouteCosts["/graphql"])
+	assert.Equal(t, 10, cfg.API.RouteCosts["/api/item-data/{itemId}"])
+}
+
+func TestValidate_WhenRouteCostsExceedBudget_ReportsRoute(t *testing.T) {
+	// Arrange
+	cfg := Default()
+	cfg.API.RateLimitRequests = 8
+	cfg.API.RouteCosts["missing-slash"] = 1
+
+	// Act
+	err := cfg.Validate()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "api.route_costs of /api/item-data/{itemId} must be between 1 and the smallest rate limit 8, got 10")
+	assert.Contains(t, err.Error(), `api.route_costs key "missing-slash"`)
+}
+
+func TestValidate_WhenACLInvalid_ReportsIt(t *testing.T) {
+	// Arrange
+	cfg := Default()
+	cfg.Admin.ACL = []string{"@office"}
+	cfg.ACL.Routes = map[string][]string{"/metrics": {"*"}}
+
+	// Act
+	err := cfg.Validate()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `acl: /admin: unknown group "office"`)
+	assert.Contains(t, err.Error(), "acl.routes must not list /metrics")
+}
+
+func TestLoad_WhenTracingEnvSet_ParsesSampleRatio(t *testing.T) {
+	// Arrange
+	t.Setenv("TRACING_ENDPOINT", "http://localhost:4318/v1/traces")
+	t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+
+	// Act
+	cfg, err := Load("")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:4318/v1/traces", cfg.Tracing.Endpoint)
+	assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+}
+
+func TestValidate_WhenTracingInvalid_ReportsIt(t *testing.T) {
+	// Arrange
+	cfg := Default()
+	cfg.Tracing.Endpoint = "localhost:4318"
+	cfg.Tracing.SampleRatio = 1.5
+
+	// Act
+	err := cfg.Validate()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tracing.endpoint")
+	assert.Contains(t, err.Error(), "tracing.sample_ratio")
+}
+
+func TestLoad_WhenFileMissing_ReturnsError(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+
+	// Act
+	_, err := Load(path)
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestValidate_WhenSettingsInvalid_ReportsEveryProblem(t *testing.T) {
+	// Arrange
+	cfg := Default()
+	cfg.Redis.Port = 0
+	cfg.Upstream.HypixelURL = "not a url"
+	cfg.Scheduler.LeaseRenewInterval = cfg.Scheduler.LeaseTTL
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/99"}
+
+	// Act
+	err := cfg.Validate()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.trusted_proxies")
+	assert.Contains(t, err.Error(), "redis.port")
+	assert.Contains(t, err.Error(), "upstream.hypixel_url")
+	assert.Contains(t, err.Error(), "scheduler.lease_renew_interval")
+}
+
+func TestDump_WhenSecretsSet_MasksThem(t *testing.T) {
+	// Arrange
+	cfg := Default()
+	cfg.Redis.Password = "hunter2"
+	cfg.Admin.Token = "s3cret"
 
 	// Act
-	LoadEnv()
+	out, err := cfg.Dump()
 
 	// Assert
-	assert.NotEmpty(t, APIURL)
+	require.NoError(t, err)
+	assert.NotContains(t, string(out), "hunter2")
+	assert.NotContains(t, string(out), "s3cret")
+	assert.Contains(t, string(out), "listen_addr: :8080")
+	assert.Equal(t, "hunter2", cfg.Redis.Password)
 }
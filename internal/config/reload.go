@@ -0,0 +1,109 @@
+package config
+
+import (
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// what a reload changed, settings are named by their yaml path such as api.allowed_origins
+type ReloadResult struct {
+	Applied         []string
+	RestartRequired []string
+}
+
+// re-reads the config file and hands the live settings to every subscriber
+type Reloader struct {
+	mu          sync.Mutex
+	path        string
+	current     atomic.Pointer[Config]
+	subscribers []func(*Config)
+}
+
+// creates a reloader that re-reads path, starting from the config the process was started with
+func NewReloader(path string, cfg *Config) *Reloader {
+	r := &Reloader{path: path}
+	r.current.Store(cfg)
+	return r
+}
+
+// returns the config currently in effect
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// registers fn to receive the new config after every reload that changed something
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// loads the config again and applies its live settings
+// an invalid file leaves the current config in place and returns the error
+func (r *Reloader) Reload() (ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := Load(r.path)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+
+	merged, result := r.current.Load().Merge(next)
+	for _, name := range result.RestartRequired {
+		log.Printf("Config reload: %s changed but only takes effect after a restart", name)
+	}
+	if len(result.Applied) == 0 {
+		log.Println("Config reload: no live settings changed")
+		return result, nil
+	}
+
+	r.current.Store(merged)
+	for _, fn := range r.subscribers {
+		fn(merged)
+	}
+	log.Printf("Config reload: applied %s", strings.Join(result.Applied, ", "))
+	return result, nil
+}
+
+// returns a copy of c with the live settings taken from next, and which settings differed
+func (c *Config) Merge(next *Config) (*Config, ReloadResult) {
+	merged := *c
+	var result ReloadResult
+	mergeLive(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem(), "", &result)
+	return &merged, result
+}
+
+func mergeLive(dst, src reflect.Value, prefix string, result *ReloadResult) {
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + field.Tag.Get("yaml")
+
+		if field.Type.Kind() == reflect.Struct {
+			mergeLive(dst.Field(i), src.Field(i), name+".", result)
+			continue
+		}
+
+		if sameValue(dst.Field(i), src.Field(i)) {
+			continue
+		}
+		if field.Tag.Get("reload") == "live" {
+			dst.Field(i).Set(src.Field(i))
+			result.Applied = append(result.Applied, name)
+		} else {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+}
+
+// compares two setting values, treating a nil and an empty list or map as the same
+func sameValue(a, b reflect.Value) bool {
+	if (a.Kind() == reflect.Slice || a.Kind() == reflect.Map) && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
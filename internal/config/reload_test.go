@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge_WhenLiveAndStaticSettingsChange_AppliesOnlyLiveOnes(t *testing.T) {
+	// Arrange
+	current := Default()
+	next := Default()
+	next.API.RateLimitRequests = 120
+	next.Scheduler.PriceInterval = time.Minute
+	next.Server.ListenAddr = ":9090"
+	next.Redis.Host = "redis.internal"
+
+	// Act
+	merged, result := current.Merge(next)
+
+	// Assert
+	assert.Equal(t, []string{"scheduler.price_interval", "api.rate_limit_requests"}, result.Applied)
+	assert.Equal(t, []string{"server.listen_addr", "redis.host"}, result.RestartRequired)
+	assert.Equal(t, 120, merged.API.RateLimitRequests)
+	assert.Equal(t, time.Minute, merged.Scheduler.PriceInterval)
+	assert.Equal(t, ":8080", merged.Server.ListenAddr)
+	assert.Equal(t, "localhost", merged.Redis.Host)
+	assert.Equal(t, 60, current.API.RateLimitRequests)
+}
+
+func TestMerge_WhenWhitelistGoesFromNilToEmpty_ReportsNothing(t *testing.T) {
+	// Arrange
+	current := Default()
+	next := Default()
+	next.Metrics.IPWhitelist = []string{}
+
+	// Act
+	_, result := current.Merge(next)
+
+	// Assert
+	assert.Empty(t, result.Applied)
+	assert.Empty(t, result.RestartRequired)
+}
+
+func TestReload_WhenFileChanged_NotifiesSubscribers(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("metrics:\n  ip_whitelist: [\"10.0.0.0/8\"]\n"), 0o644))
+	reloader := NewReloader(path, Default())
+
+	var received *Config
+	reloader.OnReload(func(cfg *Config) { received = cfg })
+
+	// Act
+	result, err := reloader.Reload()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metrics.ip_whitelist"}, result.Applied)
+	require.NotNil(t, received)
+	assert.Equal(t, []string{"10.0.0.0/8"}, received.Metrics.IPWhitelist)
+	assert.Same(t, received, reloader.Current())
+}
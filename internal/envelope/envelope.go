@@ -0,0 +1,54 @@
+package envelope
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"yard-backend/internal/models"
+)
+
+// response header carrying the id of the request, error bodies repeat it so reports can be matched to logs
+const RequestIDHeader = "X-Request-ID"
+
+// stable error codes of /api/v2, clients switch on these so they must never change meaning
+const (
+	CodeInvalidParameter   = "invalid_parameter"
+	CodeRouteNotFound      = "route_not_found"
+	CodeRateLimited        = "rate_limited"
+	CodeInvalidAPIKey      = "invalid_api_key"
+	CodeForbidden          = "forbidden"
+	CodeReforgeNotFound    = "reforge_not_found"
+	CodeItemNotFound       = "item_not_found"
+	CodeTextureNotFound    = "texture_not_found"
+	CodeStorageUnavailable = "storage_unavailable"
+	CodeInternal           = "internal_error"
+)
+
+// writes a successful envelope
+func Write(w http.ResponseWriter, status int, data interface{}, meta models.Meta) {
+	write(w, status, models.Envelope{Success: true, Data: data, Meta: meta})
+}
+
+// writes a failed envelope, the message is shown to users so it must not carry internal details
+func Error(w http.ResponseWriter, status int, code, message string) {
+	write(w, status, models.Envelope{
+		Error: &models.APIError{
+			Code:      code,
+			Message:   message,
+			RequestID: w.Header().Get(RequestIDHeader),
+		},
+	})
+}
+
+func write(w http.ResponseWriter, status int, response models.Envelope) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// returns a handler answering every request with the same error, for unmatched routes
+func ErrorHandler(status int, code, message string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, status, code, message)
+	})
+}
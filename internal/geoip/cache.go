@@ -0,0 +1,58 @@
+package geoip
+
+import (
+	"container/list"
+	"net/netip"
+	"sync"
+)
+
+// remembers the locations of recently seen addresses, least recently used ones are dropped first
+type cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[netip.Addr]*list.Element
+}
+
+type cacheEntry struct {
+	addr     netip.Addr
+	location Location
+}
+
+func newCache(size int) *cache {
+	return &cache{size: size, order: list.New(), entries: make(map[netip.Addr]*list.Element)}
+}
+
+func (c *cache) get(addr netip.Addr) (Location, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[addr]
+	if !ok {
+		return Location{}, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry).location, true
+}
+
+func (c *cache) put(addr netip.Addr, location Location) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[addr]; ok {
+		element.Value.(*cacheEntry).location = location
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[addr] = c.order.PushFront(&cacheEntry{addr: addr, location: location})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).addr)
+	}
+}
+
+func (c *cache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+}
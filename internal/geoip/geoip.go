@@ -0,0 +1,179 @@
+package geoip
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang/v2"
+)
+
+// what the databases know about an address, fields are empty when it isn't listed
+type Location struct {
+	Country string
+	ASN     uint
+}
+
+// the fields read from country and asn databases, geolite2, geoip2 and combined files all use these names
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+	ASN uint `maxminddb:"autonomous_system_number"`
+}
+
+// an open database file and the modification time it was read at
+type database struct {
+	path    string
+	modTime time.Time
+	reader  *maxminddb.Reader
+}
+
+// resolves addresses against local maxmind format databases, files are reopened when they change
+type Resolver struct {
+	mu sync.RWMutex
+	// the configured files, kept apart from the open ones so a file that failed to open is retried
+	countryPath string
+	asnPath     string
+	country     *database
+	asn         *database
+	cache       *cache
+}
+
+// creates a resolver without databases that remembers up to cacheSize addresses
+func New(cacheSize int) *Resolver {
+	return &Resolver{cache: newCache(cacheSize)}
+}
+
+// switches to the databases at countryPath and asnPath, an empty path turns that lookup off
+// files that didn't change since they were opened are kept and a file that fails to open leaves the previous one in use
+func (r *Resolver) Open(countryPath, asnPath string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.countryPath, r.asnPath = countryPath, asnPath
+	return r.load()
+}
+
+// opens the configured files that changed, the caller holds the write lock
+func (r *Resolver) load() error {
+	var errs []error
+	country, err := reopen(r.country, r.countryPath)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	asn, err := reopen(r.asn, r.asnPath)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if country != r.country || asn != r.asn {
+		r.swap(country, asn)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("opening geoip database: %w", errs[0])
+	}
+	return nil
+}
+
+// returns the country and asn of addr, answers are cached until a database changes
+func (r *Resolver) Lookup(addr netip.Addr) Location {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.country == nil && r.asn == nil {
+		return Location{}
+	}
+	addr = addr.Unmap()
+	if location, ok := r.cache.get(addr); ok {
+		return location
+	}
+
+	var location Location
+	if r.country != nil {
+		var rec record
+		if err := r.country.reader.Lookup(addr).Decode(&rec); err == nil {
+			location.Country = rec.Country.ISOCode
+			if location.Country == "" {
+				location.Country = rec.RegisteredCountry.ISOCode
+			}
+		}
+	}
+	if r.asn != nil {
+		var rec record
+		if err := r.asn.reader.Lookup(addr).Decode(&rec); err == nil {
+			location.ASN = rec.ASN
+		}
+	}
+	r.cache.put(addr, location)
+	return location
+}
+
+// reports whether an asn database is open
+func (r *Resolver) HasASN() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.asn != nil
+}
+
+// reopens the current files every interval when they were replaced, e.g. by geoipupdate
+func (r *Resolver) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.mu.Lock()
+			err := r.load()
+			r.mu.Unlock()
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+// closes every open database, lookups find nothing afterwards
+func (r *Resolver) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.countryPath, r.asnPath = "", ""
+	r.swap(nil, nil)
+}
+
+// installs new databases, closing the ones no longer used, and forgets cached answers
+func (r *Resolver) swap(country, asn *database) {
+	for _, old := range []*database{r.country, r.asn} {
+		if old != nil && old != country && old != asn {
+			old.reader.Close()
+		}
+	}
+	r.country, r.asn = country, asn
+	r.cache.clear()
+}
+
+// returns the database to use for path, current when its file is unchanged
+func reopen(current *database, path string) (*database, error) {
+	if path == "" {
+		return nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return current, err
+	}
+	if current != nil && current.path == path && current.modTime.Equal(info.ModTime()) {
+		return current, nil
+	}
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return current, fmt.Errorf("%s: %w", path, err)
+	}
+	return &database{path: path, modTime: info.ModTime(), reader: reader}, nil
+}
@@ -0,0 +1,128 @@
+package geoip
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxmind/mmdbwriter"
+	"github.com/maxmind/mmdbwriter/mmdbtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writes a database mapping each network to a record with the given fields
+func writeDatabase(t *testing.T, path string, records map[string]mmdbtype.Map) {
+	t.Helper()
+	tree, err := mmdbwriter.New(mmdbwriter.Options{DatabaseType: "YARD-Test", RecordSize: 24})
+	require.NoError(t, err)
+	for network, record := range records {
+		_, ipNet, err := net.ParseCIDR(network)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(ipNet, record))
+	}
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+	_, err = tree.WriteTo(file)
+	require.NoError(t, err)
+}
+
+func country(code string) mmdbtype.Map {
+	return mmdbtype.Map{"country": mmdbtype.Map{"iso_code": mmdbtype.String(code)}}
+}
+
+func TestLookup_WhenDatabasesOpen_ResolvesCountryAndASN(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	countryPath := filepath.Join(dir, "country.mmdb")
+	asnPath := filepath.Join(dir, "asn.mmdb")
+	writeDatabase(t, countryPath, map[string]mmdbtype.Map{
+		"81.2.69.0/24":   country("GB"),
+		"2a02:8100::/24": {"registered_country": mmdbtype.Map{"iso_code": mmdbtype.String("DE")}},
+	})
+	writeDatabase(t, asnPath, map[string]mmdbtype.Map{
+		"81.2.69.0/24": {"autonomous_system_number": mmdbtype.Uint32(20712)},
+	})
+	resolver := New(10)
+	defer resolver.Close()
+
+	// Act
+	err := resolver.Open(countryPath, asnPath)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, Location{Country: "GB", ASN: 20712}, resolver.Lookup(netip.MustParseAddr("81.2.69.160")))
+	assert.Equal(t, Location{Country: "GB", ASN: 20712}, resolver.Lookup(netip.MustParseAddr("::ffff:81.2.69.160")))
+	assert.Equal(t, Location{Country: "DE"}, resolver.Lookup(netip.MustParseAddr("2a02:8100::1")))
+	assert.Equal(t, Location{}, resolver.Lookup(netip.MustParseAddr("8.8.8.8")))
+	assert.True(t, resolver.HasASN())
+}
+
+func TestOpen_WhenFileReplacedOrPathChanged_ForgetsCachedAnswers(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "country.mmdb")
+	other := filepath.Join(dir, "other.mmdb")
+	writeDatabase(t, path, map[string]mmdbtype.Map{"81.2.69.0/24": country("GB")})
+	writeDatabase(t, other, map[string]mmdbtype.Map{"81.2.69.0/24": country("FR")})
+	resolver := New(10)
+	defer resolver.Close()
+	require.NoError(t, resolver.Open(path, ""))
+	addr := netip.MustParseAddr("81.2.69.1")
+	before := resolver.Lookup(addr)
+
+	// Act
+	writeDatabase(t, path, map[string]mmdbtype.Map{"81.2.69.0/24": country("IE")})
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Hour)))
+	require.NoError(t, resolver.Open(path, ""))
+	replaced := resolver.Lookup(addr)
+	require.NoError(t, resolver.Open(other, ""))
+	switched := resolver.Lookup(addr)
+
+	// Assert
+	assert.Equal(t, "GB", before.Country)
+	assert.Equal(t, "IE", replaced.Country)
+	assert.Equal(t, "FR", switched.Country)
+	assert.False(t, resolver.HasASN())
+}
+
+func TestOpen_WhenFileBroken_KeepsPreviousDatabase(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	path := filepath.Join(dir, "country.mmdb")
+	broken := filepath.Join(dir, "broken.mmdb")
+	writeDatabase(t, path, map[string]mmdbtype.Map{"81.2.69.0/24": country("GB")})
+	require.NoError(t, os.WriteFile(broken, []byte("not a database"), 0o644))
+	resolver := New(10)
+	defer resolver.Close()
+	require.NoError(t, resolver.Open(path, ""))
+
+	// Act
+	err := resolver.Open(broken, "")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, "GB", resolver.Lookup(netip.MustParseAddr("81.2.69.1")).Country)
+}
+
+func TestCache_WhenFull_DropsLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	c := newCache(2)
+	first, second, third := netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("10.0.0.3")
+	c.put(first, Location{Country: "GB"})
+	c.put(second, Location{Country: "DE"})
+	c.get(first)
+
+	// Act
+	c.put(third, Location{Country: "FR"})
+
+	// Assert
+	_, hasFirst := c.get(first)
+	_, hasSecond := c.get(second)
+	assert.True(t, hasFirst)
+	assert.False(t, hasSecond)
+}
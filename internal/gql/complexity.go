@@ -0,0 +1,183 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// how many entries a list field without a limit argument is assumed to return
+var listSizes = map[string]int{
+	"Query.stats":        21,
+	"Reforge.stats":      8,
+	"Reforge.costs":      8,
+	"RarityStats.values": 21,
+}
+
+const defaultListSize = 10
+
+// extra cost of fields that make a storage round trip every time they resolve
+var fieldCosts = map[string]int{
+	"Item.priceHistory": 10,
+}
+
+// the static cost of a query, worked out before any resolver runs
+type cost struct {
+	Complexity int
+	Depth      int
+}
+
+// every field costs one, a list field costs its expected length times the cost of its selection
+// and fields reading storage add the cost of the round trip
+func measure(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) (cost, error) {
+	m := &measurer{variables: variables, fragments: make(map[string]*ast.FragmentDefinition)}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch d := definition.(type) {
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operation = d
+			}
+		case *ast.FragmentDefinition:
+			m.fragments[d.Name.Value] = d
+		}
+	}
+	if operation == nil {
+		return cost{}, fmt.Errorf("unknown operation %q", operationName)
+	}
+	if operation.Operation != ast.OperationTypeQuery {
+		return cost{}, fmt.Errorf("only queries are supported")
+	}
+
+	complexity := m.selectionSet(schema.QueryType(), operation.SelectionSet, 1)
+	return cost{Complexity: complexity, Depth: m.depth}, nil
+}
+
+type measurer struct {
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	depth     int
+}
+
+func (m *measurer) selectionSet(parent *graphql.Object, set *ast.SelectionSet, depth int) int {
+	if set == nil {
+		return 0
+	}
+	total := 0
+	for _, selection := range set.Selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			total += m.field(parent, s, depth)
+		case *ast.InlineFragment:
+			total += m.selectionSet(parent, s.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			if fragment, ok := m.fragments[s.Name.Value]; ok {
+				total += m.selectionSet(parent, fragment.SelectionSet, depth)
+			}
+		}
+	}
+	return total
+}
+
+func (m *measurer) field(parent *graphql.Object, field *ast.Field, depth int) int {
+	if depth > m.depth {
+		m.depth = depth
+	}
+
+	// introspection fields are not part of the object and cost the same as a scalar
+	definition, ok := parent.Fields()[field.Name.Value]
+	if !ok {
+		return 1
+	}
+
+	own := 1 + fieldCosts[parent.Name()+"."+definition.Name]
+	children := 0
+	if object, ok := namedType(definition.Type).(*graphql.Object); ok {
+		children = m.selectionSet(object, field.SelectionSet, depth+1)
+	}
+	if !isList(definition.Type) {
+		return own + children
+	}
+	return own + m.listSize(parent, definition, field)*children
+}
+
+func (m *measurer) listSize(parent *graphql.Object, definition *graphql.FieldDefinition, field *ast.Field) int {
+	for _, arg := range definition.Args {
+		switch arg.Name() {
+		case "limit":
+			// resolvers reject limits below one, they must not lower the cost of the rest of the query
+			if limit, ok := m.intArgument(field, "limit", arg.DefaultValue); ok {
+				return max(limit, 1)
+			}
+		case "rarity":
+			// asking for a single rarity returns at most one entry
+			if value := m.argument(field, "rarity"); value != nil {
+				return 1
+			}
+		}
+	}
+	if size, ok := listSizes[parent.Name()+"."+definition.Name]; ok {
+		return size
+	}
+	return defaultListSize
+}
+
+// returns the value given for an argument, resolving variables, nil when it was left out
+func (m *measurer) argument(field *ast.Field, name string) interface{} {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != name {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.Variable:
+			return m.variables[v.Name.Value]
+		case *ast.IntValue:
+			n, err := strconv.Atoi(v.Value)
+			if err != nil {
+				return nil
+			}
+			return n
+		default:
+			return v.GetValue()
+		}
+	}
+	return nil
+}
+
+func (m *measurer) intArgument(field *ast.Field, name string, fallback interface{}) (int, bool) {
+	value := m.argument(field, name)
+	if value == nil {
+		value = fallback
+	}
+	switch n := value.(type) {
+	case int:
+		return n, true
+	case float64:
+		// json numbers in variables decode as floats
+		return int(n), true
+	}
+	return 0, false
+}
+
+func namedType(t graphql.Type) graphql.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func isList(t graphql.Type) bool {
+	if nonNull, ok := t.(*graphql.NonNull); ok {
+		t = nonNull.OfType
+	}
+	_, ok := t.(*graphql.List)
+	return ok
+}
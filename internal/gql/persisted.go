@@ -0,0 +1,86 @@
+package gql
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// the automatic persisted query extension, clients send the hash first and the full query only when it is unknown
+type PersistedQuery struct {
+	Version    int    `json:"version"`
+	SHA256Hash string `json:"sha256Hash"`
+}
+
+// remembers recently used queries by hash, least recently used ones are dropped first
+type persistedQueries struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type persistedEntry struct {
+	hash  string
+	query string
+}
+
+func newPersistedQueries(size int) *persistedQueries {
+	return &persistedQueries{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// returns the query text of a request, looking it up by hash when only the hash was sent
+func (p *persistedQueries) resolve(req Request) (string, error) {
+	persisted := req.Extensions.PersistedQuery
+	if persisted == nil {
+		if req.Query == "" {
+			return "", &requestError{code: "BAD_REQUEST", message: "query is required"}
+		}
+		return req.Query, nil
+	}
+	if persisted.Version != 1 {
+		return "", &requestError{code: "PERSISTED_QUERY_NOT_SUPPORTED", message: "PersistedQueryNotSupported"}
+	}
+
+	hash := strings.ToLower(persisted.SHA256Hash)
+	if req.Query != "" {
+		sum := sha256.Sum256([]byte(req.Query))
+		if hex.EncodeToString(sum[:]) != hash {
+			return "", &requestError{code: "BAD_REQUEST", message: "provided sha256Hash does not match query"}
+		}
+		return req.Query, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	element, ok := p.entries[hash]
+	if !ok {
+		// the message is what apollo clients look for before resending with the full query
+		return "", &requestError{code: "PERSISTED_QUERY_NOT_FOUND", message: "PersistedQueryNotFound"}
+	}
+	p.order.MoveToFront(element)
+	return element.Value.(*persistedEntry).query, nil
+}
+
+// keeps a query that was sent in full together with its hash
+func (p *persistedQueries) store(req Request, query string) {
+	if req.Extensions.PersistedQuery == nil || req.Query == "" {
+		return
+	}
+	hash := strings.ToLower(req.Extensions.PersistedQuery.SHA256Hash)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if element, ok := p.entries[hash]; ok {
+		p.order.MoveToFront(element)
+		return
+	}
+	p.entries[hash] = p.order.PushFront(&persistedEntry{hash: hash, query: query})
+	for p.order.Len() > p.size {
+		oldest := p.order.Back()
+		p.order.Remove(oldest)
+		delete(p.entries, oldest.Value.(*persistedEntry).hash)
+	}
+}
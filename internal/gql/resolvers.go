@@ -0,0 +1,208 @@
+package gql
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"yard-backend/internal/models"
+	"yard-backend/internal/services"
+)
+
+// resolves the root and relation fields against the same service the rest handlers use
+type resolvers struct {
+	svc *services.Service
+}
+
+// logs a storage failure in full and answers with a message that is safe to show
+func storageError(what string, err error) error {
+	log.Printf("Error loading %s for graphql: %v", what, err)
+	return &requestError{code: "STORAGE_UNAVAILABLE", message: "storage is not available"}
+}
+
+func (r *resolvers) model(p graphql.ResolveParams) (*services.ReadModel, error) {
+	model, err := r.svc.ReadModel(p.Context)
+	if err != nil {
+		return nil, storageError("read model", err)
+	}
+	return model, nil
+}
+
+func (r *resolvers) items(p graphql.ResolveParams) (interface{}, error) {
+	limit, err := limitArg(p)
+	if err != nil {
+		return nil, err
+	}
+	model, err := r.model(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids map[string]bool
+	if list, ok := p.Args["ids"].([]interface{}); ok {
+		ids = make(map[string]bool, len(list))
+		for _, id := range list {
+			if s, ok := id.(string); ok {
+				ids[strings.ToUpper(s)] = true
+			}
+		}
+	}
+	search, _ := p.Args["search"].(string)
+	tier, _ := p.Args["tier"].(string)
+	includeDelisted, _ := p.Args["includeDelisted"].(bool)
+
+	items := make([]models.Item, 0)
+	for _, stone := range model.Stones {
+		switch {
+		case ids != nil && !ids[stone.ID]:
+		case search != "" && !strings.Contains(strings.ToLower(stone.Name), strings.ToLower(search)):
+		case tier != "" && !strings.EqualFold(stone.Tier, tier):
+		case !includeDelisted && stone.DelistedAt != nil:
+		default:
+			items = append(items, stone)
+		}
+	}
+	return truncate(items, limit), nil
+}
+
+func (r *resolvers) item(p graphql.ResolveParams) (interface{}, error) {
+	model, err := r.model(p)
+	if err != nil {
+		return nil, err
+	}
+	id, _ := p.Args["id"].(string)
+	return findStone(model, id), nil
+}
+
+func (r *resolvers) reforges(p graphql.ResolveParams) (interface{}, error) {
+	limit, err := limitArg(p)
+	if err != nil {
+		return nil, err
+	}
+	model, err := r.model(p)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := p.Args["name"].(string)
+	itemType, _ := p.Args["itemType"].(string)
+	rarity, _ := p.Args["rarity"].(string)
+	source, _ := p.Args["source"].(string)
+	maxStonePrice, filterPrice := p.Args["maxStonePrice"].(float64)
+
+	reforges := make([]models.Reforge, 0)
+	for _, reforge := range model.Reforges {
+		_, hasRarity := reforge.ReforgeStats[strings.ToUpper(rarity)]
+		switch {
+		case name != "" && !strings.EqualFold(reforge.ReforgeName, name):
+		case itemType != "" && !appliesTo(reforge, itemType):
+		case rarity != "" && !hasRarity:
+		case source != "" && !strings.EqualFold(reforge.Source, source):
+		// blacksmith reforges and stones without a price can't be compared
+		case filterPrice && (reforge.StonePrice == nil || float64(*reforge.StonePrice) > maxStonePrice):
+		default:
+			reforges = append(reforges, reforge)
+		}
+	}
+	return truncate(reforges, limit), nil
+}
+
+func (r *resolvers) reforge(p graphql.ResolveParams) (interface{}, error) {
+	model, err := r.model(p)
+	if err != nil {
+		return nil, err
+	}
+	name, _ := p.Args["name"].(string)
+	for _, reforge := range model.Reforges {
+		if strings.EqualFold(reforge.ReforgeName, name) {
+			return reforge, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *resolvers) reforgeStone(p graphql.ResolveParams) (interface{}, error) {
+	reforge, ok := p.Source.(models.Reforge)
+	if !ok {
+		return nil, fmt.Errorf("unexpected source %T", p.Source)
+	}
+	if reforge.StoneID == "" {
+		return nil, nil
+	}
+	model, err := r.model(p)
+	if err != nil {
+		return nil, err
+	}
+	return findStone(model, reforge.StoneID), nil
+}
+
+func (r *resolvers) stoneReforge(p graphql.ResolveParams) (interface{}, error) {
+	stone, ok := p.Source.(models.Item)
+	if !ok {
+		return nil, fmt.Errorf("unexpected source %T", p.Source)
+	}
+	model, err := r.model(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, reforge := range model.Reforges {
+		if reforge.StoneID == stone.ID {
+			return reforge, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *resolvers) orders(read func(models.Item) []models.BazaarOrder) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		stone, ok := p.Source.(models.Item)
+		if !ok {
+			return nil, fmt.Errorf("unexpected source %T", p.Source)
+		}
+		limit, err := limitArg(p)
+		if err != nil {
+			return nil, err
+		}
+		orders := read(stone)
+		if orders == nil {
+			orders = []models.BazaarOrder{}
+		}
+		return truncate(orders, limit), nil
+	}
+}
+
+func (r *resolvers) priceHistory(p graphql.ResolveParams) (interface{}, error) {
+	stone, ok := p.Source.(models.Item)
+	if !ok {
+		return nil, fmt.Errorf("unexpected source %T", p.Source)
+	}
+	limit, err := limitArg(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var since time.Time
+	if value, ok := p.Args["since"].(string); ok {
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			return nil, fmt.Errorf("since must be an RFC3339 time: %w", err)
+		}
+	}
+
+	history, err := r.svc.PriceHistory(p.Context, stone.ID, since, limit)
+	if err != nil {
+		return nil, storageError("price history of "+stone.ID, err)
+	}
+	return history, nil
+}
+
+func findStone(model *services.ReadModel, id string) interface{} {
+	for _, stone := range model.Stones {
+		if strings.EqualFold(stone.ID, id) {
+			return stone
+		}
+	}
+	return nil
+}
@@ -0,0 +1,431 @@
+package gql
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"yard-backend/internal/models"
+	"yard-backend/internal/services"
+)
+
+// rarities in game order, reforge stats and costs are listed in this order
+var rarityOrder = []string{"COMMON", "UNCOMMON", "RARE", "EPIC", "LEGENDARY", "MYTHIC", "DIVINE", "SPECIAL", "VERY_SPECIAL"}
+
+// the stats of a reforge at one rarity
+type rarityStats struct {
+	Rarity string
+	Stats  models.ReforgeStats
+}
+
+type rarityCost struct {
+	Rarity string
+	Cost   int
+}
+
+type statValue struct {
+	Stat  string
+	Value float64
+}
+
+// resolves every field from the service layer, items and reforges come from the read model
+func newSchema(svc *services.Service) (graphql.Schema, error) {
+	r := &resolvers{svc: svc}
+
+	statType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Stat",
+		Description: "A stat a reforge can give",
+		Fields: graphql.Fields{
+			"key":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(s models.StatInfo) interface{} { return s.Key })},
+			"name":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(s models.StatInfo) interface{} { return s.Name })},
+			"percent": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: from(func(s models.StatInfo) interface{} { return s.Percent })},
+		},
+	})
+
+	statValueType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StatValue",
+		Fields: graphql.Fields{
+			"stat":  &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(s statValue) interface{} { return s.Stat })},
+			"value": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: from(func(s statValue) interface{} { return s.Value })},
+		},
+	})
+
+	rarityStatsFields := graphql.Fields{
+		"rarity": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(s rarityStats) interface{} { return s.Rarity })},
+		"values": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statValueType))),
+			Description: "Every stat the reforge gives at this rarity",
+			Resolve:     from(func(s rarityStats) interface{} { return statValues(s.Stats) }),
+		},
+	}
+	for _, stat := range services.StatMetadata() {
+		key := stat.Key
+		rarityStatsFields[camelCase(key)] = &graphql.Field{
+			Type:        graphql.Float,
+			Description: stat.Name,
+			Resolve: from(func(s rarityStats) interface{} {
+				if value, ok := services.StatValues(s.Stats)[key]; ok {
+					return value
+				}
+				return nil
+			}),
+		}
+	}
+	rarityStatsType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "RarityStats",
+		Description: "The stats a reforge gives at one rarity, stats it doesn't give are null",
+		Fields:      rarityStatsFields,
+	})
+
+	rarityCostType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "RarityCost",
+		Fields: graphql.Fields{
+			"rarity": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(c rarityCost) interface{} { return c.Rarity })},
+			"cost":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: from(func(c rarityCost) interface{} { return c.Cost })},
+		},
+	})
+
+	bazaarOrderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BazaarOrder",
+		Fields: graphql.Fields{
+			"amount":       &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: from(func(o models.BazaarOrder) interface{} { return float64(o.Amount) })},
+			"pricePerUnit": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: from(func(o models.BazaarOrder) interface{} { return o.PricePerUnit })},
+			"orders":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: from(func(o models.BazaarOrder) interface{} { return o.Orders })},
+		},
+	})
+
+	pricePointType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PricePoint",
+		Description: "The prices of a stone after a refresh that changed them",
+		Fields: graphql.Fields{
+			"at":              &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(p models.PricePoint) interface{} { return p.At.UTC().Format(time.RFC3339) })},
+			"auctionPrice":    &graphql.Field{Type: graphql.Float, Resolve: from(func(p models.PricePoint) interface{} { return intPrice(p.AuctionPrice) })},
+			"bazaarBuyPrice":  &graphql.Field{Type: graphql.Float, Resolve: from(func(p models.PricePoint) interface{} { return floatPrice(p.BazaarBuyPrice) })},
+			"bazaarSellPrice": &graphql.Field{Type: graphql.Float, Resolve: from(func(p models.PricePoint) interface{} { return floatPrice(p.BazaarSellPrice) })},
+		},
+	})
+
+	itemType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Item",
+		Description: "A reforge stone as listed by Hypixel with its latest prices",
+		Fields: graphql.Fields{
+			"id":              &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(i models.Item) interface{} { return i.ID })},
+			"name":            &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(i models.Item) interface{} { return i.Name })},
+			"category":        &graphql.Field{Type: graphql.String, Resolve: from(func(i models.Item) interface{} { return i.Category })},
+			"tier":            &graphql.Field{Type: graphql.String, Resolve: from(func(i models.Item) interface{} { return i.Tier })},
+			"npcSellPrice":    &graphql.Field{Type: graphql.Float, Resolve: from(func(i models.Item) interface{} { return number(i.NPCSellPrice) })},
+			"delistedAt":      &graphql.Field{Type: graphql.String, Resolve: from(func(i models.Item) interface{} { return timestamp(i.DelistedAt) })},
+			"auctionPrice":    &graphql.Field{Type: graphql.Float, Resolve: from(func(i models.Item) interface{} { return intPrice(i.AuctionPrice) })},
+			"bazaarBuyPrice":  &graphql.Field{Type: graphql.Float, Resolve: from(func(i models.Item) interface{} { return floatPrice(i.BazaarBuyPrice) })},
+			"bazaarSellPrice": &graphql.Field{Type: graphql.Float, Resolve: from(func(i models.Item) interface{} { return floatPrice(i.BazaarSellPrice) })},
+			"bestPrice": &graphql.Field{
+				Type:        graphql.Float,
+				Description: "Auction price, falling back to the bazaar buy and then sell price",
+				Resolve:     from(func(i models.Item) interface{} { return bestPrice(i) }),
+			},
+			"bazaarBuyOrders": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bazaarOrderType))),
+				Args:    limitArgs(10),
+				Resolve: r.orders(func(i models.Item) []models.BazaarOrder { return i.BazaarBuyOrders }),
+			},
+			"bazaarSellOrders": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(bazaarOrderType))),
+				Args:    limitArgs(10),
+				Resolve: r.orders(func(i models.Item) []models.BazaarOrder { return i.BazaarSellOrders }),
+			},
+			"priceHistory": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(pricePointType))),
+				Description: "Price changes newest first, optionally only those after since (RFC3339)",
+				Args: graphql.FieldConfigArgument{
+					"since": &graphql.ArgumentConfig{Type: graphql.String},
+					"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 100},
+				},
+				Resolve: r.priceHistory,
+			},
+		},
+	})
+
+	reforgeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Reforge",
+		Description: "A reforge from the NEU repository merged with the price of its stone",
+		Fields: graphql.Fields{
+			"name":             &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(f models.Reforge) interface{} { return f.ReforgeName })},
+			"itemTypes":        &graphql.Field{Type: graphql.String, Resolve: from(func(f models.Reforge) interface{} { return f.ItemTypes })},
+			"requiredRarities": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), Resolve: from(func(f models.Reforge) interface{} { return f.RequiredRarities })},
+			"source":           &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: from(func(f models.Reforge) interface{} { return f.Source })},
+			"stoneId":          &graphql.Field{Type: graphql.String, Resolve: from(func(f models.Reforge) interface{} { return optionalString(f.StoneID) })},
+			"stoneName":        &graphql.Field{Type: graphql.String, Resolve: from(func(f models.Reforge) interface{} { return optionalString(f.StoneName) })},
+			"stoneTier":        &graphql.Field{Type: graphql.String, Resolve: from(func(f models.Reforge) interface{} { return optionalString(f.StoneTier) })},
+			"stonePrice":       &graphql.Field{Type: graphql.Float, Resolve: from(func(f models.Reforge) interface{} { return intPrice(f.StonePrice) })},
+			"stone":            &graphql.Field{Type: itemType, Resolve: r.reforgeStone},
+			"stats": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(rarityStatsType))),
+				Description: "Stats per rarity in game order, or only those of rarity",
+				Args:        graphql.FieldConfigArgument{"rarity": &graphql.ArgumentConfig{Type: graphql.String}},
+				Resolve:     reforgeStats,
+			},
+			"costs": &graphql.Field{
+				Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(rarityCostType))),
+				Resolve: from(func(f models.Reforge) interface{} { return reforgeCosts(f) }),
+			},
+			"ability": &graphql.Field{
+				Type:        graphql.String,
+				Description: "The reforge ability, abilities that differ per rarity need rarity",
+				Args:        graphql.FieldConfigArgument{"rarity": &graphql.ArgumentConfig{Type: graphql.String}},
+				Resolve:     reforgeAbility,
+			},
+		},
+	})
+
+	// items and reforges refer to each other
+	itemType.AddFieldConfig("reforge", &graphql.Field{
+		Type:        reforgeType,
+		Description: "The reforge this stone applies",
+		Resolve:     r.stoneReforge,
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"items": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(itemType))),
+				Description: "Reforge stones, filtered by every argument given",
+				Args: graphql.FieldConfigArgument{
+					"ids":             &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"search":          &graphql.ArgumentConfig{Type: graphql.String, Description: "Case insensitive part of the name"},
+					"tier":            &graphql.ArgumentConfig{Type: graphql.String},
+					"includeDelisted": &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: true},
+					"limit":           &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 100},
+				},
+				Resolve: r.items,
+			},
+			"item": &graphql.Field{
+				Type:    itemType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: r.item,
+			},
+			"reforges": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(reforgeType))),
+				Description: "Reforges sorted by name, filtered by every argument given",
+				Args: graphql.FieldConfigArgument{
+					"name":          &graphql.ArgumentConfig{Type: graphql.String, Description: "Case insensitive reforge name"},
+					"itemType":      &graphql.ArgumentConfig{Type: graphql.String, Description: "Item type the reforge applies to, e.g. SWORD"},
+					"rarity":        &graphql.ArgumentConfig{Type: graphql.String, Description: "Only reforges with stats for this rarity"},
+					"source":        &graphql.ArgumentConfig{Type: graphql.String, Description: "Blacksmith or Reforge Stone"},
+					"maxStonePrice": &graphql.ArgumentConfig{Type: graphql.Float, Description: "Only stone reforges whose stone costs at most this many coins"},
+					"limit":         &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 100},
+				},
+				Resolve: r.reforges,
+			},
+			"reforge": &graphql.Field{
+				Type:    reforgeType,
+				Args:    graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: r.reforge,
+			},
+			"stats": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(statType))),
+				Description: "Every stat a reforge can give",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return services.StatMetadata(), nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// wraps a resolver that only reads from its typed source
+func from[T any](read func(T) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		value, ok := p.Source.(T)
+		if !ok {
+			return nil, fmt.Errorf("unexpected source %T", p.Source)
+		}
+		return read(value), nil
+	}
+}
+
+func limitArgs(fallback int) graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{"limit": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: fallback}}
+}
+
+// largest limit any list field accepts
+const maxLimit = 1000
+
+func limitArg(p graphql.ResolveParams) (int, error) {
+	limit, _ := p.Args["limit"].(int)
+	if limit < 1 || limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d, got %d", maxLimit, limit)
+	}
+	return limit, nil
+}
+
+func truncate[T any](values []T, limit int) []T {
+	if len(values) > limit {
+		return values[:limit]
+	}
+	return values
+}
+
+// converts "crit_chance" to "critChance"
+func camelCase(key string) string {
+	words := strings.Split(key, "_")
+	for i := 1; i < len(words); i++ {
+		words[i] = strings.ToUpper(words[i][:1]) + words[i][1:]
+	}
+	return strings.Join(words, "")
+}
+
+// returns nil for a missing price so graphql renders null
+func intPrice(price *int64) interface{} {
+	if price == nil {
+		return nil
+	}
+	return float64(*price)
+}
+
+func floatPrice(price *float64) interface{} {
+	if price == nil {
+		return nil
+	}
+	return *price
+}
+
+func bestPrice(item models.Item) interface{} {
+	switch {
+	case item.AuctionPrice != nil:
+		return float64(*item.AuctionPrice)
+	case item.BazaarBuyPrice != nil:
+		return *item.BazaarBuyPrice
+	case item.BazaarSellPrice != nil:
+		return *item.BazaarSellPrice
+	}
+	return nil
+}
+
+func number(value interface{}) interface{} {
+	if n, ok := value.(float64); ok {
+		return n
+	}
+	return nil
+}
+
+func timestamp(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func optionalString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func statValues(stats models.ReforgeStats) []statValue {
+	values := make([]statValue, 0)
+	for _, stat := range services.StatMetadata() {
+		if value, ok := services.StatValues(stats)[stat.Key]; ok {
+			values = append(values, statValue{Stat: stat.Key, Value: value})
+		}
+	}
+	return values
+}
+
+// sorts rarity keys in game order, unknown rarities go last by name
+func sortedRarities[V any](values map[string]V) []string {
+	rank := func(rarity string) int {
+		for i, known := range rarityOrder {
+			if known == rarity {
+				return i
+			}
+		}
+		return len(rarityOrder)
+	}
+	rarities := make([]string, 0, len(values))
+	for rarity := range values {
+		rarities = append(rarities, rarity)
+	}
+	sort.Slice(rarities, func(i, j int) bool {
+		ri, rj := rank(rarities[i]), rank(rarities[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return rarities[i] < rarities[j]
+	})
+	return rarities
+}
+
+func reforgeStats(p graphql.ResolveParams) (interface{}, error) {
+	reforge, ok := p.Source.(models.Reforge)
+	if !ok {
+		return nil, fmt.Errorf("unexpected source %T", p.Source)
+	}
+	if rarity, ok := p.Args["rarity"].(string); ok {
+		stats, found := reforge.ReforgeStats[strings.ToUpper(rarity)]
+		if !found {
+			return []rarityStats{}, nil
+		}
+		return []rarityStats{{Rarity: strings.ToUpper(rarity), Stats: stats}}, nil
+	}
+
+	stats := make([]rarityStats, 0, len(reforge.ReforgeStats))
+	for _, rarity := range sortedRarities(reforge.ReforgeStats) {
+		stats = append(stats, rarityStats{Rarity: rarity, Stats: reforge.ReforgeStats[rarity]})
+	}
+	return stats, nil
+}
+
+func reforgeCosts(reforge models.Reforge) []rarityCost {
+	costs := make([]rarityCost, 0, len(reforge.ReforgeCosts))
+	for _, rarity := range sortedRarities(reforge.ReforgeCosts) {
+		costs = append(costs, rarityCost{Rarity: rarity, Cost: reforge.ReforgeCosts[rarity]})
+	}
+	return costs
+}
+
+// neu stores the ability either as one text or as a text per rarity
+func reforgeAbility(p graphql.ResolveParams) (interface{}, error) {
+	reforge, ok := p.Source.(models.Reforge)
+	if !ok {
+		return nil, fmt.Errorf("unexpected source %T", p.Source)
+	}
+	switch ability := reforge.ReforgeAbility.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return ability, nil
+	case map[string]interface{}:
+		rarity, _ := p.Args["rarity"].(string)
+		if text, ok := ability[strings.ToUpper(rarity)].(string); ok {
+			return text, nil
+		}
+		return nil, nil
+	default:
+		data, err := json.Marshal(ability)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+}
+
+// reports whether a reforge applies to the item type, neu lists several types separated by slashes or commas
+func appliesTo(reforge models.Reforge, itemType string) bool {
+	types := strings.FieldsFunc(strings.TrimPrefix(reforge.ItemTypes, "SPECIFIC:"), func(r rune) bool {
+		return r == '/' || r == ',' || r == ' '
+	})
+	for _, t := range types {
+		if strings.EqualFold(t, itemType) {
+			return true
+		}
+	}
+	return false
+}
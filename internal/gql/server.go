@@ -0,0 +1,113 @@
+package gql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"yard-backend/internal/services"
+)
+
+// a graphql request as sent in a json body or as get parameters
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    Extensions             `json:"extensions"`
+}
+
+type Extensions struct {
+	PersistedQuery *PersistedQuery `json:"persistedQuery,omitempty"`
+}
+
+// limits a query has to stay within before it is executed
+type Limits struct {
+	MaxComplexity int
+	MaxDepth      int
+}
+
+// executes graphql queries against the service layer
+type Server struct {
+	schema    graphql.Schema
+	persisted *persistedQueries
+}
+
+// builds the schema once, persistedQueries bounds how many automatic persisted queries are kept
+func NewServer(svc *services.Service, persistedQueries int) (*Server, error) {
+	schema, err := newSchema(svc)
+	if err != nil {
+		return nil, fmt.Errorf("building graphql schema: %w", err)
+	}
+	return &Server{schema: schema, persisted: newPersistedQueries(persistedQueries)}, nil
+}
+
+// parses, validates, measures and runs a request, every failure is reported in the result's errors
+func (s *Server) Execute(ctx context.Context, req Request, limits Limits) *graphql.Result {
+	query, err := s.persisted.resolve(req)
+	if err != nil {
+		return errorResult(err)
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.FormatError(err)}}
+	}
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	cost, err := measure(&s.schema, doc, req.OperationName, req.Variables)
+	if err != nil {
+		return errorResult(err)
+	}
+	if cost.Depth > limits.MaxDepth {
+		return errorResult(&requestError{code: "QUERY_TOO_DEEP", message: fmt.Sprintf("query depth %d exceeds the limit of %d", cost.Depth, limits.MaxDepth)})
+	}
+	if cost.Complexity > limits.MaxComplexity {
+		return errorResult(&requestError{code: "QUERY_TOO_COMPLEX", message: fmt.Sprintf("query complexity %d exceeds the limit of %d", cost.Complexity, limits.MaxComplexity)})
+	}
+
+	// only queries that made it this far are worth remembering
+	s.persisted.store(req, query)
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	if result.Extensions == nil {
+		result.Extensions = make(map[string]interface{})
+	}
+	result.Extensions["complexity"] = cost.Complexity
+	return result
+}
+
+// an error with a stable code for clients, code ends up in the error's extensions
+// returned before execution for rejected requests and by resolvers for failures that must not leak details
+type requestError struct {
+	code    string
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// lets graphql-go copy the code of resolver errors into the response
+func (e *requestError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func errorResult(err error) *graphql.Result {
+	formatted := gqlerrors.FormattedError{Message: err.Error()}
+	if rejected, ok := err.(*requestError); ok {
+		formatted.Extensions = rejected.Extensions()
+	}
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+}
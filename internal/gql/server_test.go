@@ -0,0 +1,212 @@
+package gql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/services"
+	"yard-backend/internal/storage"
+)
+
+var testLimits = Limits{MaxComplexity: 10000, MaxDepth: 10}
+
+// writes neu constants files into a fresh repository directory and returns its path
+func writeNEURepo(t *testing.T, reforges, stones map[string]interface{}) string {
+	repo := t.TempDir()
+	constants := filepath.Join(repo, "constants")
+	require.NoError(t, os.MkdirAll(constants, 0o755))
+	for name, data := range map[string]map[string]interface{}{"reforges.json": reforges, "reforgestones.json": stones} {
+		encoded, err := json.Marshal(data)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(constants, name), encoded, 0o644))
+	}
+	return repo
+}
+
+// serves a blacksmith sword reforge, a cheap and an expensive sword stone and an armor stone
+func newTestServer(t *testing.T) *Server {
+	repo := writeNEURepo(t, map[string]interface{}{
+		"Epic": map[string]interface{}{
+			"itemTypes":    "SWORD",
+			"reforgeStats": map[string]interface{}{"LEGENDARY": map[string]interface{}{"strength": 15.0}},
+		},
+	}, map[string]interface{}{
+		"DRAGON_CLAW": map[string]interface{}{
+			"reforgeName":  "Fabled",
+			"itemTypes":    "SWORD",
+			"reforgeStats": map[string]interface{}{"LEGENDARY": map[string]interface{}{"strength": 30.0}},
+		},
+		"MIDAS_JEWEL": map[string]interface{}{
+			"reforgeName":  "Gilded",
+			"itemTypes":    "SWORD",
+			"reforgeStats": map[string]interface{}{"LEGENDARY": map[string]interface{}{"strength": 10.0}},
+		},
+		"LUCKY_DICE": map[string]interface{}{
+			"reforgeName":  "Lucky",
+			"itemTypes":    "ARMOR",
+			"reforgeStats": map[string]interface{}{"LEGENDARY": map[string]interface{}{"strength": 5.0}},
+		},
+	})
+
+	cheap, expensive, dice := int64(800000), int64(90000000), int64(1000)
+	store := storage.NewMemory()
+	_, err := store.Publish(context.Background(), []models.Item{
+		{ID: "DRAGON_CLAW", Name: "Dragon Claw", AuctionPrice: &cheap},
+		{ID: "MIDAS_JEWEL", Name: "Midas Jewel", AuctionPrice: &expensive},
+		{ID: "LUCKY_DICE", Name: "Lucky Dice", AuctionPrice: &dice},
+	}, 1)
+	require.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.NEU.RepoPath = repo
+	svc := services.New(cfg, store)
+	require.NoError(t, svc.LoadNEUReforges())
+	require.NoError(t, svc.LoadNEUReforgeStones())
+	server, err := NewServer(svc, 2)
+	require.NoError(t, err)
+	return server
+}
+
+func resultJSON(t *testing.T, server *Server, req Request, limits Limits) string {
+	data, err := json.Marshal(server.Execute(context.Background(), req, limits))
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestExecute_WhenFilteringReforges_ReturnsOnlyRequestedFields(t *testing.T) {
+	// Arrange
+	server := newTestServer(t)
+	query := `{ reforges(itemType: "SWORD", maxStonePrice: 1000000) { name stats(rarity: "LEGENDARY") { strength } } }`
+
+	// Act
+	result := resultJSON(t, server, Request{Query: query}, testLimits)
+
+	// Assert
+	assert.JSONEq(t, `{
+		"data": {"reforges": [{"name": "Fabled", "stats": [{"strength": 30}]}]},
+		"extensions": {"complexity": 301}
+	}`, result)
+}
+
+func TestExecute_WhenQueryTooComplex_RejectsItBeforeRunning(t *testing.T) {
+	// Arrange
+	server := newTestServer(t)
+	query := `{ items(limit: 500) { priceHistory(limit: 1000) { at } } }`
+
+	// Act
+	result := server.Execute(context.Background(), Request{Query: query}, testLimits)
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "QUERY_TOO_COMPLEX", result.Errors[0].Extensions["code"])
+	assert.Nil(t, result.Data)
+}
+
+func TestExecute_WhenQueryTooDeep_RejectsIt(t *testing.T) {
+	// Arrange
+	server := newTestServer(t)
+	query := `{ item(id: "DRAGON_CLAW") { reforge { stone { reforge { name } } } } }`
+
+	// Act
+	result := server.Execute(context.Background(), Request{Query: query}, Limits{MaxComplexity: 1000, MaxDepth: 4})
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "QUERY_TOO_DEEP", result.Errors[0].Extensions["code"])
+}
+
+func TestExecute_WhenPersistedQuerySentByHash_RunsItOnceKnown(t *testing.T) {
+	// Arrange
+	server := newTestServer(t)
+	query := `{ item(id: "LUCKY_DICE") { name auctionPrice } }`
+	sum := sha256.Sum256([]byte(query))
+	hashOnly := Request{Extensions: Extensions{PersistedQuery: &PersistedQuery{Version: 1, SHA256Hash: hex.EncodeToString(sum[:])}}}
+	withQuery := hashOnly
+	withQuery.Query = query
+
+	// Act
+	unknown := server.Execute(context.Background(), hashOnly, testLimits)
+	registered := server.Execute(context.Background(), withQuery, testLimits)
+	known := resultJSON(t, server, hashOnly, testLimits)
+
+	// Assert
+	require.Len(t, unknown.Errors, 1)
+	assert.Equal(t, "PersistedQueryNotFound", unknown.Errors[0].Message)
+	assert.Empty(t, registered.Errors)
+	assert.JSONEq(t, `{"data": {"item": {"name": "Lucky Dice", "auctionPrice": 1000}}, "extensions": {"complexity": 3}}`, known)
+}
+
+func TestExecute_WhenHashDoesNotMatchQuery_RejectsIt(t *testing.T) {
+	// Arrange
+	server := newTestServer(t)
+	req := Request{
+		Query:      `{ stats { key } }`,
+		Extensions: Extensions{PersistedQuery: &PersistedQuery{Version: 1, SHA256Hash: "deadbeef"}},
+	}
+
+	// Act
+	result := server.Execute(context.Background(), req, testLimits)
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "BAD_REQUEST", result.Errors[0].Extensions["code"])
+}
+
+func TestExecute_WhenFragmentsAndVariablesUsed_CountsTheirCost(t *testing.T) {
+	// Arrange
+	server := newTestServer(t)
+	query := `query Stones($n: Int) { items(limit: $n) { ...price } } fragment price on Item { id bestPrice }`
+
+	// Act
+	result := server.Execute(context.Background(), Request{Query: query, Variables: map[string]interface{}{"n": 2.0}}, testLimits)
+
+	// Assert
+	require.Empty(t, result.Errors)
+	assert.Equal(t, 5, result.Extensions["complexity"])
+	assert.Len(t, result.Data.(map[string]interface{})["items"], 2)
+}
+
+func TestExecute_WhenPriceHistoryRequestedPerItem_CountsEachStorageRead(t *testing.T) {
+	// Arrange
+	server := newTestServer(t)
+	query := `{ items(limit: 2) { priceHistory(limit: 3) { at } } }`
+
+	// Act
+	result := server.Execute(context.Background(), Request{Query: query}, testLimits)
+
+	// Assert
+	require.Empty(t, result.Errors)
+	// items 1 + 2 items * (priceHistory 1 + round trip 10 + 3 points * at 1)
+	assert.Equal(t, 29, result.Extensions["complexity"])
+}
+
+func TestExecute_WhenStorageUnavailable_HidesTheCause(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	store := storage.NewRedis(redis.NewClient(&redis.Options{Addr: addr}))
+	defer store.Close()
+	mr.Close()
+	server, err := NewServer(services.New(config.Default(), store), 2)
+	require.NoError(t, err)
+
+	// Act
+	result := server.Execute(context.Background(), Request{Query: `{ items { name } }`}, testLimits)
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "storage is not available", result.Errors[0].Message)
+	assert.Equal(t, "STORAGE_UNAVAILABLE", result.Errors[0].Extensions["code"])
+	assert.NotContains(t, result.Errors[0].Message, addr)
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"yard-backend/internal/jobs"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// writes an admin error in the same shape as the rate limiter
+func writeAdminError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   code,
+		"message": message,
+	})
+}
+
+// responds with 202 and the job so the caller can poll it
+func writeJobAccepted(w http.ResponseWriter, job *jobs.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/admin/jobs/"+job.ID())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(models.JobResponse{Success: true, Job: job.Status()})
+}
+
+// rejects jobs that write shared data when another replica holds the scheduler lease
+func (h *Handler) requireLeader(w http.ResponseWriter) bool {
+	if h.isLeader() {
+		return true
+	}
+	writeAdminError(w, http.StatusConflict, "not_leader", "this instance is not the scheduler leader, retry against the leader")
+	return false
+}
+
+// handles forced hypixel fetches followed by a full price refresh
+func (h *Handler) HandleAdminRefreshHypixel(w http.ResponseWriter, r *http.Request) {
+	if !h.requireLeader(w) {
+		return
+	}
+
+	job := h.Jobs.Start("refresh_hypixel", "", func(ctx context.Context, job *jobs.Job) error {
+		return h.svc.FetchAndStoreReforgeStonesWithProgress(ctx, true, job)
+	})
+	writeJobAccepted(w, job)
+}
+
+// handles price refreshes for every stone or for the stone named in the path
+func (h *Handler) HandleAdminRefreshPrices(w http.ResponseWriter, r *http.Request) {
+	if !h.requireLeader(w) {
+		return
+	}
+
+	stoneID := mux.Vars(r)["stoneId"]
+	var ids []string
+	if stoneID != "" {
+		known, err := h.svc.Store().HasStone(r.Context(), stoneID)
+		if err != nil {
+			writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage is not available")
+			return
+		}
+		if !known {
+			writeAdminError(w, http.StatusNotFound, "stone_not_found", fmt.Sprintf("no cached reforge stone with id %s", stoneID))
+			return
+		}
+		ids = []string{stoneID}
+	}
+
+	job := h.Jobs.Start("refresh_prices", stoneID, func(ctx context.Context, job *jobs.Job) error {
+		return h.svc.RefreshPricesWithProgress(ctx, ids, job)
+	})
+	writeJobAccepted(w, job)
+}
+
+// handles reloading reforges.json and reforgestones.json from the neu repository on this instance
+func (h *Handler) HandleAdminReloadNEU(w http.ResponseWriter, r *http.Request) {
+	job := h.Jobs.Start("reload_neu", "", func(ctx context.Context, job *jobs.Job) error {
+		job.SetTotal(2)
+		failed := 0
+		if err := h.svc.LoadNEUReforgeStones(); err != nil {
+			job.Fail("reforgestones.json", err)
+			failed++
+		} else {
+			job.Advance()
+		}
+		if err := h.svc.LoadNEUReforges(); err != nil {
+			job.Fail("reforges.json", err)
+			failed++
+		} else {
+			job.Advance()
+		}
+		// a file that did load still changes what /api/reforges serves
+		var rebuildErr error
+		if _, err := h.svc.RebuildReadModel(ctx); err != nil {
+			rebuildErr = fmt.Errorf("rebuilding read model: %w", err)
+		}
+		if failed > 0 {
+			return errors.Join(fmt.Errorf("%d of 2 neu files failed to load", failed), rebuildErr)
+		}
+		if rebuildErr != nil {
+			return rebuildErr
+		}
+		// every replica reloads its own files but the history is shared, so only the leader records it
+		if !h.isLeader() {
+			return nil
+		}
+		if err := h.svc.RecordReforgeHistory(ctx); err != nil {
+			return fmt.Errorf("recording reforge history: %w", err)
+		}
+		return nil
+	})
+	writeJobAccepted(w, job)
+}
+
+// handles re-reading the config file on this instance, same as sending SIGHUP
+func (h *Handler) HandleAdminReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if h.ReloadConfig == nil {
+		writeAdminError(w, http.StatusNotImplemented, "reload_unavailable", "config reload is not enabled on this instance")
+		return
+	}
+
+	result, err := h.ReloadConfig()
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid_config", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ConfigReloadResponse{
+		Success:         true,
+		Applied:         append([]string{}, result.Applied...),
+		RestartRequired: append([]string{}, result.RestartRequired...),
+	})
+}
+
+// handles rescanning the resource pack on this instance
+func (h *Handler) HandleAdminReloadResourcePack(w http.ResponseWriter, r *http.Request) {
+	job := h.Jobs.Start("reload_resource_pack", "", func(ctx context.Context, job *jobs.Job) error {
+		job.SetTotal(1)
+		if LoadResourcePack() == 0 {
+			return fmt.Errorf("no textures found in resource pack")
+		}
+		job.Advance()
+		return nil
+	})
+	writeJobAccepted(w, job)
+}
+
+// handles purging every cached stone so the next fetch starts from scratch
+func (h *Handler) HandleAdminPurgeCache(w http.ResponseWriter, r *http.Request) {
+	if !h.requireLeader(w) {
+		return
+	}
+
+	job := h.Jobs.Start("purge_cache", "", func(ctx context.Context, job *jobs.Job) error {
+		purged, err := h.svc.PurgeCache(ctx)
+		job.SetTotal(purged)
+		for i := 0; i < purged; i++ {
+			job.Advance()
+		}
+		return err
+	})
+	writeJobAccepted(w, job)
+}
+
+// handles listing the kept snapshots of the stone catalog
+func (h *Handler) HandleAdminSnapshots(w http.ResponseWriter, r *http.Request) {
+	h.writeSnapshots(w, r)
+}
+
+// handles pointing readers back at an earlier snapshot
+func (h *Handler) HandleAdminRollback(w http.ResponseWriter, r *http.Request) {
+	if !h.requireLeader(w) {
+		return
+	}
+
+	version, err := strconv.ParseInt(mux.Vars(r)["version"], 10, 64)
+	if err != nil || version <= 0 {
+		writeAdminError(w, http.StatusBadRequest, "invalid_version", "version must be a positive snapshot id")
+		return
+	}
+
+	if err := h.svc.Rollback(r.Context(), version); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writeAdminError(w, http.StatusNotFound, "version_not_found", fmt.Sprintf("snapshot %d is not kept anymore", version))
+			return
+		}
+		writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", err.Error())
+		return
+	}
+
+	h.writeSnapshots(w, r)
+}
+
+func (h *Handler) writeSnapshots(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.svc.Store().Versions(r.Context())
+	if err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage is not available")
+		return
+	}
+
+	snapshots := make([]models.Snapshot, len(versions))
+	for i, version := range versions {
+		snapshots[i] = models.Snapshot{
+			ID:          version.ID,
+			PublishedAt: version.PublishedAt,
+			Stones:      version.Stones,
+			Current:     version.Current,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.SnapshotsResponse{
+		Success:   true,
+		Count:     len(snapshots),
+		Snapshots: snapshots,
+	})
+}
+
+// handles listing recent admin jobs
+func (h *Handler) HandleAdminJobs(w http.ResponseWriter, r *http.Request) {
+	statuses := h.Jobs.List()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.JobsResponse{
+		Success: true,
+		Count:   len(statuses),
+		Jobs:    statuses,
+	})
+}
+
+// handles polling a single admin job
+func (h *Handler) HandleAdminJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.Jobs.Get(mux.Vars(r)["jobId"])
+	if !ok {
+		writeAdminError(w, http.StatusNotFound, "job_not_found", "no job with that id")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.JobResponse{Success: true, Job: job.Status()})
+}
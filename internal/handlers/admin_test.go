@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/config"
+	"yard-backend/internal/jobs"
+	"yard-backend/internal/models"
+	"yard-backend/internal/services"
+	"yard-backend/internal/storage"
+)
+
+func TestHandleAdminRefreshHypixel_WhenNotLeader_ReturnsConflict(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	h.LeaderCheck = func() bool { return false }
+
+	req := httptest.NewRequest("POST", "/admin/refresh/hypixel", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminRefreshHypixel(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestHandleAdminReloadNEU_WhenTriggered_ReturnsPollableJob(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := httptest.NewRequest("POST", "/admin/reload/neu", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminReloadNEU(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	var accepted models.JobResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &accepted))
+	assert.Equal(t, "reload_neu", accepted.Job.Type)
+	assert.Equal(t, "/admin/jobs/"+accepted.Job.ID, rr.Header().Get("Location"))
+
+	require.Eventually(t, func() bool {
+		pollReq := mux.SetURLVars(httptest.NewRequest("GET", "/admin/jobs/"+accepted.Job.ID, nil), map[string]string{"jobId": accepted.Job.ID})
+		pollRR := httptest.NewRecorder()
+		h.HandleAdminJob(pollRR, pollReq)
+
+		var polled models.JobResponse
+		if json.Unmarshal(pollRR.Body.Bytes(), &polled) != nil {
+			return false
+		}
+		return polled.Job.FinishedAt != nil && polled.Job.Total == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleAdminRollback_WhenVersionKept_ServesItAgain(t *testing.T) {
+	// Arrange
+	store := storage.NewMemory()
+	h := newTestHandler(config.Default(), store)
+	previous, _ := store.Publish(context.Background(), []models.Item{{ID: "AMBER", Name: "good"}}, 2)
+	store.Publish(context.Background(), []models.Item{{ID: "AMBER", Name: "broken"}}, 2)
+
+	version := fmt.Sprint(previous.ID)
+	req := mux.SetURLVars(httptest.NewRequest("POST", "/admin/snapshots/"+version+"/rollback", nil), map[string]string{"version": version})
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminRollback(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response models.SnapshotsResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, 2, response.Count)
+	assert.True(t, response.Snapshots[1].Current)
+	stone, _ := store.GetStone(context.Background(), "AMBER")
+	assert.Equal(t, "good", stone.Name)
+}
+
+func TestHandleAdminRollback_WhenVersionPruned_ReturnsNotFound(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := mux.SetURLVars(httptest.NewRequest("POST", "/admin/snapshots/42/rollback", nil), map[string]string{"version": "42"})
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminRollback(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestHandleAdminJob_WhenUnknownID_ReturnsNotFound(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/admin/jobs/nope", nil), map[string]string{"jobId": "nope"})
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminJob(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestHandleAdminReloadConfig_WhenOriginsChanged_AppliesThemLive(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("api:\n  allowed_origins: [\"https://new.example.com\"]\nserver:\n  listen_addr: \":9999\"\n"), 0o644))
+
+	cfg := config.Default()
+	h := newTestHandler(cfg)
+	reloader := config.NewReloader(path, cfg)
+	reloader.OnReload(h.Apply)
+	h.ReloadConfig = reloader.Reload
+
+	req := httptest.NewRequest("POST", "/admin/reload/config", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminReloadConfig(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	var response models.ConfigReloadResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []string{"api.allowed_origins"}, response.Applied)
+	assert.Equal(t, []string{"server.listen_addr"}, response.RestartRequired)
+
+	corsReq := httptest.NewRequest("GET", "/", nil)
+	corsReq.Header.Set("Origin", "https://new.example.com")
+	corsRR := httptest.NewRecorder()
+	h.EnableCORS(corsRR, corsReq)
+	assert.Equal(t, "https://new.example.com", corsRR.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestHandleAdminReloadConfig_WhenFileInvalid_KeepsCurrentConfig(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("api:\n  rate_limit_requests: -1\n"), 0o644))
+
+	cfg := config.Default()
+	h := newTestHandler(cfg)
+	reloader := config.NewReloader(path, cfg)
+	h.ReloadConfig = reloader.Reload
+
+	req := httptest.NewRequest("POST", "/admin/reload/config", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminReloadConfig(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "api.rate_limit_requests")
+	assert.Same(t, cfg, reloader.Current())
+}
+
+// polls a job through the admin api until it finished
+func waitForJob(t *testing.T, h *Handler, id string) models.JobStatus {
+	var polled models.JobResponse
+	require.Eventually(t, func() bool {
+		pollReq := mux.SetURLVars(httptest.NewRequest("GET", "/admin/jobs/"+id, nil), map[string]string{"jobId": id})
+		pollRR := httptest.NewRecorder()
+		h.HandleAdminJob(pollRR, pollReq)
+		return json.Unmarshal(pollRR.Body.Bytes(), &polled) == nil && polled.Job.FinishedAt != nil
+	}, time.Second, 5*time.Millisecond)
+	return polled.Job
+}
+
+func TestHandleAdminReloadNEU_WhenFollower_ReloadsWithoutRecordingHistory(t *testing.T) {
+	// Arrange
+	repo := t.TempDir()
+	constants := filepath.Join(repo, "constants")
+	require.NoError(t, os.MkdirAll(constants, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(constants, "reforges.json"), []byte(`{"Epic":{"itemTypes":"SWORD","reforgeStats":{"LEGENDARY":{"strength":15}}}}`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(constants, "reforgestones.json"), []byte(`{}`), 0o644))
+	cfg := config.Default()
+	cfg.NEU.RepoPath = repo
+	store := storage.NewMemory()
+	svc := services.New(cfg, store)
+	svc.WriteFence = func(ctx context.Context) error { return fmt.Errorf("not the leader") }
+	h := New(cfg, svc)
+	h.LeaderCheck = func() bool { return false }
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminReloadNEU(rr, httptest.NewRequest("POST", "/admin/reload/neu", nil))
+
+	// Assert
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	var accepted models.JobResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &accepted))
+	job := waitForJob(t, h, accepted.Job.ID)
+	assert.Equal(t, jobs.StateSucceeded, job.State)
+	assert.Empty(t, job.Errors)
+	snapshots, err := store.History(context.Background(), "neu_reforges", time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
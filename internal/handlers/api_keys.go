@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"yard-backend/internal/apikeys"
+	"yard-backend/internal/models"
+
+	"github.com/gorilla/mux"
+)
+
+// usage reports cover this many days unless the caller asks for another number
+const defaultUsageDays = 30
+
+// handles issuing a key, the response is the only place its token ever appears
+func (h *Handler) HandleAdminCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request models.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid_request", "body must be a json object with name and tier")
+		return
+	}
+	if request.Name == "" || len(request.Name) > 100 {
+		writeAdminError(w, http.StatusBadRequest, "invalid_request", "name must be between 1 and 100 characters")
+		return
+	}
+	if !apikeys.Issuable(request.Tier) {
+		writeAdminError(w, http.StatusBadRequest, "invalid_request", "tier must be free or partner")
+		return
+	}
+
+	key, token, err := apikeys.Issue(r.Context(), h.APIKeys, request.Name, request.Tier, time.Now())
+	if err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage is not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/admin/api-keys/"+key.ID+"/usage")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(models.APIKeyCreatedResponse{Success: true, Key: key, Token: token})
+}
+
+// handles listing every issued key, revoked ones included
+func (h *Handler) HandleAdminAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.APIKeys.List(r.Context())
+	if err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage is not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.APIKeysResponse{Success: true, Count: len(keys), Keys: keys})
+}
+
+// handles revoking a key, requests carrying it are rejected from then on
+func (h *Handler) HandleAdminRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.APIKeys.Revoke(r.Context(), mux.Vars(r)["keyId"], time.Now())
+	if errors.Is(err, apikeys.ErrNotFound) {
+		writeAdminError(w, http.StatusNotFound, "key_not_found", "no api key with that id")
+		return
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage is not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.APIKeyResponse{Success: true, Key: key})
+}
+
+// handles reporting what a key used per day, ?days= picks how far back the report goes
+func (h *Handler) HandleAdminAPIKeyUsage(w http.ResponseWriter, r *http.Request) {
+	days := defaultUsageDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeAdminError(w, http.StatusBadRequest, "invalid_request", "days must be a positive number")
+			return
+		}
+		days = min(n, int(apikeys.UsageRetention/(24*time.Hour)))
+	}
+
+	key, err := h.APIKeys.Get(r.Context(), mux.Vars(r)["keyId"])
+	if errors.Is(err, apikeys.ErrNotFound) {
+		writeAdminError(w, http.StatusNotFound, "key_not_found", "no api key with that id")
+		return
+	}
+	if err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage is not available")
+		return
+	}
+
+	now := time.Now()
+	usage, err := h.APIKeys.Usage(r.Context(), key.ID, now.AddDate(0, 0, 1-days), now)
+	if err != nil {
+		writeAdminError(w, http.StatusServiceUnavailable, "storage_unavailable", "storage is not available")
+		return
+	}
+	if usage == nil {
+		usage = []models.APIKeyUsage{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.APIKeyUsageResponse{Success: true, Key: key, Days: usage})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/apikeys"
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+)
+
+func TestHandleAdminCreateAPIKey_WhenValid_ReturnsTokenThatLooksUpTheKey(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	keys := apikeys.NewMemory()
+	h.APIKeys = keys
+	req := httptest.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"name":"community site","tier":"partner"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminCreateAPIKey(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var created models.APIKeyCreatedResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+	assert.Equal(t, "partner", created.Key.Tier)
+	found, err := keys.Lookup(context.Background(), apikeys.Hash(created.Token))
+	require.NoError(t, err)
+	assert.Equal(t, created.Key.ID, found.ID)
+}
+
+func TestHandleAdminCreateAPIKey_WhenTierAnonymous_ReturnsBadRequest(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	h.APIKeys = apikeys.NewMemory()
+	req := httptest.NewRequest("POST", "/admin/api-keys", strings.NewReader(`{"name":"someone","tier":"anonymous"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminCreateAPIKey(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "tier must be free or partner")
+}
+
+func TestHandleAdminAPIKeyUsage_WhenKeyUsedToday_ReportsTheDay(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	h := newTestHandler(config.Default())
+	keys := apikeys.NewMemory()
+	h.APIKeys = keys
+	key, _, err := apikeys.Issue(ctx, keys, "bot", apikeys.TierFree, time.Now())
+	require.NoError(t, err)
+	keys.RecordUsage(ctx, key.ID, "/api/item/{itemId}", 5, false, time.Now())
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/admin/api-keys/"+key.ID+"/usage?days=7", nil), map[string]string{"keyId": key.ID})
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleAdminAPIKeyUsage(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	var report models.APIKeyUsageResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	require.Len(t, report.Days, 1)
+	assert.Equal(t, time.Now().UTC().Format(time.DateOnly), report.Days[0].Date)
+	assert.Equal(t, int64(5), report.Days[0].Units)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"yard-backend/internal/models"
+)
+
+// most entries the atom feed carries, readers poll it and only need the recent ones
+const feedEntries = 50
+
+// handles requests for the catalog change log, optionally filtered by since and until
+func (h *Handler) HandleChanges(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.svc.Changes(r.Context(), since, until)
+	if err != nil {
+		log.Printf("Error fetching changes: %v", err)
+		http.Error(w, "Error fetching changes", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.ChangesResponse{
+		Success: true,
+		Count:   len(changes),
+		Changes: changes,
+	})
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  atomAuthor  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// handles requests for the catalog change log as an atom feed for patch note bots
+func (h *Handler) HandleChangesFeed(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.svc.Changes(r.Context(), since, until)
+	if err != nil {
+		log.Printf("Error fetching changes: %v", err)
+		http.Error(w, "Error fetching changes", http.StatusInternalServerError)
+		return
+	}
+	if len(changes) > feedEntries {
+		changes = changes[:feedEntries]
+	}
+
+	feed := atomFeed{
+		Title:   "YARD reforge stone changes",
+		ID:      "urn:yard:changes",
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		Link:    atomLink{Rel: "self", Href: r.URL.Path},
+		Author:  atomAuthor{Name: "YARD"},
+		Entries: make([]atomEntry, 0, len(changes)),
+	}
+	if len(changes) > 0 {
+		feed.Updated = changes[0].At.UTC().Format(time.RFC3339)
+	}
+	for _, changeSet := range changes {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   changeSetTitle(changeSet),
+			ID:      fmt.Sprintf("urn:yard:changes:%d", changeSet.At.UnixMilli()),
+			Updated: changeSet.At.UTC().Format(time.RFC3339),
+			Content: atomContent{Type: "html", Body: changeSetHTML(changeSet)},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(feed)
+}
+
+// reads since and until as RFC 3339 times or unix seconds, both are optional
+func parseTimeRange(r *http.Request) (since, until time.Time, err error) {
+	if since, err = parseTimeParam(r, "since"); err != nil {
+		return
+	}
+	until, err = parseTimeParam(r, "until")
+	return
+}
+
+func parseTimeParam(r *http.Request, name string) (time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Invalid %s parameter: use RFC 3339 or unix seconds", name)
+	}
+	return t, nil
+}
+
+func changeSetTitle(changes models.ChangeSet) string {
+	var parts []string
+	if n := len(changes.Added); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d added", n))
+	}
+	if n := len(changes.Removed); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d removed", n))
+	}
+	if n := len(changes.Modified); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d changed", n))
+	}
+	return "Reforge stones: " + strings.Join(parts, ", ")
+}
+
+// renders a changeset as a short html list, the feed encoder escapes it again as atom requires
+func changeSetHTML(changes models.ChangeSet) string {
+	var b strings.Builder
+	section := func(title string, refs []models.ItemRef) {
+		if len(refs) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "<h3>%s</h3><ul>", title)
+		for _, ref := range refs {
+			fmt.Fprintf(&b, "<li>%s (%s, %s)</li>", html.EscapeString(ref.Name), html.EscapeString(ref.ID), html.EscapeString(ref.Tier))
+		}
+		b.WriteString("</ul>")
+	}
+	section("Added", changes.Added)
+	section("Removed", changes.Removed)
+
+	if len(changes.Modified) > 0 {
+		b.WriteString("<h3>Changed</h3><ul>")
+		for _, item := range changes.Modified {
+			fmt.Fprintf(&b, "<li>%s (%s)<ul>", html.EscapeString(item.Name), html.EscapeString(item.ID))
+			for _, field := range item.Fields {
+				fmt.Fprintf(&b, "<li>%s: %s &rarr; %s</li>", html.EscapeString(field.Field),
+					html.EscapeString(formatFieldValue(field.Old)), html.EscapeString(formatFieldValue(field.New)))
+			}
+			b.WriteString("</ul></li>")
+		}
+		b.WriteString("</ul>")
+	}
+	return b.String()
+}
+
+func formatFieldValue(value interface{}) string {
+	if value == nil {
+		return "none"
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
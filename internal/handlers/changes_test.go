@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+// builds a store whose change log holds one changeset an hour apart for each stone
+func newChangesStore(t *testing.T, base time.Time, ids ...string) storage.Store {
+	store := storage.NewMemory()
+	for i, id := range ids {
+		at := base.Add(time.Duration(i) * time.Hour)
+		data, err := json.Marshal(models.ChangeSet{At: at, Added: []models.ItemRef{{ID: id, Name: id}}})
+		require.NoError(t, err)
+		require.NoError(t, store.AppendHistory(context.Background(), "catalog_changes", storage.HistoryEntry{At: at, Data: data}))
+	}
+	return store
+}
+
+func TestHandleChanges_WhenSinceGiven_ReturnsLaterChangesNewestFirst(t *testing.T) {
+	// Arrange
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	h := newTestHandler(config.Default(), newChangesStore(t, base, "AMBER", "JADE", "ONYX"))
+	req := httptest.NewRequest("GET", "/api/changes?since="+base.Format(time.RFC3339), nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleChanges(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response models.ChangesResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Equal(t, 2, response.Count)
+	assert.Equal(t, "ONYX", response.Changes[0].Added[0].ID)
+	assert.Equal(t, "JADE", response.Changes[1].Added[0].ID)
+}
+
+func TestHandleChanges_WhenSinceInvalid_ReturnsBadRequest(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := httptest.NewRequest("GET", "/api/changes?since=yesterday", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleChanges(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestHandleChangesFeed_WhenChangesRecorded_RendersAtomEntries(t *testing.T) {
+	// Arrange
+	base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	h := newTestHandler(config.Default(), newChangesStore(t, base, "AMBER"))
+	req := httptest.NewRequest("GET", "/api/changes.atom", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleChangesFeed(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", rr.Header().Get("Content-Type"))
+	body := rr.Body.String()
+	assert.Contains(t, body, `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, body, "<title>Reforge stones: 1 added</title>")
+	assert.Contains(t, body, "<updated>2026-03-01T00:00:00Z</updated>")
+	assert.Contains(t, body, "&lt;li&gt;AMBER (AMBER, )&lt;/li&gt;")
+}
+
+func TestHandleChanges_WhenStorageUnavailable_HidesTheStorageError(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	store := storage.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer store.Close()
+	mr.Close()
+
+	h := newTestHandler(config.Default(), store)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleChanges(rr, httptest.NewRequest("GET", "/api/changes", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "Error fetching changes\n", rr.Body.String())
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"yard-backend/internal/compress"
+	"yard-backend/internal/services"
+)
+
+// sends a precomputed body in the encoding the client prefers, or a 304 when the client copy is current
+func (h *Handler) writeBody(w http.ResponseWriter, r *http.Request, body services.Body) {
+	// negotiated first so a 304 validates the same entity tag the full response would carry
+	data, encoding := body.Negotiate(r.Header.Get("Accept-Encoding"))
+	w.Header().Add("Vary", "Accept-Encoding")
+	if h.writeNotModified(w, r, compress.ETag(body.ETag, encoding), body.LastModified) {
+		return
+	}
+
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// sets the caching validators for a response and answers 304 when the client copy is current
+// returns true when the 304 was written and the handler should stop
+func (h *Handler) writeNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, must-revalidate", int(h.settings().API.CacheMaxAge.Seconds())))
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if !isNotModified(r, etag, lastModified) {
+		return false
+	}
+
+	// a 304 carries no body so the content type set for the full response must go
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// follows RFC 9110: If-None-Match wins over If-Modified-Since when both are present
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		return etagMatches(match, etag)
+	}
+
+	since := r.Header.Get("If-Modified-Since")
+	if since == "" || lastModified.IsZero() {
+		return false
+	}
+	t, err := http.ParseTime(since)
+	if err != nil {
+		return false
+	}
+	// http dates only carry seconds
+	return !lastModified.Truncate(time.Second).After(t)
+}
+
+// compares a list of entity tags weakly, as If-None-Match requires
+// encoding marks are ignored, every encoding of a body carries the same content
+func etagMatches(header, etag string) bool {
+	etag = compress.StripETags(etag)
+	for _, candidate := range strings.Split(compress.StripETags(header), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
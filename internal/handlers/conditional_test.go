@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+// serves one stone whose prices were refreshed a minute ago
+func newConditionalHandler(t *testing.T) (*Handler, storage.Store) {
+	store := newTestStore(t, models.Item{ID: "AMBER", Name: "Amber"})
+	require.NoError(t, store.SetTimestamp(context.Background(), storage.PricesUpdated, time.Now().Add(-time.Minute)))
+	return newTestHandler(config.Default(), store), store
+}
+
+func TestHandleReforgeStones_WhenETagMatches_ReturnsNotModified(t *testing.T) {
+	// Arrange
+	h, _ := newConditionalHandler(t)
+	first := httptest.NewRecorder()
+	h.HandleReforgeStones(first, httptest.NewRequest("GET", "/api/reforge-stones", nil))
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeStones(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+	assert.Equal(t, "public, max-age=30, must-revalidate", rr.Header().Get("Cache-Control"))
+}
+
+func TestHandleReforgeStones_WhenNotModifiedSinceLastModified_ReturnsNotModified(t *testing.T) {
+	// Arrange
+	h, _ := newConditionalHandler(t)
+	first := httptest.NewRecorder()
+	h.HandleReforgeStones(first, httptest.NewRequest("GET", "/api/reforge-stones", nil))
+	lastModified := first.Header().Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+
+	req := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeStones(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+}
+
+func TestHandleReforgeStones_WhenNewSnapshotPublished_ReturnsFreshBodyAndETag(t *testing.T) {
+	// Arrange
+	h, store := newConditionalHandler(t)
+	first := httptest.NewRecorder()
+	h.HandleReforgeStones(first, httptest.NewRequest("GET", "/api/reforge-stones", nil))
+	etag := first.Header().Get("ETag")
+	_, err := store.Publish(context.Background(), []models.Item{{ID: "AMBER", Name: "Amber"}, {ID: "JADE", Name: "Jade"}}, 1)
+	require.NoError(t, err)
+	// what the watcher does once it notices the new snapshot
+	_, err = h.svc.RebuildReadModel(context.Background())
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeStones(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+	assert.Contains(t, rr.Body.String(), "JADE")
+}
+
+func TestHandleReforges_WhenETagMatches_ReturnsNotModified(t *testing.T) {
+	// Arrange
+	h, _ := newConditionalHandler(t)
+	first := httptest.NewRecorder()
+	h.HandleReforges(first, httptest.NewRequest("GET", "/api/reforges", nil))
+
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforges(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.NotEqual(t, first.Header().Get("ETag"), "")
+}
+
+func TestHandleReforgeStones_WhenGzipAccepted_SendsPrecompressedBody(t *testing.T) {
+	// Arrange
+	h, _ := newConditionalHandler(t)
+	req := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeStones(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.True(t, strings.HasSuffix(rr.Header().Get("ETag"), `-gzip"`))
+	reader, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	assert.Contains(t, string(body), "AMBER")
+}
+
+func TestHandleReforgeStones_WhenGzipCopyRevalidated_SendsSameETagAsFullResponse(t *testing.T) {
+	// Arrange
+	h, _ := newConditionalHandler(t)
+	firstReq := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	firstReq.Header.Set("Accept-Encoding", "gzip")
+	first := httptest.NewRecorder()
+	h.HandleReforgeStones(first, firstReq)
+	etag := first.Header().Get("ETag")
+	require.True(t, strings.HasSuffix(etag, `-gzip"`))
+
+	req := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeStones(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Equal(t, etag, rr.Header().Get("ETag"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"yard-backend/internal/gql"
+)
+
+// largest graphql request body accepted, queries are text and never need more
+const maxGraphQLBody = 1 << 20
+
+// handles graphql queries sent as a json POST body or as GET parameters
+// GET lets persisted queries be cached by cdns, POST is needed for the first send of a long query
+func (h *Handler) HandleGraphQL(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if h.GraphQL == nil {
+		http.Error(w, "GraphQL is not enabled", http.StatusNotImplemented)
+		return
+	}
+
+	req, err := parseGraphQLRequest(w, r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"message": err.Error()}},
+		})
+		return
+	}
+
+	settings := h.settings().GraphQL
+	result := h.GraphQL.Execute(r.Context(), req, gql.Limits{
+		MaxComplexity: settings.MaxComplexity,
+		MaxDepth:      settings.MaxDepth,
+	})
+	json.NewEncoder(w).Encode(result)
+}
+
+// w lets an oversized body close the connection instead of the server reading it to the end
+func parseGraphQLRequest(w http.ResponseWriter, r *http.Request) (gql.Request, error) {
+	var req gql.Request
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBody)).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body: %w", err)
+		}
+		return req, nil
+	}
+
+	query := r.URL.Query()
+	req.Query = query.Get("query")
+	req.OperationName = query.Get("operationName")
+	if variables := query.Get("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			return req, fmt.Errorf("invalid variables: %w", err)
+		}
+	}
+	if extensions := query.Get("extensions"); extensions != "" {
+		if err := json.Unmarshal([]byte(extensions), &req.Extensions); err != nil {
+			return req, fmt.Errorf("invalid extensions: %w", err)
+		}
+	}
+	return req, nil
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/config"
+	"yard-backend/internal/gql"
+	"yard-backend/internal/models"
+)
+
+// serves graphql over a single stone
+func newGraphQLHandler(t *testing.T) *Handler {
+	h := newTestHandler(config.Default(), newTestStore(t, models.Item{ID: "AMBER", Name: "Amber"}))
+	var err error
+	h.GraphQL, err = gql.NewServer(h.svc, 10)
+	require.NoError(t, err)
+	return h
+}
+
+func TestHandleGraphQL_WhenQueryPosted_ReturnsData(t *testing.T) {
+	// Arrange
+	h := newGraphQLHandler(t)
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query": "{ items { id name } }"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleGraphQL(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response struct {
+		Data struct {
+			Items []struct{ ID, Name string }
+		}
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	require.Len(t, response.Data.Items, 1)
+	assert.Equal(t, "Amber", response.Data.Items[0].Name)
+}
+
+func TestHandleGraphQL_WhenQuerySentAsGetParameter_ReturnsData(t *testing.T) {
+	// Arrange
+	h := newGraphQLHandler(t)
+	req := httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`{ item(id: "AMBER") { name } }`), nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleGraphQL(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"Amber"`)
+}
+
+func TestHandleGraphQL_WhenBodyMalformed_ReturnsBadRequest(t *testing.T) {
+	// Arrange
+	h := newGraphQLHandler(t)
+	req := httptest.NewRequest("POST", "/graphql", strings.NewReader(`{"query":`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleGraphQL(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "errors")
+}
+
+func TestHandleGraphQL_WhenBodyTooLarge_ClosesTheConnection(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(newGraphQLHandler(t).HandleGraphQL))
+	defer server.Close()
+	body := `{"query":"` + strings.Repeat(" ", maxGraphQLBody) + `{ items { name } }"}`
+
+	// Act
+	resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.True(t, resp.Close, "the server stops reading an oversized body and drops the connection")
+}
@@ -1,29 +1,73 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"sort"
-	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"yard-backend/internal/apikeys"
 	"yard-backend/internal/config"
+	"yard-backend/internal/gql"
+	"yard-backend/internal/jobs"
 	"yard-backend/internal/models"
 	"yard-backend/internal/services"
 
 	"github.com/gorilla/mux"
 )
 
+// serves the http api using the config and service it was built with
+type Handler struct {
+	cfg atomic.Pointer[config.Config]
+	svc *services.Service
+
+	// reports whether the instance has finished warming up, nil means always ready
+	ReadinessCheck func() bool
+	// reports whether this instance runs the scheduler, nil means always leader
+	LeaderCheck func() bool
+	// runs admin triggered jobs, replaced in main so jobs are cancelled on shutdown
+	Jobs *jobs.Manager
+	// re-reads the config file, nil disables the admin reload endpoint
+	ReloadConfig func() (config.ReloadResult, error)
+	// executes /graphql queries, nil disables the endpoint
+	GraphQL *gql.Server
+	// keeps the keys issued through the admin api, nil disables the api key routes
+	APIKeys apikeys.Store
+}
+
+// creates a handler for the given config and service
+func New(cfg *config.Config, svc *services.Service) *Handler {
+	h := &Handler{
+		svc:  svc,
+		Jobs: jobs.NewManager(context.Background(), 100),
+	}
+	h.cfg.Store(cfg)
+	return h
+}
+
+// returns the config currently in effect
+func (h *Handler) settings() *config.Config {
+	return h.cfg.Load()
+}
+
+// switches to a reloaded config, requests already in flight finish with the old one
+func (h *Handler) Apply(cfg *config.Config) {
+	h.cfg.Store(cfg)
+}
+
 // sets cors headers to allow cross origin requests from configured origin
-func EnableCORS(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) EnableCORS(w http.ResponseWriter, r *http.Request) {
 	origin := r.Header.Get("Origin")
-	if config.AllowedOrigin == "*" {
+	allowedOrigins := h.settings().API.AllowedOrigins
+	if len(allowedOrigins) == 1 && allowedOrigins[0] == "*" {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 	} else {
-		allowedOrigins := strings.Split(config.AllowedOrigin, ",")
 		for _, allowed := range allowedOrigins {
 			if strings.TrimSpace(allowed) == origin {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
@@ -32,25 +76,64 @@ func EnableCORS(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+apikeys.Header)
+	// lets browser clients see how much of their budget is left
+	w.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 	w.Header().Set("Access-Control-Max-Age", "3600")
 }
 
+// answers cors preflights for every route, browsers send one before any request carrying an api key
+func (h *Handler) HandlePreflight(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// answers preflights and hands every other request to next, for routers that catch unmatched requests themselves
+func (h *Handler) Preflight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			h.HandlePreflight(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) isReady() bool {
+	return h.ReadinessCheck == nil || h.ReadinessCheck()
+}
+
+func (h *Handler) isLeader() bool {
+	return h.LeaderCheck == nil || h.LeaderCheck()
+}
+
 // handles health check requests and returns server status
-func HandleHealth(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w, r)
+func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
 	w.Header().Set("Content-Type", "application/json")
 	response := models.HealthResponse{
 		Status:  "ok",
 		Message: "YARD Backend is running",
+		Ready:   h.isReady(),
+		Leader:  h.isLeader(),
 		Time:    time.Now(),
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
-// handles requests for all reforge stones fetching them from redis and returning json
-func HandleReforgeStones(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w, r)
+// handles readiness probes returning 503 until warm-up finishes or once shutdown starts
+func (h *Handler) HandleReady(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ready := h.isReady()
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(models.ReadyResponse{Ready: ready})
+}
+
+// handles requests for all reforge stones fetching them from storage and returning json
+func (h *Handler) HandleReforgeStones(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method == "OPTIONS" {
@@ -58,92 +141,46 @@ func HandleReforgeStones(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if config.RDB == nil {
-		http.Error(w, "Redis client not initialized", http.StatusInternalServerError)
-		return
-	}
-
-	ids, err := config.RDB.SMembers(config.Ctx, "reforge_stones:ids").Result()
+	model, err := h.svc.ReadModel(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching reforge stone IDs: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Error fetching reforge stones: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	var reforgeStones []models.Item
-	for _, id := range ids {
-		key := fmt.Sprintf("reforge_stone:%s", id)
-		stoneJSON, err := config.RDB.Get(config.Ctx, key).Result()
-		if err != nil {
-			log.Printf("Error fetching stone %s: %v", id, err)
-			continue
-		}
-
-		var stone models.Item
-		if err := json.Unmarshal([]byte(stoneJSON), &stone); err != nil {
-			log.Printf("Error unmarshaling stone %s: %v", id, err)
-			continue
-		}
-
-		reforgeStones = append(reforgeStones, stone)
-	}
-
-	pricesUpdatedStr, _ := config.RDB.Get(config.Ctx, "reforge_stones:prices_updated").Result()
-	var lastUpdated time.Time
-	if pricesUpdatedStr != "" {
-		if timestamp, err := strconv.ParseInt(pricesUpdatedStr, 10, 64); err == nil {
-			// prices_updated is stored in milliseconds
-			lastUpdated = time.UnixMilli(timestamp)
-		}
-	}
-
-	response := models.ReforgeStonesResponse{
-		Success:       true,
-		Count:         len(reforgeStones),
-		LastUpdated:   lastUpdated,
-		ReforgeStones: reforgeStones,
-	}
-
-	json.NewEncoder(w).Encode(response)
+	h.writeBody(w, r, model.StonesBody)
 }
 
 // handles requests for item images by id upscaling textures and returning png data
-func HandleItemImage(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w, r)
+func (h *Handler) HandleItemImage(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
 
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	vars := mux.Vars(r)
-	itemID := vars["itemId"]
+	itemID := mux.Vars(r)["itemId"]
 	if itemID == "" {
 		http.Error(w, "Item ID is required", http.StatusBadRequest)
 		return
 	}
 
-	normalizedID := strings.ToUpper(strings.ReplaceAll(itemID, " ", "_"))
-
-	if texturePath, ok := GetItemTexturePath(normalizedID); ok {
-		imageData, err := UpscaleTexture(texturePath, 256)
-		if err != nil {
-			log.Printf("Error upscaling texture file %s: %v", texturePath, err)
-			http.Error(w, "Texture processing error", http.StatusInternalServerError)
-			return
-		}
-
-		w.Header().Set("Content-Type", "image/png")
-		w.Header().Set("Cache-Control", "public, max-age=31536000")
-		w.Write(imageData)
+	imageData, err := h.renderItem(itemID)
+	if errors.Is(err, errNoTexture) {
+		http.Error(w, "Item texture not found", http.StatusNotFound)
 		return
 	}
-
-	http.Error(w, "Item texture not found", http.StatusNotFound)
+	if err != nil {
+		log.Printf("Error %v", err)
+		http.Error(w, "Texture processing error", http.StatusInternalServerError)
+		return
+	}
+	writePNG(w, imageData)
 }
 
 // handles requests for all reforges returning merged data from reforges.json and reforgestones.json
-func HandleReforges(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w, r)
+func (h *Handler) HandleReforges(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
 	w.Header().Set("Content-Type", "application/json")
 
 	if r.Method == "OPTIONS" {
@@ -151,34 +188,54 @@ func HandleReforges(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	reforges := services.GetAllReforges()
+	model, err := h.svc.ReadModel(r.Context())
+	if err == nil {
+		h.writeBody(w, r, model.ReforgesBody)
+		return
+	}
 
-	// sort reforges alphabetically by name
+	// stone prices are optional for reforges, serve the neu data without validators
+	log.Printf("Error building read model: %v", err)
+	reforges := h.svc.GetAllReforges(r.Context())
 	sort.Slice(reforges, func(i, j int) bool {
 		return reforges[i].ReforgeName < reforges[j].ReforgeName
 	})
 
-	// get last updated time from redis if available
-	var lastUpdated time.Time
-	if config.RDB != nil {
-		lastUpdatedStr, err := config.RDB.Get(config.Ctx, "reforge_stones:last_updated").Result()
-		if err == nil && lastUpdatedStr != "" {
-			if timestamp, err := strconv.ParseInt(lastUpdatedStr, 10, 64); err == nil {
-				lastUpdated = time.Unix(timestamp/1000, 0)
-			}
-		}
-	}
-
-	if lastUpdated.IsZero() {
-		lastUpdated = time.Now()
-	}
-
 	response := models.ReforgesResponse{
 		Success:     true,
 		Count:       len(reforges),
-		LastUpdated: lastUpdated,
+		LastUpdated: time.Now(),
 		Reforges:    reforges,
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
+
+// handles requests for the neu balance history of a single reforge
+func (h *Handler) HandleReforgeHistory(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	name, history, found, err := h.svc.ReforgeHistory(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		log.Printf("Error fetching reforge history: %v", err)
+		http.Error(w, "Error fetching reforge history", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Reforge not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.ReforgeHistoryResponse{
+		Success: true,
+		Reforge: name,
+		Count:   len(history),
+		History: history,
+	})
+}
@@ -1,35 +1,71 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"yard-backend/internal/config"
 	"yard-backend/internal/models"
+	"yard-backend/internal/services"
+	"yard-backend/internal/storage"
 )
 
+// builds a handler over the given store, or over an empty memory store when none is passed
+func newTestHandler(cfg *config.Config, stores ...storage.Store) *Handler {
+	var store storage.Store = storage.NewMemory()
+	if len(stores) > 0 {
+		store = stores[0]
+	}
+	return New(cfg, services.New(cfg, store))
+}
+
+// publishes items as the first snapshot of a fresh memory store
+func newTestStore(t *testing.T, items ...models.Item) storage.Store {
+	store := storage.NewMemory()
+	_, err := store.Publish(context.Background(), items, 1)
+	require.NoError(t, err)
+	return store
+}
+
 func TestEnableCORS_WhenWildcardOrigin_SetsWildcardHeader(t *testing.T) {
 	// Arrange
-	originalAllowedOrigin := config.AllowedOrigin
-	config.AllowedOrigin = "*"
-	defer func() { config.AllowedOrigin = originalAllowedOrigin }()
-
+	h := newTestHandler(config.Default())
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/", nil)
 	req.Header.Set("Origin", "https://example.com")
 
 	// Act
-	EnableCORS(rr, req)
+	h.EnableCORS(rr, req)
 
 	// Assert
 	assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
 }
 
+func TestEnableCORS_WhenOriginListed_EchoesOrigin(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.API.AllowedOrigins = []string{"https://a.example.com", "https://b.example.com"}
+	h := newTestHandler(cfg)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Origin", "https://b.example.com")
+
+	// Act
+	h.EnableCORS(rr, req)
+
+	// Assert
+	assert.Equal(t, "https://b.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
 func TestHandleHealth_WhenRequested_ReturnsOkStatus(t *testing.T) {
 	// Arrange
 	req, err := http.NewRequest("GET", "/health", nil)
@@ -37,7 +73,7 @@ func TestHandleHealth_WhenRequested_ReturnsOkStatus(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Act
-	HandleHealth(rr, req)
+	newTestHandler(config.Default()).HandleHealth(rr, req)
 
 	// Assert
 	assert.Equal(t, http.StatusOK, rr.Code)
@@ -47,23 +83,79 @@ func TestHandleHealth_WhenRequested_ReturnsOkStatus(t *testing.T) {
 	assert.Equal(t, "ok", response.Status)
 }
 
-func TestHandleReforgeStones_WhenRedisNotInitialized_ReturnsInternalServerError(t *testing.T) {
+func TestHandleReforgeStones_WhenStorageUnavailable_ReturnsInternalServerError(t *testing.T) {
 	// Arrange
-	originalRDB := config.RDB
-	config.RDB = nil
-	defer func() { config.RDB = originalRDB }()
+	mr := miniredis.RunT(t)
+	store := storage.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer store.Close()
+	mr.Close()
 
+	h := newTestHandler(config.Default(), store)
 	req, err := http.NewRequest("GET", "/api/reforge-stones", nil)
 	require.NoError(t, err)
 	rr := httptest.NewRecorder()
 
 	// Act
-	HandleReforgeStones(rr, req)
+	h.HandleReforgeStones(rr, req)
 
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
 }
 
+func TestHandleReforgeStones_WhenStonesCached_ReturnsThem(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default(), newTestStore(t, models.Item{ID: "AMBER", Name: "Amber"}))
+	req := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeStones(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	var response models.ReforgeStonesResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Count)
+	assert.Equal(t, "Amber", response.ReforgeStones[0].Name)
+}
+
+func TestHandleReforgeStones_WhenStorageGoesDownAfterBuild_ServesReadModel(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	store := storage.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer store.Close()
+	store.Publish(context.Background(), []models.Item{{ID: "AMBER", Name: "Amber"}}, 1)
+	cfg := config.Default()
+	svc := services.New(cfg, store)
+	_, err := svc.RebuildReadModel(context.Background())
+	require.NoError(t, err)
+	mr.Close()
+
+	h := New(cfg, svc)
+	req := httptest.NewRequest("GET", "/api/reforge-stones", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeStones(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Amber")
+}
+
+func TestHandleReforgeHistory_WhenReforgeUnknown_ReturnsNotFound(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/reforges/Nope/history", nil), map[string]string{"name": "Nope"})
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReforgeHistory(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
 func TestHandleItemImage_WhenItemNotFound_ReturnsNotFound(t *testing.T) {
 	// Arrange
 	req, err := http.NewRequest("GET", "/api/item/NONEXISTENT", nil)
@@ -74,8 +166,28 @@ func TestHandleItemImage_WhenItemNotFound_ReturnsNotFound(t *testing.T) {
 	req = mux.SetURLVars(req, map[string]string{"itemId": "NONEXISTENT"})
 
 	// Act
-	HandleItemImage(rr, req)
+	newTestHandler(config.Default()).HandleItemImage(rr, req)
 
 	// Assert
 	assert.Equal(t, http.StatusNotFound, rr.Code)
 }
+
+func TestHandleReady_WhenWarmUpPending_ReturnsServiceUnavailable(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	h.ReadinessCheck = func() bool { return false }
+
+	req, err := http.NewRequest("GET", "/ready", nil)
+	require.NoError(t, err)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleReady(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	var response models.ReadyResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.False(t, response.Ready)
+}
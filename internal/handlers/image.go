@@ -2,20 +2,28 @@ package handlers
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/png"
+	"io"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"golang.org/x/image/draw"
-	"yard-backend/internal/config"
+	"yard-backend/internal/metrics"
 	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+	"yard-backend/internal/upstream"
 )
 
+// returned when neither the resource pack nor a skull skin has a texture for an item
+var errNoTexture = errors.New("item texture not found")
+
 // upscales a texture image to the target size using nearest neighbor scaling
 func UpscaleTexture(texturePath string, targetSize int) ([]byte, error) {
 	file, err := os.Open(texturePath)
@@ -41,99 +49,113 @@ func UpscaleTexture(texturePath string, targetSize int) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// handles requests for item images by data fetching from redis and rendering textures or skins
-func HandleItemImageByData(w http.ResponseWriter, r *http.Request) {
-	EnableCORS(w, r)
-	
+// handles requests for item images by data fetching from storage and rendering textures or skins
+func (h *Handler) HandleItemImageByData(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	vars := mux.Vars(r)
-	itemID := vars["itemId"]
+	itemID := mux.Vars(r)["itemId"]
 	if itemID == "" {
 		http.Error(w, "Item ID is required", http.StatusBadRequest)
 		return
 	}
 
-	if config.RDB == nil {
-		http.Error(w, "Redis client not initialized", http.StatusInternalServerError)
-		return
+	imageData, err := h.renderStone(r.Context(), itemID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "Item not found", http.StatusNotFound)
+	case errors.Is(err, errNoTexture):
+		http.Error(w, "Item texture not found", http.StatusNotFound)
+	case err != nil:
+		http.Error(w, "Error fetching reforge stones", http.StatusInternalServerError)
+	default:
+		writePNG(w, imageData)
 	}
+}
 
-	ids, err := config.RDB.SMembers(config.Ctx, "reforge_stones:ids").Result()
+// renders an item from the resource pack, errNoTexture when the pack has no texture for it
+func (h *Handler) renderItem(itemID string) ([]byte, error) {
+	normalizedID := strings.ToUpper(strings.ReplaceAll(itemID, " ", "_"))
+	texturePath, ok := GetItemTexturePath(normalizedID)
+	if !ok {
+		return nil, errNoTexture
+	}
+	start := time.Now()
+	imageData, err := UpscaleTexture(texturePath, h.settings().Images.Size)
 	if err != nil {
-		http.Error(w, "Error fetching reforge stones", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("upscaling texture file %s: %w", texturePath, err)
 	}
+	metrics.ObserveImageRender("resource_pack", time.Since(start))
+	return imageData, nil
+}
 
-	var targetItem *models.Item
-	for _, id := range ids {
-		if id == itemID {
-			key := fmt.Sprintf("reforge_stone:%s", id)
-			stoneJSON, err := config.RDB.Get(config.Ctx, key).Result()
-			if err != nil {
-				continue
-			}
+// renders a cached stone from the resource pack, falling back to its skull skin
+// storage.ErrNotFound means the stone isn't cached, errNoTexture that neither source had an image
+func (h *Handler) renderStone(ctx context.Context, itemID string) ([]byte, error) {
+	stone, err := h.svc.Store().GetStone(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
 
-			var stone models.Item
-			if err := json.Unmarshal([]byte(stoneJSON), &stone); err != nil {
-				continue
-			}
+	if imageData, err := h.renderItem(itemID); err == nil {
+		return imageData, nil
+	}
+	if imageData, ok := h.renderSkin(ctx, stone); ok {
+		return imageData, nil
+	}
+	return nil, errNoTexture
+}
 
-			targetItem = &stone
-			break
-		}
+// downloads and upscales the skull texture of an item, false when it has none or the download fails
+func (h *Handler) renderSkin(ctx context.Context, item models.Item) ([]byte, bool) {
+	skinValue, ok := item.Skin["value"]
+	if !ok {
+		return nil, false
+	}
+	textureHash, err := ExtractTextureHashFromSkin(skinValue)
+	if err != nil || textureHash == "" {
+		return nil, false
 	}
 
-	if targetItem == nil {
-		http.Error(w, "Item not found", http.StatusNotFound)
-		return
+	textureURL := fmt.Sprintf("https://textures.minecraft.net/texture/%s", textureHash)
+	resp, err := h.svc.Client().Get(ctx, textureURL, upstream.NoRetryPolicy())
+	if err != nil {
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false
 	}
 
-	normalizedID := strings.ToUpper(strings.ReplaceAll(itemID, " ", "_"))
-	
-	if texturePath, ok := GetItemTexturePath(normalizedID); ok {
-		imageData, err := UpscaleTexture(texturePath, 256)
-		if err == nil {
-			w.Header().Set("Content-Type", "image/png")
-			w.Header().Set("Cache-Control", "public, max-age=31536000")
-			w.Write(imageData)
-			return
-		}
-	}
-
-	if targetItem.Skin != nil {
-		if skinValue, ok := targetItem.Skin["value"]; ok {
-			textureHash, err := ExtractTextureHashFromSkin(skinValue)
-			if err == nil && textureHash != "" {
-				textureURL := fmt.Sprintf("https://textures.minecraft.net/texture/%s", textureHash)
-				resp, err := http.Get(textureURL)
-				if err == nil && resp.StatusCode == http.StatusOK {
-					defer resp.Body.Close()
-					
-					srcImg, err := png.Decode(resp.Body)
-					if err == nil {
-						bounds := srcImg.Bounds()
-						
-						targetSize := 256
-						dstImg := image.NewRGBA(image.Rect(0, 0, targetSize, targetSize))
-						draw.NearestNeighbor.Scale(dstImg, dstImg.Bounds(), srcImg, bounds, draw.Over, nil)
-						
-						var buf bytes.Buffer
-						if err := png.Encode(&buf, dstImg); err == nil {
-							w.Header().Set("Content-Type", "image/png")
-							w.Header().Set("Cache-Control", "public, max-age=31536000")
-							w.Write(buf.Bytes())
-							return
-						}
-					}
-				}
-			}
-		}
-	}
-
-	http.Error(w, "Item texture not found", http.StatusNotFound)
+	// read first so the render time doesn't include the download
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false
+	}
+	start := time.Now()
+	srcImg, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	targetSize := h.settings().Images.Size
+	dstImg := image.NewRGBA(image.Rect(0, 0, targetSize, targetSize))
+	draw.NearestNeighbor.Scale(dstImg, dstImg.Bounds(), srcImg, srcImg.Bounds(), draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dstImg); err != nil {
+		return nil, false
+	}
+	metrics.ObserveImageRender("skin", time.Since(start))
+	return buf.Bytes(), true
 }
 
+// sends a rendered item image, images never change for an id so clients may keep them for a year
+func writePNG(w http.ResponseWriter, imageData []byte) {
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=31536000")
+	w.Write(imageData)
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"yard-backend/internal/openapi"
+)
+
+// handles requests for the openapi document describing every route
+func (h *Handler) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.settings().API.CacheMaxAge.Seconds())))
+	w.Write(openapi.JSON())
+}
+
+// handles requests for the interactive docs page, it loads /openapi.json itself
+func (h *Handler) HandleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(openapi.DocsPage)
+}
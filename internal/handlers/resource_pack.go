@@ -9,6 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+
+	"yard-backend/internal/metrics"
 )
 
 // stores model data from json files
@@ -17,8 +20,11 @@ type ModelData struct {
 	Textures map[string]string `json:"textures"`
 }
 
-// maps item ids to their texture file paths
-var textureRegistry = make(map[string]string)
+// maps item ids to their texture file paths, swapped wholesale on reload
+var (
+	textureRegistry      = make(map[string]string)
+	textureRegistryMutex sync.RWMutex
+)
 
 // base paths for the hypixelplus resource pack
 const (
@@ -29,14 +35,16 @@ const (
 )
 
 // scans the resource pack and builds a mapping of item ids to texture paths
-func LoadResourcePack() {
+// returns the number of textures loaded, safe to call again while requests are being served
+func LoadResourcePack() int {
 	log.Println("scanning hypixelplus resource pack...")
 
 	if _, err := os.Stat(modelsBasePath); os.IsNotExist(err) {
 		log.Printf("warning: resource pack models not found at %s", modelsBasePath)
-		return
+		return 0
 	}
 
+	registry := make(map[string]string)
 	loadedCount := 0
 	err := filepath.WalkDir(modelsBasePath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
@@ -79,9 +87,9 @@ func LoadResourcePack() {
 
 		// verify the texture file exists
 		if _, err := os.Stat(fullTexturePath); err == nil {
-			textureRegistry[itemIdUpper] = fullTexturePath
-			textureRegistry[itemIdLower] = fullTexturePath
-			textureRegistry[baseName] = fullTexturePath
+			registry[itemIdUpper] = fullTexturePath
+			registry[itemIdLower] = fullTexturePath
+			registry[baseName] = fullTexturePath
 			loadedCount++
 		}
 
@@ -92,7 +100,13 @@ func LoadResourcePack() {
 		log.Printf("error scanning resource pack: %v", err)
 	}
 
+	textureRegistryMutex.Lock()
+	textureRegistry = registry
+	textureRegistryMutex.Unlock()
+
 	log.Printf("loaded %d item textures from resource pack", loadedCount)
+	metrics.SetTextureRegistrySize(loadedCount)
+	return loadedCount
 }
 
 // extracts and converts texture reference to actual file path, returns path and namespace
@@ -148,6 +162,9 @@ func resolveTexturePath(model ModelData) string {
 
 // looks up texture path for an item, tries multiple id formats
 func GetItemTexturePath(itemID string) (string, bool) {
+	textureRegistryMutex.RLock()
+	defer textureRegistryMutex.RUnlock()
+
 	// try uppercase with underscores
 	normalized := strings.ToUpper(strings.ReplaceAll(itemID, " ", "_"))
 	if path, ok := textureRegistry[normalized]; ok {
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"yard-backend/internal/envelope"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// every /api/v2 failure is logged in full here and answered with a message that is safe to show
+func v2StorageError(w http.ResponseWriter, what string, err error) {
+	log.Printf("Error fetching %s for request %s: %v", what, w.Header().Get(envelope.RequestIDHeader), err)
+	envelope.Error(w, http.StatusServiceUnavailable, envelope.CodeStorageUnavailable, what+" are not available right now, please retry shortly")
+}
+
+// handles /api/v2/reforge-stones, the v2 envelope around the same stones as v1
+func (h *Handler) HandleV2ReforgeStones(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
+	model, err := h.svc.ReadModel(r.Context())
+	if err != nil {
+		v2StorageError(w, "reforge stones", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	h.writeBody(w, r, model.StonesV2Body)
+}
+
+// handles /api/v2/reforges, the v2 envelope around the same reforges as v1
+func (h *Handler) HandleV2Reforges(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
+	model, err := h.svc.ReadModel(r.Context())
+	if err == nil {
+		w.Header().Set("Content-Type", "application/json")
+		h.writeBody(w, r, model.ReforgesV2Body)
+		return
+	}
+
+	// same fallback as v1, the neu data is served under a version of the neu files alone when storage is down
+	log.Printf("Error building read model: %v", err)
+	reforges := h.svc.GetAllReforges(r.Context())
+	sort.Slice(reforges, func(i, j int) bool {
+		return reforges[i].ReforgeName < reforges[j].ReforgeName
+	})
+	envelope.Write(w, http.StatusOK, reforges, h.svc.NEUOnlyVersion().Meta(time.Now()))
+}
+
+// handles /api/v2/reforges/{name}/history
+func (h *Handler) HandleV2ReforgeHistory(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
+	name, history, found, err := h.svc.ReforgeHistory(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		v2StorageError(w, "reforge histories", err)
+		return
+	}
+	if !found {
+		envelope.Error(w, http.StatusNotFound, envelope.CodeReforgeNotFound, "no reforge with that name")
+		return
+	}
+
+	version, err := h.svc.DataVersion(r.Context())
+	if err != nil {
+		v2StorageError(w, "reforge histories", err)
+		return
+	}
+	var lastUpdated time.Time
+	if history == nil {
+		history = []models.ReforgeHistoryEntry{}
+	}
+	if len(history) > 0 {
+		lastUpdated = history[0].Date
+	}
+	envelope.Write(w, http.StatusOK, models.ReforgeHistory{Reforge: name, History: history}, version.Meta(lastUpdated))
+}
+
+// handles /api/v2/changes
+func (h *Handler) HandleV2Changes(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
+	since, until, err := parseTimeRange(r)
+	if err != nil {
+		envelope.Error(w, http.StatusBadRequest, envelope.CodeInvalidParameter, err.Error())
+		return
+	}
+
+	changes, err := h.svc.Changes(r.Context(), since, until)
+	if err != nil {
+		v2StorageError(w, "changes", err)
+		return
+	}
+
+	version, err := h.svc.DataVersion(r.Context())
+	if err != nil {
+		v2StorageError(w, "changes", err)
+		return
+	}
+	var lastUpdated time.Time
+	if changes == nil {
+		changes = []models.ChangeSet{}
+	}
+	if len(changes) > 0 {
+		lastUpdated = changes[0].At
+	}
+	envelope.Write(w, http.StatusOK, changes, version.Meta(lastUpdated))
+}
+
+// handles /api/v2/item/{itemId}, images are sent as png and only errors use the envelope
+func (h *Handler) HandleV2ItemImage(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
+	imageData, err := h.renderItem(mux.Vars(r)["itemId"])
+	switch {
+	case errors.Is(err, errNoTexture):
+		envelope.Error(w, http.StatusNotFound, envelope.CodeTextureNotFound, "the resource pack has no texture for this item")
+	case err != nil:
+		log.Printf("Error %v", err)
+		envelope.Error(w, http.StatusInternalServerError, envelope.CodeInternal, "the texture could not be rendered")
+	default:
+		writePNG(w, imageData)
+	}
+}
+
+// handles /api/v2/item-data/{itemId}, images are sent as png and only errors use the envelope
+func (h *Handler) HandleV2ItemImageByData(w http.ResponseWriter, r *http.Request) {
+	h.EnableCORS(w, r)
+
+	imageData, err := h.renderStone(r.Context(), mux.Vars(r)["itemId"])
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		envelope.Error(w, http.StatusNotFound, envelope.CodeItemNotFound, "no cached reforge stone with that id")
+	case errors.Is(err, errNoTexture):
+		envelope.Error(w, http.StatusNotFound, envelope.CodeTextureNotFound, "there is no texture for this item")
+	case err != nil:
+		v2StorageError(w, "reforge stones", err)
+	default:
+		writePNG(w, imageData)
+	}
+}
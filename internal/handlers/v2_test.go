@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/config"
+	"yard-backend/internal/envelope"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+func decodeEnvelope(t *testing.T, rr *httptest.ResponseRecorder) models.Envelope {
+	var response models.Envelope
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	return response
+}
+
+func TestHandleV2ReforgeStones_WhenStonesCached_WrapsThemWithMeta(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default(), newTestStore(t, models.Item{ID: "AMBER", Name: "Amber"}))
+	req := httptest.NewRequest("GET", "/api/v2/reforge-stones", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleV2ReforgeStones(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	response := decodeEnvelope(t, rr)
+	assert.True(t, response.Success)
+	assert.Nil(t, response.Error)
+	assert.NotEmpty(t, response.Meta.Version)
+	assert.Equal(t, "Amber", response.Data.([]interface{})[0].(map[string]interface{})["name"])
+	assert.NotEmpty(t, rr.Header().Get("ETag"))
+}
+
+func TestHandleV2ReforgeStones_WhenStorageUnavailable_HidesTheCause(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	store := storage.NewRedis(redis.NewClient(&redis.Options{Addr: addr}))
+	defer store.Close()
+	mr.Close()
+
+	h := newTestHandler(config.Default(), store)
+	req := httptest.NewRequest("GET", "/api/v2/reforge-stones", nil)
+	rr := httptest.NewRecorder()
+	rr.Header().Set(envelope.RequestIDHeader, "req-1")
+
+	// Act
+	h.HandleV2ReforgeStones(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	response := decodeEnvelope(t, rr)
+	assert.False(t, response.Success)
+	assert.Nil(t, response.Data)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, envelope.CodeStorageUnavailable, response.Error.Code)
+	assert.Equal(t, "req-1", response.Error.RequestID)
+	assert.NotContains(t, response.Error.Message, addr)
+	assert.NotContains(t, response.Error.Message, "connect")
+}
+
+func TestHandleV2ReforgeHistory_WhenReforgeUnknown_ReturnsErrorCode(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v2/reforges/Nope/history", nil), map[string]string{"name": "Nope"})
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleV2ReforgeHistory(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, envelope.CodeReforgeNotFound, decodeEnvelope(t, rr).Error.Code)
+}
+
+func TestHandleV2ItemImageByData_WhenStoneNotCached_ReturnsErrorCode(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/v2/item-data/NOPE", nil), map[string]string{"itemId": "NOPE"})
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleV2ItemImageByData(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Equal(t, envelope.CodeItemNotFound, decodeEnvelope(t, rr).Error.Code)
+}
+
+func TestHandleV2Changes_WhenNothingChanged_SendsAnEmptyList(t *testing.T) {
+	// Arrange
+	h := newTestHandler(config.Default())
+	req := httptest.NewRequest("GET", "/api/v2/changes", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleV2Changes(rr, req)
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	response := decodeEnvelope(t, rr)
+	assert.Equal(t, []interface{}{}, response.Data)
+	assert.Nil(t, response.Meta.LastUpdated)
+	assert.NotEmpty(t, response.Meta.Version)
+}
+
+func TestHandleV2Reforges_WhenStorageUnavailable_StillVersionsTheFallback(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	store := storage.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer store.Close()
+	mr.Close()
+
+	h := newTestHandler(config.Default(), store)
+	rr := httptest.NewRecorder()
+
+	// Act
+	h.HandleV2Reforges(rr, httptest.NewRequest("GET", "/api/v2/reforges", nil))
+
+	// Assert
+	require.Equal(t, http.StatusOK, rr.Code)
+	response := decodeEnvelope(t, rr)
+	assert.True(t, response.Success)
+	assert.NotEmpty(t, response.Meta.Version)
+	assert.NotNil(t, response.Meta.LastUpdated)
+}
@@ -0,0 +1,213 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"yard-backend/internal/models"
+)
+
+const (
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// caps how many errors a single job keeps so a broken upstream can't grow it without bound
+const maxErrorsPerJob = 50
+
+// a background job and its progress
+type Job struct {
+	mu         sync.Mutex
+	id         string
+	kind       string
+	target     string
+	state      string
+	processed  int
+	total      int
+	errors     []string
+	startedAt  time.Time
+	finishedAt time.Time
+}
+
+// sets how many items the job expects to process
+func (j *Job) SetTotal(total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.total = total
+}
+
+// marks one more item as processed
+func (j *Job) Advance() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.processed++
+}
+
+// records a per item failure, the item still counts as processed
+func (j *Job) Fail(item string, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.processed++
+	if len(j.errors) < maxErrorsPerJob {
+		j.errors = append(j.errors, fmt.Sprintf("%s: %v", item, err))
+	}
+}
+
+// returns the job id
+func (j *Job) ID() string {
+	return j.id
+}
+
+// returns a point in time copy of the job for api responses
+func (j *Job) Status() models.JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := models.JobStatus{
+		ID:        j.id,
+		Type:      j.kind,
+		Target:    j.target,
+		State:     j.state,
+		Processed: j.processed,
+		Total:     j.total,
+		Errors:    append([]string(nil), j.errors...),
+		StartedAt: j.startedAt,
+	}
+
+	if !j.finishedAt.IsZero() {
+		finished := j.finishedAt
+		status.FinishedAt = &finished
+	} else if j.processed > 0 && j.total > j.processed {
+		perItem := time.Since(j.startedAt).Seconds() / float64(j.processed)
+		eta := perItem * float64(j.total-j.processed)
+		status.ETASeconds = &eta
+	}
+
+	return status
+}
+
+func (j *Job) finish(ctx context.Context, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.finishedAt = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		j.state = StateCancelled
+	case err != nil:
+		j.state = StateFailed
+		if len(j.errors) < maxErrorsPerJob {
+			j.errors = append(j.errors, err.Error())
+		}
+	default:
+		j.state = StateSucceeded
+	}
+}
+
+// runs jobs in the background and keeps the most recent ones around for polling
+type Manager struct {
+	mu      sync.RWMutex
+	jobs    map[string]*Job
+	retain  int
+	baseCtx context.Context
+}
+
+// creates a manager that keeps up to retain finished jobs, jobs stop when ctx is cancelled
+func NewManager(ctx context.Context, retain int) *Manager {
+	return &Manager{
+		jobs:    make(map[string]*Job),
+		retain:  retain,
+		baseCtx: ctx,
+	}
+}
+
+// starts fn in the background and returns its job straight away
+func (m *Manager) Start(kind, target string, fn func(ctx context.Context, job *Job) error) *Job {
+	ctx, cancel := context.WithCancel(m.baseCtx)
+	job := &Job{
+		id:        newJobID(),
+		kind:      kind,
+		target:    target,
+		state:     StateRunning,
+		startedAt: time.Now(),
+	}
+
+	m.mu.Lock()
+	m.jobs[job.id] = job
+	m.pruneLocked()
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := fn(ctx, job)
+		job.finish(ctx, err)
+		status := job.Status()
+		log.Printf("Job %s (%s %s) %s: %d/%d processed, %d errors", job.id, kind, target, status.State, status.Processed, status.Total, len(status.Errors))
+	}()
+
+	return job
+}
+
+// looks up a job by id
+func (m *Manager) Get(id string) (*Job, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// returns every known job newest first
+func (m *Manager) List() []models.JobStatus {
+	m.mu.RLock()
+	statuses := make([]models.JobStatus, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		statuses = append(statuses, job.Status())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].StartedAt.After(statuses[j].StartedAt)
+	})
+	return statuses
+}
+
+// drops the oldest finished jobs beyond the retention limit
+func (m *Manager) pruneLocked() {
+	if m.retain <= 0 || len(m.jobs) <= m.retain {
+		return
+	}
+
+	finished := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		job.mu.Lock()
+		done := !job.finishedAt.IsZero()
+		job.mu.Unlock()
+		if done {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].startedAt.Before(finished[j].startedAt)
+	})
+
+	for _, job := range finished {
+		if len(m.jobs) <= m.retain {
+			return
+		}
+		delete(m.jobs, job.id)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitForState(t *testing.T, job *Job, state string) {
+	t.Helper()
+	require.Eventually(t, func() bool { return job.Status().State == state }, time.Second, 5*time.Millisecond)
+}
+
+func TestStart_WhenJobSucceeds_ReportsProgress(t *testing.T) {
+	// Arrange
+	m := NewManager(context.Background(), 10)
+
+	// Act
+	job := m.Start("refresh_prices", "", func(ctx context.Context, job *Job) error {
+		job.SetTotal(3)
+		job.Advance()
+		job.Advance()
+		job.Fail("STONE3", errors.New("no data"))
+		return nil
+	})
+	waitForState(t, job, StateSucceeded)
+
+	// Assert
+	status := job.Status()
+	assert.Equal(t, 3, status.Processed)
+	assert.Equal(t, 3, status.Total)
+	assert.Equal(t, []string{"STONE3: no data"}, status.Errors)
+	assert.NotNil(t, status.FinishedAt)
+	assert.Nil(t, status.ETASeconds)
+}
+
+func TestStart_WhenManagerContextCancelled_MarksJobCancelled(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	m := NewManager(ctx, 10)
+	job := m.Start("refresh_hypixel", "", func(ctx context.Context, job *Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// Act
+	cancel()
+
+	// Assert
+	waitForState(t, job, StateCancelled)
+}
+
+func TestStatus_WhenPartiallyDone_EstimatesRemainingTime(t *testing.T) {
+	// Arrange
+	m := NewManager(context.Background(), 10)
+	release := make(chan struct{})
+	defer close(release)
+	progressed := make(chan struct{})
+
+	// Act
+	job := m.Start("refresh_prices", "", func(ctx context.Context, job *Job) error {
+		job.SetTotal(4)
+		time.Sleep(10 * time.Millisecond)
+		job.Advance()
+		close(progressed)
+		<-release
+		return nil
+	})
+	<-progressed
+
+	// Assert
+	status := job.Status()
+	assert.Equal(t, StateRunning, status.State)
+	require.NotNil(t, status.ETASeconds)
+	assert.Greater(t, *status.ETASeconds, 0.0)
+}
+
+func TestStart_WhenRetentionExceeded_DropsOldestFinishedJobs(t *testing.T) {
+	// Arrange
+	m := NewManager(context.Background(), 2)
+	var first *Job
+
+	// Act
+	for i := 0; i < 3; i++ {
+		job := m.Start("reload_neu", "", func(ctx context.Context, job *Job) error { return nil })
+		waitForState(t, job, StateSucceeded)
+		if first == nil {
+			first = job
+		}
+	}
+	m.Start("reload_neu", "", func(ctx context.Context, job *Job) error { return nil })
+
+	// Assert
+	_, ok := m.Get(first.ID())
+	assert.False(t, ok)
+	assert.LessOrEqual(t, len(m.List()), 3)
+}
@@ -0,0 +1,282 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// returned by validate when this instance no longer holds the lease
+var ErrNotLeader = errors.New("not the scheduler leader")
+
+const (
+	defaultKey      = "yard:leader"
+	defaultTokenKey = "yard:leader:fencing_token"
+)
+
+// grabs the lease if it is free and hands out the next fencing token
+var acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	local token = redis.call("INCR", KEYS[2])
+	redis.call("SET", KEYS[1], ARGV[1] .. ":" .. token, "PX", ARGV[2])
+	return token
+end
+return 0
+`)
+
+// extends the lease only while it still carries our value
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// deletes the lease only while it still carries our value
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// timing for the lease
+type Options struct {
+	LeaseTTL      time.Duration
+	RenewInterval time.Duration
+}
+
+// returns a 15 second lease renewed every 5 seconds
+func DefaultOptions() Options {
+	return Options{
+		LeaseTTL:      15 * time.Second,
+		RenewInterval: 5 * time.Second,
+	}
+}
+
+// campaigns for a redis lease so only one replica runs the background jobs
+type Elector struct {
+	rdb      *redis.Client
+	key      string
+	tokenKey string
+	id       string
+	opts     Options
+
+	mu         sync.Mutex
+	value      string
+	leader     atomic.Bool
+	token      atomic.Int64
+	onChange   func(leader bool, token int64)
+	campaigned chan struct{}
+	firstOnce  sync.Once
+}
+
+// creates an elector with a unique instance id
+func NewElector(rdb *redis.Client, opts Options) *Elector {
+	return &Elector{
+		rdb:      rdb,
+		key:      defaultKey,
+		tokenKey: defaultTokenKey,
+		id:       instanceID(),
+		opts:     opts,
+
+		campaigned: make(chan struct{}),
+	}
+}
+
+// closed once the first campaign attempt has finished, win or lose
+func (e *Elector) Campaigned() <-chan struct{} {
+	return e.campaigned
+}
+
+// registers a callback fired whenever leadership is gained or lost
+func (e *Elector) OnChange(fn func(leader bool, token int64)) {
+	e.onChange = fn
+}
+
+// returns the id this instance campaigns with
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// reports whether this instance currently holds the lease
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// returns the fencing token of the current term, zero when not leader
+func (e *Elector) Token() int64 {
+	if !e.IsLeader() {
+		return 0
+	}
+	return e.token.Load()
+}
+
+// returns the lease key and the value it carries while this instance leads, for stores that check it inside each write
+func (e *Elector) Lease() (key, value string, ok bool) {
+	e.mu.Lock()
+	value = e.value
+	e.mu.Unlock()
+	return e.key, value, value != "" && e.IsLeader()
+}
+
+// confirms the lease still carries our value and token, checked before starting work so a deposed leader stops early
+// it can't stop a write racing the lease change, a store fenced by Lease rejects those
+func (e *Elector) Validate(ctx context.Context) error {
+	e.mu.Lock()
+	value := e.value
+	e.mu.Unlock()
+
+	if value == "" || !e.IsLeader() {
+		return ErrNotLeader
+	}
+
+	current, err := e.rdb.Get(ctx, e.key).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if current != value {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+// campaigns until ctx is done, running lead in its own context for every term won
+// lead must return once its context is cancelled, the lease is released when run exits
+func (e *Elector) Run(ctx context.Context, lead func(ctx context.Context)) {
+	ticker := time.NewTicker(e.opts.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		won := e.tryAcquire(ctx)
+		e.firstOnce.Do(func() { close(e.campaigned) })
+		if won {
+			e.holdLease(ctx, ticker, lead)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// attempts to take the lease once
+func (e *Elector) tryAcquire(ctx context.Context) bool {
+	token, err := acquireScript.Run(ctx, e.rdb, []string{e.key, e.tokenKey}, e.id, e.opts.LeaseTTL.Milliseconds()).Int64()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Leader election: failed to campaign: %v", err)
+		}
+		return false
+	}
+	if token == 0 {
+		return false
+	}
+
+	e.mu.Lock()
+	e.value = e.id + ":" + strconv.FormatInt(token, 10)
+	e.mu.Unlock()
+	e.token.Store(token)
+	e.setLeader(true)
+	log.Printf("Leader election: %s became leader (fencing token %d)", e.id, token)
+	return true
+}
+
+// runs lead while renewing the lease, stepping down when a renewal fails or ctx ends
+func (e *Elector) holdLease(ctx context.Context, ticker *time.Ticker, lead func(ctx context.Context)) {
+	termCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		lead(termCtx)
+	}()
+
+	defer func() {
+		cancel()
+		<-done
+		e.setLeader(false)
+		e.release()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			if !e.renew(ctx) {
+				log.Printf("Leader election: %s lost the lease, stepping down", e.id)
+				return
+			}
+		}
+	}
+}
+
+// extends the lease and reports whether we still hold it
+func (e *Elector) renew(ctx context.Context) bool {
+	e.mu.Lock()
+	value := e.value
+	e.mu.Unlock()
+
+	renewCtx, cancel := context.WithTimeout(ctx, e.opts.RenewInterval)
+	defer cancel()
+
+	ok, err := renewScript.Run(renewCtx, e.rdb, []string{e.key}, value, e.opts.LeaseTTL.Milliseconds()).Int64()
+	if err != nil {
+		log.Printf("Leader election: failed to renew lease: %v", err)
+		return false
+	}
+	return ok == 1
+}
+
+// gives the lease up early so another replica can take over without waiting for expiry
+func (e *Elector) release() {
+	e.mu.Lock()
+	value := e.value
+	e.value = ""
+	e.mu.Unlock()
+
+	if value == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := releaseScript.Run(ctx, e.rdb, []string{e.key}, value).Err(); err != nil {
+		log.Printf("Leader election: failed to release lease: %v", err)
+	}
+}
+
+func (e *Elector) setLeader(leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+	if e.onChange != nil {
+		e.onChange(leader, e.token.Load())
+	}
+}
+
+// builds an id from the hostname pid and a random suffix
+func instanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", strings.ReplaceAll(host, ":", "_"), os.Getpid(), hex.EncodeToString(suffix))
+}
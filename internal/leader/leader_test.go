@@ -0,0 +1,105 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestElector(t *testing.T, mr *miniredis.Miniredis) *Elector {
+	t.Helper()
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return NewElector(rdb, Options{LeaseTTL: 200 * time.Millisecond, RenewInterval: 20 * time.Millisecond})
+}
+
+func TestRun_WhenTwoInstancesCampaign_OnlyOneLeads(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	first := newTestElector(t, mr)
+	second := newTestElector(t, mr)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	go first.Run(ctx, func(ctx context.Context) { <-ctx.Done() })
+	<-first.Campaigned()
+	go second.Run(ctx, func(ctx context.Context) { <-ctx.Done() })
+	<-second.Campaigned()
+
+	// Assert
+	assert.True(t, first.IsLeader())
+	assert.False(t, second.IsLeader())
+	assert.NoError(t, first.Validate(ctx))
+	assert.ErrorIs(t, second.Validate(ctx), ErrNotLeader)
+	key, value, ok := first.Lease()
+	assert.True(t, ok)
+	held, err := mr.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, held, value)
+	_, _, ok = second.Lease()
+	assert.False(t, ok)
+}
+
+func TestRun_WhenLeaderStops_FollowerTakesOverWithHigherToken(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	first := newTestElector(t, mr)
+	second := newTestElector(t, mr)
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		first.Run(firstCtx, func(ctx context.Context) { <-ctx.Done() })
+	}()
+	<-first.Campaigned()
+	firstToken := first.Token()
+	go second.Run(ctx, func(ctx context.Context) { <-ctx.Done() })
+	<-second.Campaigned()
+
+	// Act
+	stopFirst()
+	<-firstDone
+
+	// Assert
+	require.Eventually(t, second.IsLeader, 2*time.Second, 10*time.Millisecond)
+	assert.False(t, first.IsLeader())
+	assert.Greater(t, second.Token(), firstToken)
+}
+
+func TestRun_WhenLeaseStolen_StepsDown(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	e := newTestElector(t, mr)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	termEnded := make(chan struct{})
+	go e.Run(ctx, func(ctx context.Context) {
+		<-ctx.Done()
+		close(termEnded)
+	})
+	<-e.Campaigned()
+	require.True(t, e.IsLeader())
+
+	// Act
+	mr.Set(defaultKey, "someone-else:99")
+
+	// Assert
+	select {
+	case <-termEnded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("leader term was not cancelled after losing the lease")
+	}
+	assert.ErrorIs(t, e.Validate(ctx), ErrNotLeader)
+	_, _, ok := e.Lease()
+	assert.False(t, ok, "a store fenced by the lease rejects writes once the term ended")
+}
@@ -0,0 +1,87 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+)
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// tracks readiness and runs registered shutdown hooks in order
+type Manager struct {
+	ready    atomic.Bool
+	mu       sync.Mutex
+	hooks    []shutdownHook
+	shutdown sync.Once
+	err      error
+}
+
+// creates a manager that starts out not ready
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// marks the instance as ready to serve traffic
+func (m *Manager) MarkReady() {
+	if !m.ready.Swap(true) {
+		log.Println("Warm-up complete, instance is ready")
+	}
+}
+
+// reports whether warm-up has finished and shutdown has not started
+func (m *Manager) IsReady() bool {
+	return m.ready.Load()
+}
+
+// registers a hook to run on shutdown, hooks run in the order they were registered
+func (m *Manager) OnShutdown(name string, fn func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// blocks until one of the signals arrives or ctx is done and returns the signal if any
+func (m *Manager) WaitForSignal(ctx context.Context, signals ...os.Signal) os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	defer signal.Stop(ch)
+
+	select {
+	case sig := <-ch:
+		return sig
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// marks the instance not ready and runs every hook, only the first call does any work
+// every hook runs even if an earlier one fails, the errors are joined
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.shutdown.Do(func() {
+		m.ready.Store(false)
+
+		m.mu.Lock()
+		hooks := append([]shutdownHook(nil), m.hooks...)
+		m.mu.Unlock()
+
+		var errs []error
+		for _, hook := range hooks {
+			log.Printf("Shutdown: %s", hook.name)
+			if err := hook.fn(ctx); err != nil {
+				log.Printf("Shutdown: %s failed: %v", hook.name, err)
+				errs = append(errs, fmt.Errorf("%s: %w", hook.name, err))
+			}
+		}
+		m.err = errors.Join(errs...)
+	})
+	return m.err
+}
@@ -0,0 +1,65 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManager_WhenCreated_IsNotReady(t *testing.T) {
+	// Arrange
+	m := NewManager()
+
+	// Act
+	ready := m.IsReady()
+
+	// Assert
+	assert.False(t, ready)
+}
+
+func TestShutdown_WhenHooksRegistered_RunsInOrderAndClearsReady(t *testing.T) {
+	// Arrange
+	m := NewManager()
+	m.MarkReady()
+	var order []string
+	m.OnShutdown("first", func(ctx context.Context) error {
+		order = append(order, "first")
+		return nil
+	})
+	m.OnShutdown("second", func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	// Act
+	err := m.Shutdown(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, order)
+	assert.False(t, m.IsReady())
+}
+
+func TestShutdown_WhenHookFails_StillRunsRemainingHooks(t *testing.T) {
+	// Arrange
+	m := NewManager()
+	ran := false
+	m.OnShutdown("broken", func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+	m.OnShutdown("after", func(ctx context.Context) error {
+		ran = true
+		return nil
+	})
+
+	// Act
+	err := m.Shutdown(context.Background())
+	second := m.Shutdown(context.Background())
+
+	// Assert
+	assert.ErrorContains(t, err, "broken: boom")
+	assert.True(t, ran)
+	assert.Equal(t, err, second)
+}
@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+// how long a scrape may spend reading the snapshot before the market gauges are skipped
+const marketScrapeTimeout = 5 * time.Second
+
+var (
+	marketUp = prometheus.NewDesc(
+		"yard_market_up",
+		"Whether the current snapshot could be read for the market gauges (1) or not (0)",
+		nil, nil,
+	)
+	marketLowestBIN = prometheus.NewDesc(
+		"yard_market_lowest_bin",
+		"Lowest buy it now auction price of a stone in coins",
+		[]string{"stone"}, nil,
+	)
+	marketBazaarBuy = prometheus.NewDesc(
+		"yard_market_bazaar_buy_price",
+		"Bazaar instant buy price of a stone in coins",
+		[]string{"stone"}, nil,
+	)
+	marketBazaarSell = prometheus.NewDesc(
+		"yard_market_bazaar_sell_price",
+		"Bazaar instant sell price of a stone in coins",
+		[]string{"stone"}, nil,
+	)
+	marketBazaarSpread = prometheus.NewDesc(
+		"yard_market_bazaar_spread",
+		"Bazaar buy price minus sell price of a stone in coins",
+		[]string{"stone"}, nil,
+	)
+	marketTopOfBook = prometheus.NewDesc(
+		"yard_market_top_of_book_volume",
+		"Items offered by the best bazaar order of a stone on each side",
+		[]string{"stone", "side"}, nil,
+	)
+	marketDataAge = prometheus.NewDesc(
+		"yard_market_data_age_seconds",
+		"Seconds since the stone list or the prices were last refreshed",
+		[]string{"source"}, nil,
+	)
+	marketStones = prometheus.NewDesc(
+		"yard_market_stones",
+		"Stones currently listed by Hypixel",
+		nil, nil,
+	)
+	marketMissingPrices = prometheus.NewDesc(
+		"yard_market_stones_missing_price",
+		"Listed stones without an auction price, without bazaar prices, or without any price",
+		[]string{"price"}, nil,
+	)
+	marketOmitted = prometheus.NewDesc(
+		"yard_market_stones_omitted",
+		"Listed stones left out of the per stone gauges by metrics.market_stones",
+		nil, nil,
+	)
+)
+
+// exports prices and data age of the current snapshot, read from storage on every scrape
+type MarketCollector struct {
+	store     storage.Store
+	maxStones atomic.Int64
+}
+
+// creates a collector reading store that exports per stone gauges for up to maxStones stones
+func NewMarketCollector(store storage.Store, maxStones int) *MarketCollector {
+	c := &MarketCollector{store: store}
+	c.SetMaxStones(maxStones)
+	return c
+}
+
+// creates a market collector and registers it with the default registry served at /metrics
+func RegisterMarket(store storage.Store, maxStones int) *MarketCollector {
+	c := NewMarketCollector(store, maxStones)
+	prometheus.MustRegister(c)
+	return c
+}
+
+// caps how many stones get per stone gauges, stones are taken in id order and 0 turns them off
+func (c *MarketCollector) SetMaxStones(maxStones int) {
+	c.maxStones.Store(int64(maxStones))
+}
+
+func (c *MarketCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		marketUp, marketLowestBIN, marketBazaarBuy, marketBazaarSell, marketBazaarSpread,
+		marketTopOfBook, marketDataAge, marketStones, marketMissingPrices, marketOmitted,
+	} {
+		ch <- desc
+	}
+}
+
+func (c *MarketCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), marketScrapeTimeout)
+	defer cancel()
+
+	stones, err := c.store.AllStones(ctx)
+	if err != nil {
+		log.Printf("Market metrics: loading stones: %v", err)
+		ch <- prometheus.MustNewConstMetric(marketUp, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(marketUp, prometheus.GaugeValue, 1)
+
+	now := time.Now()
+	for source, name := range map[string]string{"hypixel": storage.HypixelUpdated, "prices": storage.PricesUpdated} {
+		updated, err := c.store.Timestamp(ctx, name)
+		if err != nil || updated.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(marketDataAge, prometheus.GaugeValue, now.Sub(updated).Seconds(), source)
+	}
+
+	var listed, missingAuction, missingBazaar, missingAny, omitted int
+	maxStones := int(c.maxStones.Load())
+	for _, stone := range stones {
+		// delisted stones keep their last prices but are no longer traded
+		if stone.DelistedAt != nil {
+			continue
+		}
+		listed++
+		hasAuction := stone.AuctionPrice != nil
+		hasBazaar := stone.BazaarBuyPrice != nil || stone.BazaarSellPrice != nil
+		if !hasAuction {
+			missingAuction++
+		}
+		if !hasBazaar {
+			missingBazaar++
+		}
+		if !hasAuction && !hasBazaar {
+			missingAny++
+		}
+
+		if listed > maxStones {
+			omitted++
+			continue
+		}
+		collectStone(ch, stone)
+	}
+
+	ch <- prometheus.MustNewConstMetric(marketStones, prometheus.GaugeValue, float64(listed))
+	ch <- prometheus.MustNewConstMetric(marketMissingPrices, prometheus.GaugeValue, float64(missingAuction), "auction")
+	ch <- prometheus.MustNewConstMetric(marketMissingPrices, prometheus.GaugeValue, float64(missingBazaar), "bazaar")
+	ch <- prometheus.MustNewConstMetric(marketMissingPrices, prometheus.GaugeValue, float64(missingAny), "any")
+	ch <- prometheus.MustNewConstMetric(marketOmitted, prometheus.GaugeValue, float64(omitted))
+}
+
+// sends the gauges of one stone, prices it has none of are left out rather than reported as 0
+func collectStone(ch chan<- prometheus.Metric, stone models.Item) {
+	if stone.AuctionPrice != nil {
+		ch <- prometheus.MustNewConstMetric(marketLowestBIN, prometheus.GaugeValue, float64(*stone.AuctionPrice), stone.ID)
+	}
+	if stone.BazaarBuyPrice != nil {
+		ch <- prometheus.MustNewConstMetric(marketBazaarBuy, prometheus.GaugeValue, *stone.BazaarBuyPrice, stone.ID)
+	}
+	if stone.BazaarSellPrice != nil {
+		ch <- prometheus.MustNewConstMetric(marketBazaarSell, prometheus.GaugeValue, *stone.BazaarSellPrice, stone.ID)
+	}
+	if stone.BazaarBuyPrice != nil && stone.BazaarSellPrice != nil {
+		ch <- prometheus.MustNewConstMetric(marketBazaarSpread, prometheus.GaugeValue, *stone.BazaarBuyPrice-*stone.BazaarSellPrice, stone.ID)
+	}
+	// orders are kept best first
+	if len(stone.BazaarBuyOrders) > 0 {
+		ch <- prometheus.MustNewConstMetric(marketTopOfBook, prometheus.GaugeValue, float64(stone.BazaarBuyOrders[0].Amount), stone.ID, "buy")
+	}
+	if len(stone.BazaarSellOrders) > 0 {
+		ch <- prometheus.MustNewConstMetric(marketTopOfBook, prometheus.GaugeValue, float64(stone.BazaarSellOrders[0].Amount), stone.ID, "sell")
+	}
+}
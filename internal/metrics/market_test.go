@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+func TestMarketCollector_WhenStonesCapped_ExportsTotalsForEveryStone(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := storage.NewMemory()
+	bin := int64(1500)
+	buy, sell := 120.5, 100.0
+	delisted := time.Now()
+	_, err := store.Publish(ctx, []models.Item{
+		{ID: "AMBER", AuctionPrice: &bin, BazaarBuyPrice: &buy, BazaarSellPrice: &sell,
+			BazaarBuyOrders: []models.BazaarOrder{{Amount: 64, PricePerUnit: 100}}},
+		{ID: "BLAZE_WAX"},
+		{ID: "DRAGON_CLAW", AuctionPrice: &bin},
+		{ID: "OLD_STONE", DelistedAt: &delisted},
+	}, 1)
+	require.NoError(t, err)
+	require.NoError(t, store.SetTimestamp(ctx, storage.PricesUpdated, time.Now().Add(-time.Minute)))
+	collector := NewMarketCollector(store, 2)
+
+	// Act
+	err = testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP yard_market_bazaar_spread Bazaar buy price minus sell price of a stone in coins
+# TYPE yard_market_bazaar_spread gauge
+yard_market_bazaar_spread{stone="AMBER"} 20.5
+# HELP yard_market_lowest_bin Lowest buy it now auction price of a stone in coins
+# TYPE yard_market_lowest_bin gauge
+yard_market_lowest_bin{stone="AMBER"} 1500
+# HELP yard_market_stones_missing_price Listed stones without an auction price, without bazaar prices, or without any price
+# TYPE yard_market_stones_missing_price gauge
+yard_market_stones_missing_price{price="any"} 1
+yard_market_stones_missing_price{price="auction"} 1
+yard_market_stones_missing_price{price="bazaar"} 2
+# HELP yard_market_stones_omitted Listed stones left out of the per stone gauges by metrics.market_stones
+# TYPE yard_market_stones_omitted gauge
+yard_market_stones_omitted 1
+# HELP yard_market_top_of_book_volume Items offered by the best bazaar order of a stone on each side
+# TYPE yard_market_top_of_book_volume gauge
+yard_market_top_of_book_volume{side="buy",stone="AMBER"} 64
+`), "yard_market_bazaar_spread", "yard_market_lowest_bin", "yard_market_stones_missing_price",
+		"yard_market_stones_omitted", "yard_market_top_of_book_volume")
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, 1, testutil.CollectAndCount(collector, "yard_market_data_age_seconds"))
+}
+
+func TestMarketCollector_WhenPerStoneGaugesOff_KeepsOnlyTotals(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := storage.NewMemory()
+	bin := int64(1500)
+	_, err := store.Publish(ctx, []models.Item{{ID: "AMBER", AuctionPrice: &bin}}, 1)
+	require.NoError(t, err)
+	collector := NewMarketCollector(store, 5)
+
+	// Act
+	collector.SetMaxStones(0)
+
+	// Assert
+	assert.Equal(t, 0, testutil.CollectAndCount(collector, "yard_market_lowest_bin"))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector, "yard_market_stones"))
+}
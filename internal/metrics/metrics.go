@@ -4,8 +4,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-
-	"yard-backend/internal/config"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -31,6 +31,15 @@ var (
 		[]string{"method", "endpoint", "status_code"},
 	)
 
+	httpRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yard_http_request_duration_seconds",
+			Help:    "Time taken to answer HTTP requests by route template",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route"},
+	)
+
 	httpRequestsByCountry = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "yard_http_requests_by_country_total",
@@ -38,6 +47,102 @@ var (
 		},
 		[]string{"country", "endpoint"},
 	)
+
+	httpRequestsByASN = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "yard_http_requests_by_asn_total",
+			Help: "Total number of HTTP requests by autonomous system, asns past the label limit are counted as other",
+		},
+		[]string{"asn"},
+	)
+
+	// asns that got their own label, capped so clients spread over many networks can't grow the series without bound
+	asnLabels = struct {
+		sync.Mutex
+		limit int
+		seen  map[uint]bool
+	}{seen: make(map[uint]bool)}
+
+	upstreamRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yard_upstream_request_duration_seconds",
+			Help:    "Time until upstream servers answered, per attempt, status is error when no response arrived",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"host", "status"},
+	)
+
+	priceRefreshDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yard_price_refresh_duration_seconds",
+			Help:    "Time taken by price refreshes",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+		},
+		[]string{"outcome"},
+	)
+
+	priceRefreshFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "yard_price_refresh_item_failures_total",
+			Help: "Prices of single stones that could not be fetched during a refresh",
+		},
+		[]string{"source"},
+	)
+
+	redisCommandDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yard_redis_command_duration_seconds",
+			Help:    "Time taken by redis commands, pipelines are counted as one",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 2, 12),
+		},
+		[]string{"command"},
+	)
+
+	neuLoadDuration = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yard_neu_load_duration_seconds",
+			Help: "Time the last load of each NEU file took",
+		},
+		[]string{"file"},
+	)
+
+	neuItems = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "yard_neu_items",
+			Help: "Entries read from each NEU file by its last load",
+		},
+		[]string{"file"},
+	)
+
+	textureRegistrySize = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "yard_texture_registry_size",
+			Help: "Item textures loaded from the resource pack",
+		},
+	)
+
+	imageRenderDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "yard_image_render_duration_seconds",
+			Help:    "Time taken to scale and encode item images",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 12),
+		},
+		[]string{"source"},
+	)
+
+	schedulerLeader = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "yard_scheduler_leader",
+			Help: "Whether this instance currently holds the scheduler leader lease (1) or not (0)",
+		},
+	)
+
+	schedulerFencingToken = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "yard_scheduler_fencing_token",
+			Help: "Fencing token of the most recent leader term won by this instance",
+		},
+	)
 )
 
 func Init(enable bool) {
@@ -64,6 +169,13 @@ func RecordError(method, endpoint string, statusCode int) {
 	}
 }
 
+func ObserveRequestDuration(method, route string, duration time.Duration) {
+	if !enabled {
+		return
+	}
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
 func RecordRequestByCountry(country, endpoint string) {
 	if !enabled {
 		return
@@ -74,124 +186,105 @@ func RecordRequestByCountry(country, endpoint string) {
 	httpRequestsByCountry.WithLabelValues(country, endpoint).Inc()
 }
 
-// returns the prometheus metrics handler with optional ip whitelisting
-func GetHandler() http.Handler {
-	handler := promhttp.Handler()
-
-	if config.MetricsIPWhitelist == "" {
-		return handler
+// records a request by the asn it came from, 0 when the asn is unknown
+func RecordRequestByASN(asn uint) {
+	if !enabled {
+		return
 	}
+	httpRequestsByASN.WithLabelValues(asnLabel(asn)).Inc()
+}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientIP := getClientIPFromRequest(r)
+// sets how many distinct asns get their own label, asns labelled already keep theirs
+func SetMaxASNs(limit int) {
+	asnLabels.Lock()
+	defer asnLabels.Unlock()
+	asnLabels.limit = limit
+}
 
-		if !isIPAllowed(clientIP, config.MetricsIPWhitelist) {
-			http.Error(w, "Forbidden", http.StatusForbidden)
-			return
+func asnLabel(asn uint) string {
+	if asn == 0 {
+		return "unknown"
+	}
+	asnLabels.Lock()
+	defer asnLabels.Unlock()
+	if !asnLabels.seen[asn] {
+		if len(asnLabels.seen) >= asnLabels.limit {
+			return "other"
 		}
-
-		handler.ServeHTTP(w, r)
-	})
+		asnLabels.seen[asn] = true
+	}
+	return "AS" + strconv.FormatUint(uint64(asn), 10)
 }
 
-func getClientIPFromRequest(r *http.Request) string {
-	ip := r.Header.Get("CF-Connecting-IP")
-	if ip != "" {
-		return ip
+// records one attempt of an upstream call, status 0 when it failed without a response
+func ObserveUpstream(host string, status int, duration time.Duration) {
+	if !enabled {
+		return
+	}
+	label := "error"
+	if status > 0 {
+		label = strconv.Itoa(status)
 	}
+	upstreamRequestDuration.WithLabelValues(host, label).Observe(duration.Seconds())
+}
 
-	ip = r.Header.Get("X-Real-IP")
-	if ip != "" {
-		return ip
+// records a finished price refresh, failed covers refreshes that were cancelled or couldn't publish
+func ObservePriceRefresh(failed bool, duration time.Duration) {
+	if !enabled {
+		return
 	}
+	outcome := "success"
+	if failed {
+		outcome = "failed"
+	}
+	priceRefreshDuration.WithLabelValues(outcome).Observe(duration.Seconds())
+}
 
-	ip = r.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		ips := strings.Split(ip, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
+// records a stone whose auction or bazaar price couldn't be fetched
+func RecordPriceFailure(source string) {
+	if !enabled {
+		return
 	}
+	priceRefreshFailures.WithLabelValues(source).Inc()
+}
 
-	return strings.Split(r.RemoteAddr, ":")[0]
+// records how long loading a neu file took and how many entries it had
+func SetNEULoaded(file string, items int, duration time.Duration) {
+	if !enabled {
+		return
+	}
+	neuLoadDuration.WithLabelValues(file).Set(duration.Seconds())
+	neuItems.WithLabelValues(file).Set(float64(items))
 }
 
-func isIPAllowed(ip, whitelist string) bool {
-	if whitelist == "" {
-		return true
+func SetTextureRegistrySize(textures int) {
+	if !enabled {
+		return
 	}
+	textureRegistrySize.Set(float64(textures))
+}
 
-	allowedIPs := strings.Split(whitelist, ",")
-	for _, allowed := range allowedIPs {
-		allowed = strings.TrimSpace(allowed)
-		if allowed == "*" {
-			return true
-		}
-		if ip == allowed {
-			return true
-		}
+// records scaling and encoding one item image, source is resource_pack or skin
+func ObserveImageRender(source string, duration time.Duration) {
+	if !enabled {
+		return
+	}
+	imageRenderDuration.WithLabelValues(source).Observe(duration.Seconds())
+}
 
-		// handle CIDR notation
-		if strings.Contains(allowed, "/") {
-			parts := strings.Split(allowed, "/")
-			if len(parts) != 2 {
-				continue
-			}
-
-			networkIP := parts[0]
-			maskStr := parts[1]
-
-			maskBits, err := strconv.Atoi(maskStr)
-			if err != nil || maskBits < 0 || maskBits > 32 {
-				continue
-			}
-
-			// parse IPs
-			networkParts := strings.Split(networkIP, ".")
-			ipParts := strings.Split(ip, ".")
-
-			if len(networkParts) != 4 || len(ipParts) != 4 {
-				continue
-			}
-
-			// calculate how many full octets to check
-			fullOctets := maskBits / 8
-			remainingBits := maskBits % 8
-
-			// check full octets
-			match := true
-			for i := 0; i < fullOctets && i < 4; i++ {
-				netOctet, err1 := strconv.Atoi(networkParts[i])
-				ipOctet, err2 := strconv.Atoi(ipParts[i])
-				if err1 != nil || err2 != nil || netOctet != ipOctet {
-					match = false
-					break
-				}
-			}
-
-			if !match {
-				continue
-			}
-
-			// check partial octet if needed
-			if remainingBits > 0 && fullOctets < 4 {
-				netOctet, err1 := strconv.Atoi(networkParts[fullOctets])
-				ipOctet, err2 := strconv.Atoi(ipParts[fullOctets])
-				if err1 != nil || err2 != nil {
-					continue
-				}
-
-				mask := (0xFF << (8 - remainingBits)) & 0xFF
-				if (netOctet & mask) != (ipOctet & mask) {
-					continue
-				}
-			}
-
-			return true
-		}
+// records whether this instance is the scheduler leader and the token of its latest term
+func SetLeader(leader bool, token int64) {
+	if leader {
+		schedulerLeader.Set(1)
+		schedulerFencingToken.Set(float64(token))
+		return
 	}
+	schedulerLeader.Set(0)
+}
 
-	return false
+// returns the prometheus metrics handler, who may scrape it is decided by the /metrics acl
+func GetHandler() http.Handler {
+	return promhttp.Handler()
 }
 
 // cleans up endpoint paths so metrics are consistent
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestASNLabel_WhenLimitReached_CountsNewASNsAsOther(t *testing.T) {
+	// Arrange
+	SetMaxASNs(2)
+	t.Cleanup(func() {
+		SetMaxASNs(0)
+		clear(asnLabels.seen)
+	})
+	asnLabel(13335)
+	asnLabel(15169)
+
+	// Act
+	labels := []string{asnLabel(13335), asnLabel(16509), asnLabel(0)}
+
+	// Assert
+	assert.Equal(t, []string{"AS13335", "other", "unknown"}, labels)
+}
+
+func TestMetricsMiddleware_WhenRequestServed_ObservesItsDurationByRouteTemplate(t *testing.T) {
+	// Arrange
+	Init(true)
+	t.Cleanup(func() { Init(false) })
+	r := mux.NewRouter()
+	r.Use(MetricsMiddleware)
+	r.HandleFunc("/api/v2/item/{itemId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	before := sampleCount(t, httpRequestDuration, "GET", "/api/v2/item/{itemId}")
+
+	// Act
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v2/item/AMBER", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/v2/item/JADE", nil))
+
+	// Assert
+	assert.Equal(t, before+2, sampleCount(t, httpRequestDuration, "GET", "/api/v2/item/{itemId}"))
+}
+
+func TestMetricsMiddleware_WhenNoRouteMatched_ObservesUnderFixedLabel(t *testing.T) {
+	// Arrange
+	Init(true)
+	t.Cleanup(func() { Init(false) })
+	handler := MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	before := sampleCount(t, httpRequestDuration, "GET", "unmatched")
+
+	// Act
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/wp-login.php", nil))
+
+	// Assert
+	assert.Equal(t, before+1, sampleCount(t, httpRequestDuration, "GET", "unmatched"))
+}
+
+func TestRedisHook_WhenCommandsRun_ObservesEachByName(t *testing.T) {
+	// Arrange
+	Init(true)
+	t.Cleanup(func() { Init(false) })
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	client.AddHook(RedisHook())
+	ctx := context.Background()
+	// connecting sends a pipeline of its own
+	require.NoError(t, client.Ping(ctx).Err())
+	sets, pipelines := sampleCount(t, redisCommandDuration, "set"), sampleCount(t, redisCommandDuration, "pipeline")
+
+	// Act
+	require.NoError(t, client.Set(ctx, "key", "value", 0).Err())
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "key")
+		return nil
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, sets+1, sampleCount(t, redisCommandDuration, "set"))
+	assert.Equal(t, pipelines+1, sampleCount(t, redisCommandDuration, "pipeline"))
+}
+
+// returns how many observations the histogram with the given labels has
+func sampleCount(t *testing.T, vec *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	require.NoError(t, vec.WithLabelValues(labels...).(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
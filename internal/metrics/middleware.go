@@ -2,9 +2,24 @@ package metrics
 
 import (
 	"net/http"
+	"net/netip"
 	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"yard-backend/internal/clientip"
+	"yard-backend/internal/geoip"
 )
 
+// looks up countries and asns of clients no proxy header told us about, unset until the databases are configured
+var resolver atomic.Pointer[geoip.Resolver]
+
+// sets the resolver used by the country and asn metrics
+func SetGeoIP(r *geoip.Resolver) {
+	resolver.Store(r)
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
@@ -15,6 +30,17 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+func (rw *responseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// lets http.ResponseController reach the writers underneath
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !IsEnabled() {
@@ -27,7 +53,9 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 			statusCode:     http.StatusOK,
 		}
 
+		start := time.Now()
 		next.ServeHTTP(rw, r)
+		duration := time.Since(start)
 
 		method := r.Method
 		endpoint := NormalizeEndpoint(r.URL.Path)
@@ -35,15 +63,41 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 
 		RecordRequest(method, endpoint, statusCode)
 		RecordError(method, endpoint, statusCode)
+		ObserveRequestDuration(method, routeTemplate(r), duration)
+
+		ip := clientip.FromRequest(r)
+		var location geoip.Location
+		geo := resolver.Load()
+		if geo != nil && ip.IsValid() {
+			location = geo.Lookup(ip)
+		}
 
-		country := getCountryFromRequest(r)
-		RecordRequestByCountry(country, endpoint)
+		RecordRequestByCountry(getCountryFromRequest(r, ip, location), endpoint)
+		if geo != nil && geo.HasASN() {
+			RecordRequestByASN(location.ASN)
+		}
 	})
 }
 
+// label of requests no route matched, kept fixed so scanners can't create series
+const unmatchedRoute = "unmatched"
+
+// returns the template of the matched route, e.g. /api/v2/item/{itemId}, so every item shares one series
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return unmatchedRoute
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return unmatchedRoute
+	}
+	return template
+}
+
 // tries to figure out the country from request headers
-// looks for cloudflare header first then falls back to other headers
-func getCountryFromRequest(r *http.Request) string {
+// looks for cloudflare header first then falls back to other headers and the local database
+func getCountryFromRequest(r *http.Request, ip netip.Addr, location geoip.Location) string {
 	country := r.Header.Get("CF-IPCountry")
 	if country != "" && country != "XX" {
 		return strings.ToUpper(country)
@@ -54,38 +108,19 @@ func getCountryFromRequest(r *http.Request) string {
 		return strings.ToUpper(country)
 	}
 
-	ip := getClientIP(r)
-	if ip != "" {
-		return getCountryFromIP(ip)
-	}
-
-	return "unknown"
-}
-
-func getClientIP(r *http.Request) string {
-	ip := r.Header.Get("CF-Connecting-IP")
-	if ip != "" {
-		return ip
-	}
-
-	ip = r.Header.Get("X-Real-IP")
-	if ip != "" {
-		return ip
+	if location.Country != "" {
+		return strings.ToUpper(location.Country)
 	}
 
-	ip = r.Header.Get("X-Forwarded-For")
-	if ip != "" {
-		ips := strings.Split(ip, ",")
-		if len(ips) > 0 {
-			return strings.TrimSpace(ips[0])
-		}
+	if ip.IsValid() {
+		return getCountryFromIP(ip)
 	}
 
-	return strings.Split(r.RemoteAddr, ":")[0]
+	return "unknown"
 }
 
-func getCountryFromIP(ip string) string {
-	if ip == "" || ip == "::1" || ip == "127.0.0.1" {
+func getCountryFromIP(ip netip.Addr) string {
+	if ip.IsLoopback() {
 		return "localhost"
 	}
 
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"yard-backend/internal/acl"
+	"yard-backend/internal/clientip"
+	"yard-backend/internal/envelope"
+	"yard-backend/internal/models"
+)
+
+const forbiddenMessage = "your address is not allowed to use this route"
+
+// rejects clients the acl of the requested path doesn't allow, the policy is swapped on config reload
+type AccessControl struct {
+	policy atomic.Pointer[acl.Policy]
+}
+
+// creates access control enforcing policy, nil leaves every route open
+func NewAccessControl(policy *acl.Policy) *AccessControl {
+	a := &AccessControl{}
+	a.policy.Store(policy)
+	return a
+}
+
+// switches to a reloaded policy, requests already past the check are not affected
+func (a *AccessControl) Set(policy *acl.Policy) {
+	a.policy.Store(policy)
+}
+
+func (a *AccessControl) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		list := a.policy.Load().For(r.URL.Path)
+		if list == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		client := clientip.FromRequest(r)
+		if list.Allows(client) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		log.Printf("AUDIT acl client=%s method=%s path=%s status=%d outcome=denied", client, r.Method, r.URL.Path, http.StatusForbidden)
+		if strings.HasPrefix(r.URL.Path, v2Prefix) {
+			envelope.Error(w, http.StatusForbidden, envelope.CodeForbidden, forbiddenMessage)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(models.ErrorResponse{Error: "forbidden", Message: forbiddenMessage})
+	})
+}
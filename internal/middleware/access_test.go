@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/acl"
+)
+
+func TestAccessControl_WhenClientNotAllowed_ReturnsForbidden(t *testing.T) {
+	// Arrange
+	policy, err := acl.ParsePolicy(map[string][]string{"/metrics": {"2001:db8::/32"}}, nil)
+	require.NoError(t, err)
+	handler := NewAccessControl(policy).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Act
+	codes := map[string]int{}
+	for _, remote := range []string{"[2001:db8::5]:443", "192.0.2.1:443"} {
+		req := httptest.NewRequest("GET", "/metrics", nil)
+		req.RemoteAddr = remote
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		codes[remote] = rr.Code
+	}
+
+	// Assert
+	assert.Equal(t, http.StatusOK, codes["[2001:db8::5]:443"])
+	assert.Equal(t, http.StatusForbidden, codes["192.0.2.1:443"])
+}
+
+func TestAccessControl_WhenV2RouteDenied_AnswersWithEnvelope(t *testing.T) {
+	// Arrange
+	policy, err := acl.ParsePolicy(map[string][]string{"/api/v2": {"!*"}}, nil)
+	require.NoError(t, err)
+	handler := RequestID(NewAccessControl(policy).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	req := httptest.NewRequest("GET", "/api/v2/reforges", nil)
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"forbidden"`)
+}
+
+func TestAccessControlSet_WhenPolicyReloaded_AppliesToNextRequest(t *testing.T) {
+	// Arrange
+	access := NewAccessControl(nil)
+	handler := access.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest("POST", "/admin/reload/config", nil)
+	req.RemoteAddr = "192.0.2.1:443"
+	before := httptest.NewRecorder()
+	handler.ServeHTTP(before, req)
+
+	// Act
+	policy, err := acl.ParsePolicy(map[string][]string{"/admin": {"10.0.0.0/8"}}, nil)
+	require.NoError(t, err)
+	access.Set(policy)
+	after := httptest.NewRecorder()
+	handler.ServeHTTP(after, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, before.Code)
+	assert.Equal(t, http.StatusForbidden, after.Code)
+}
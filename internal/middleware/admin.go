@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// records the status code written by an admin handler for the audit log
+type auditResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *auditResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// extracts the bearer token from the authorization header
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// admin auth middleware that requires the given admin token and audits every call
+// admin routes are not subject to the public rate limiter
+func AdminAuthMiddleware(adminToken string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		clientID := getClientID(r)
+		aw := &auditResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		token := bearerToken(r)
+		if adminToken == "" || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			log.Printf("AUDIT admin client=%s method=%s path=%s status=%d outcome=denied", clientID, r.Method, r.URL.Path, http.StatusUnauthorized)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="yard-admin"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized","message":"a valid admin token is required"}`))
+			return
+		}
+
+		next(aw, r)
+
+		log.Printf("AUDIT admin client=%s method=%s path=%s status=%d duration=%v", clientID, r.Method, r.URL.Path, aw.statusCode, time.Since(start).Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAuthMiddleware_WhenTokenMissing_ReturnsUnauthorized(t *testing.T) {
+	// Arrange
+	token := "secret"
+
+	called := false
+	handler := AdminAuthMiddleware(token, func(w http.ResponseWriter, r *http.Request) { called = true })
+	req := httptest.NewRequest("POST", "/admin/refresh/hypixel", nil)
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.False(t, called)
+}
+
+func TestAdminAuthMiddleware_WhenTokenMatches_CallsHandler(t *testing.T) {
+	// Arrange
+	token := "secret"
+
+	handler := AdminAuthMiddleware(token, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	req := httptest.NewRequest("POST", "/admin/refresh/hypixel", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+}
+
+func TestAdminAuthMiddleware_WhenNoTokenConfigured_RejectsEverything(t *testing.T) {
+	// Arrange
+	token := ""
+
+	handler := AdminAuthMiddleware(token, func(w http.ResponseWriter, r *http.Request) {})
+	req := httptest.NewRequest("POST", "/admin/cache/purge", nil)
+	req.Header.Set("Authorization", "Bearer ")
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"yard-backend/internal/compress"
+)
+
+// responses smaller than this are sent as is, compressing them costs more than it saves
+const compressMinSize = 1024
+
+// content types worth compressing, images are already compressed and are left alone
+var compressibleTypes = map[string]bool{
+	"application/json":     true,
+	"application/atom+xml": true,
+	"application/xml":      true,
+	"text/html":            true,
+	"text/plain":           true,
+	"text/xml":             true,
+	"text/css":             true,
+	"text/javascript":      true,
+	"image/svg+xml":        true,
+}
+
+// compresses responses with the best encoding the client accepts
+// goes inside the metrics middleware so the status it records is the one actually sent
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := compress.Negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			// the representation still depends on accept-encoding for compressible types
+			cw := &compressWriter{ResponseWriter: w}
+			next.ServeHTTP(cw, r)
+			cw.finish()
+			return
+		}
+
+		// etags of compressed responses carry the encoding, strip it so handlers compare their own tags
+		if match := r.Header.Get("If-None-Match"); match != "" {
+			r.Header.Set("If-None-Match", compress.StripETags(match))
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		next.ServeHTTP(cw, r)
+		cw.finish()
+	})
+}
+
+// buffers the start of a response until it knows whether compressing it is worthwhile
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         bytes.Buffer
+	encoder     io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = code
+
+	// informational and bodiless responses are passed straight through
+	// a 304 has no content type left but must name the same vary as the full response
+	if code < http.StatusOK || code == http.StatusNoContent || code == http.StatusNotModified {
+		cw.addVary(code == http.StatusNotModified)
+		cw.decided = true
+		cw.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	if !cw.compressible() {
+		cw.start(false)
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf.Write(p)
+	if cw.buf.Len() >= compressMinSize {
+		if err := cw.flushBuffer(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// reports whether the response may be compressed judging by its headers alone
+func (cw *compressWriter) compressible() bool {
+	header := cw.Header()
+	if cw.encoding == "" || header.Get("Content-Encoding") != "" {
+		return false
+	}
+	return isCompressibleType(header.Get("Content-Type"))
+}
+
+func isCompressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && compressibleTypes[mediaType]
+}
+
+func (cw *compressWriter) addVary(force bool) {
+	if !force && !isCompressibleType(cw.Header().Get("Content-Type")) {
+		return
+	}
+	for _, value := range cw.Header().Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), "Accept-Encoding") {
+				return
+			}
+		}
+	}
+	cw.Header().Add("Vary", "Accept-Encoding")
+}
+
+// sends the header, switching to the encoder when encode is set
+func (cw *compressWriter) start(encode bool) {
+	cw.decided = true
+	cw.addVary(false)
+	if encode {
+		header := cw.Header()
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		if etag := header.Get("ETag"); etag != "" {
+			header.Set("ETag", compress.ETag(etag, cw.encoding))
+		}
+		cw.encoder = compress.NewWriter(cw.encoding, cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *compressWriter) flushBuffer(encode bool) error {
+	cw.start(encode)
+	data := cw.buf.Bytes()
+	cw.buf.Reset()
+	if len(data) == 0 {
+		return nil
+	}
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(data)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(data)
+	return err
+}
+
+// writes whatever is still buffered and closes the encoder, called once the handler returns
+func (cw *compressWriter) finish() {
+	if !cw.wroteHeader {
+		// the handler wrote nothing at all, let net/http send its default response
+		cw.addVary(false)
+		return
+	}
+	if !cw.decided {
+		// too small to be worth compressing
+		cw.flushBuffer(false)
+	}
+	if cw.encoder != nil {
+		cw.encoder.Close()
+		compress.Release(cw.encoder)
+		cw.encoder = nil
+	}
+}
+
+// pushes buffered data to the client, used by handlers that stream
+func (cw *compressWriter) Flush() {
+	if cw.wroteHeader && !cw.decided {
+		cw.flushBuffer(cw.compressible())
+	}
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// lets http.ResponseController reach the writers underneath
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
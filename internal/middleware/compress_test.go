@@ -0,0 +1,167 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+var largeJSON = `{"items":"` + strings.Repeat("reforge ", 512) + `"}`
+
+func jsonHandler(status int, body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	})
+}
+
+// records the status the way the metrics middleware does
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func TestCompress_WhenClientAcceptsGzip_CompressesJSON(t *testing.T) {
+	// Arrange
+	handler := Compress(jsonHandler(http.StatusOK, largeJSON))
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.Equal(t, `"abc-gzip"`, rr.Header().Get("ETag"))
+	reader, err := gzip.NewReader(rr.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	assert.Equal(t, largeJSON, string(body))
+}
+
+func TestCompress_WhenClientPrefersZstd_CompressesWithZstd(t *testing.T) {
+	// Arrange
+	handler := Compress(jsonHandler(http.StatusOK, largeJSON))
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0.5, zstd")
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, "zstd", rr.Header().Get("Content-Encoding"))
+	decoder, err := zstd.NewReader(bytes.NewReader(rr.Body.Bytes()))
+	assert.NoError(t, err)
+	defer decoder.Close()
+	body, _ := io.ReadAll(decoder)
+	assert.Equal(t, largeJSON, string(body))
+}
+
+func TestCompress_WhenNoEncodingAccepted_SendsIdentityWithVary(t *testing.T) {
+	// Arrange
+	handler := Compress(jsonHandler(http.StatusOK, largeJSON))
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0, identity")
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.Equal(t, `"abc"`, rr.Header().Get("ETag"))
+	assert.Equal(t, largeJSON, rr.Body.String())
+}
+
+func TestCompress_WhenResponseIsImage_LeavesItAlone(t *testing.T) {
+	// Arrange
+	png := bytes.Repeat([]byte{0x89}, 4096)
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	}))
+	req := httptest.NewRequest("GET", "/api/item/STONE1", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Empty(t, rr.Header().Get("Vary"))
+	assert.Equal(t, png, rr.Body.Bytes())
+}
+
+func TestCompress_WhenBodyIsSmall_SendsItUncompressed(t *testing.T) {
+	// Arrange
+	handler := Compress(jsonHandler(http.StatusOK, `{"success":true}`))
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.Equal(t, `{"success":true}`, rr.Body.String())
+}
+
+func TestCompress_WhenIfNoneMatchCarriesEncoding_HandlerSeesItsOwnTag(t *testing.T) {
+	// Arrange
+	var seen string
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("If-None-Match")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", `"abc-gzip", "def-zstd"`)
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, `"abc", "def"`, seen)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+}
+
+func TestCompress_WhenWrappedByStatusRecorder_RecorderSeesStatus(t *testing.T) {
+	// Arrange
+	handler := Compress(jsonHandler(http.StatusNotFound, largeJSON))
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	recorder := &statusRecorder{ResponseWriter: rr, status: http.StatusOK}
+
+	// Act
+	handler.ServeHTTP(recorder, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, recorder.status)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+}
@@ -1,99 +1,295 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
-	"yard-backend/internal/config"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	"yard-backend/internal/apikeys"
+	"yard-backend/internal/clientip"
+	"yard-backend/internal/envelope"
+	"yard-backend/internal/models"
+	"yard-backend/internal/upstream"
 )
 
-type rateLimitEntry struct {
-	count     int
-	firstSeen time.Time
+// budget a client has used, it drains at the tier budget per window so the window slides instead of resetting
+type rateLimitBucket struct {
+	level   float64
+	updated time.Time
 }
 
-var (
-	rateLimitMap = make(map[string]*rateLimitEntry)
-	rateLimitMutex sync.RWMutex
-	cleanupTicker *time.Ticker
-	cleanupOnce sync.Once
-)
+// limits the budget each client uses per time window, clients are api keys or else addresses
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*rateLimitBucket
+	// budget per window of each tier
+	limits map[string]int
+	window time.Duration
+	// budget one request uses keyed by route path template, unlisted routes cost 1
+	costs map[string]int
+	// stops asking redis for a while once it fails so requests don't wait on an outage
+	breaker *upstream.Breaker
+	now     func() time.Time
 
-func init() {
-	cleanupOnce.Do(func() {
-		cleanupTicker = time.NewTicker(5 * time.Minute)
-		go func() {
-			for range cleanupTicker.C {
-				cleanupOldEntries()
-			}
-		}()
-	})
+	// resolves the key sent in X-API-Key and records its usage, nil treats every request as anonymous
+	Keys apikeys.Store
+	// shares budgets between replicas, nil or an unreachable redis keeps them in this process
+	Redis *redis.Client
+}
+
+// result of charging a request, enough to fill in the RateLimit headers
+type rateLimitDecision struct {
+	allowed bool
+	limit   int
+	// budget left after this request, or right now when it was rejected
+	remaining int
+	// until the whole budget is available again
+	reset time.Duration
+	// until the rejected request would fit, zero when it was allowed
+	retryAfter time.Duration
 }
 
-// cleans up old rate limit entries to prevent memory leaks
-func cleanupOldEntries() {
-	rateLimitMutex.Lock()
-	defer rateLimitMutex.Unlock()
-	
-	now := time.Now()
-	for key, entry := range rateLimitMap {
-		if now.Sub(entry.firstSeen) > config.APIRateLimitWindow*2 {
-			delete(rateLimitMap, key)
+// creates a rate limiter allowing maxRequests per window to every tier until SetTierLimits says otherwise
+func NewRateLimiter(maxRequests int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*rateLimitBucket),
+		limits: map[string]int{
+			apikeys.TierAnonymous: maxRequests,
+			apikeys.TierFree:      maxRequests,
+			apikeys.TierPartner:   maxRequests,
+		},
+		window:  window,
+		costs:   make(map[string]int),
+		breaker: upstream.NewBreaker(3, 10*time.Second),
+		now:     time.Now,
+	}
+}
+
+// changes the anonymous limit and the window of every tier, budgets already used keep draining at the new rate
+func (rl *RateLimiter) SetLimit(maxRequests int, window time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limits[apikeys.TierAnonymous] = maxRequests
+	rl.window = window
+}
+
+// changes the budget of the given tiers, the others keep theirs
+func (rl *RateLimiter) SetTierLimits(limits map[string]int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for tier, limit := range limits {
+		rl.limits[tier] = limit
+	}
+}
+
+// replaces every route cost
+func (rl *RateLimiter) SetRouteCosts(costs map[string]int) {
+	copied := make(map[string]int, len(costs))
+	for route, cost := range costs {
+		copied[route] = cost
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.costs = copied
+}
+
+// drops buckets that have fully drained to prevent memory leaks
+func (rl *RateLimiter) cleanupOldEntries() {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	for key, bucket := range rl.buckets {
+		if now.Sub(bucket.updated) > rl.window {
+			delete(rl.buckets, key)
 		}
 	}
 }
 
-// gets the client identifier from the request
+// cleans up expired entries every interval until stop is closed
+func (rl *RateLimiter) RunCleanup(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			rl.cleanupOldEntries()
+		}
+	}
+}
+
+// gets the client identifier from the request, the address behind any trusted proxies
 func getClientID(r *http.Request) string {
-	ip := r.RemoteAddr
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		ip = forwarded
+	if addr := clientip.FromRequest(r); addr.IsValid() {
+		return addr.String()
 	}
-	return ip
+	return r.RemoteAddr
 }
 
+// message for keys that are unknown or revoked, the same for both so keys can't be probed
+const invalidAPIKeyMessage = "the api key is unknown or was revoked"
+
 // rate limit middleware that limits requests per client per time window
-func RateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+func (rl *RateLimiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return rl.limit(next, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"rate limit exceeded","message":"too many requests please try again later"}`))
+	}, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid api key","message":"` + invalidAPIKeyMessage + `"}`))
+	})
+}
+
+// same limit as Middleware, rejections are answered with the /api/v2 envelope
+func (rl *RateLimiter) MiddlewareV2(next http.HandlerFunc) http.HandlerFunc {
+	return rl.limit(next, func(w http.ResponseWriter, r *http.Request) {
+		envelope.Error(w, http.StatusTooManyRequests, envelope.CodeRateLimited, "too many requests, please try again later")
+	}, func(w http.ResponseWriter, r *http.Request) {
+		envelope.Error(w, http.StatusUnauthorized, envelope.CodeInvalidAPIKey, invalidAPIKeyMessage)
+	})
+}
+
+func (rl *RateLimiter) limit(next, reject, unauthorized http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		clientID := getClientID(r)
-		now := time.Now()
-		
-		rateLimitMutex.Lock()
-		entry, exists := rateLimitMap[clientID]
-		
-		if !exists {
-			entry = &rateLimitEntry{
-				count:     1,
-				firstSeen: now,
-			}
-			rateLimitMap[clientID] = entry
-			rateLimitMutex.Unlock()
-			next(w, r)
+		key, keyed, err := rl.lookupKey(r)
+		if errors.Is(err, apikeys.ErrNotFound) {
+			unauthorized(w, r)
 			return
 		}
-		
-		elapsed := now.Sub(entry.firstSeen)
-		if elapsed > config.APIRateLimitWindow {
-			entry.count = 1
-			entry.firstSeen = now
-			rateLimitMutex.Unlock()
-			next(w, r)
-			return
+		if err != nil {
+			// a key store outage shouldn't take the api down, the client is limited like anyone without a key
+			log.Printf("Error looking up api key, limiting request as anonymous: %v", err)
 		}
-		
-		if entry.count >= config.APIRateLimitMaxRequests {
-			rateLimitMutex.Unlock()
-			w.Header().Set("Content-Type", "application/json")
-			w.Header().Set("Retry-After", "60")
-			w.WriteHeader(http.StatusTooManyRequests)
-			w.Write([]byte(`{"error":"rate limit exceeded","message":"too many requests please try again later"}`))
+
+		clientID, tier := getClientID(r), apikeys.TierAnonymous
+		if keyed {
+			clientID, tier = "key:"+key.ID, key.Tier
+		}
+		route := routeTemplate(r)
+		now := rl.now()
+		cost, decision := rl.take(r.Context(), clientID, tier, route, now)
+
+		if keyed {
+			if err := rl.Keys.RecordUsage(r.Context(), key.ID, route, cost, !decision.allowed, now); err != nil {
+				log.Printf("Error recording usage of api key %s: %v", key.ID, err)
+			}
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(decision.reset)))
+		if !decision.allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(1, seconds(decision.retryAfter))))
+			reject(w, r)
 			return
 		}
-		
-		entry.count++
-		rateLimitMutex.Unlock()
 		next(w, r)
 	}
 }
 
+// returns the key the request carries, keyed is false for anonymous requests and when the store fails
+func (rl *RateLimiter) lookupKey(r *http.Request) (key models.APIKey, keyed bool, err error) {
+	token := r.Header.Get(apikeys.Header)
+	if token == "" || rl.Keys == nil {
+		return models.APIKey{}, false, nil
+	}
+	key, err = rl.Keys.Lookup(r.Context(), apikeys.Hash(token))
+	if err != nil {
+		return models.APIKey{}, false, err
+	}
+	return key, true, nil
+}
+
+// charges the cost of route to the client in redis when it is reachable and in this process otherwise
+func (rl *RateLimiter) take(ctx context.Context, clientID, tier, route string, now time.Time) (int, rateLimitDecision) {
+	rl.mu.Lock()
+	cost, ok := rl.costs[route]
+	if !ok {
+		cost = 1
+	}
+	limit, ok := rl.limits[tier]
+	if !ok {
+		limit = rl.limits[apikeys.TierAnonymous]
+	}
+	window := rl.window
+	rl.mu.Unlock()
+
+	if rl.Redis != nil && rl.breaker.Allow() == nil {
+		level, allowed, err := rl.takeShared(ctx, clientID, cost, limit, window, now)
+		if err == nil {
+			rl.breaker.Success()
+			return cost, decide(level, allowed, cost, limit, window)
+		}
+		rl.breaker.Failure()
+		log.Printf("Error charging rate limit in redis, limiting in this process: %v", err)
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	bucket, exists := rl.buckets[clientID]
+	if !exists {
+		bucket = &rateLimitBucket{updated: now}
+		rl.buckets[clientID] = bucket
+	}
+	level := drain(bucket.level, now.Sub(bucket.updated), limit, window)
+	allowed := level+float64(cost) <= float64(limit)
+	if allowed {
+		level += float64(cost)
+	}
+	bucket.level = level
+	if now.After(bucket.updated) {
+		bucket.updated = now
+	}
+	return cost, decide(level, allowed, cost, limit, window)
+}
+
+// empties a bucket by limit per window for the time that passed, this is the gcra kept as a level
+// rather than a theoretical arrival time so budget changes apply to clients straight away
+func drain(level float64, elapsed time.Duration, limit int, window time.Duration) float64 {
+	if elapsed <= 0 {
+		return level
+	}
+	return math.Max(0, level-float64(elapsed)*float64(limit)/float64(window))
+}
+
+// turns the level of a bucket after a request into what the client is told
+func decide(level float64, allowed bool, cost, limit int, window time.Duration) rateLimitDecision {
+	perUnit := float64(window) / float64(limit)
+	decision := rateLimitDecision{
+		allowed:   allowed,
+		limit:     limit,
+		remaining: max(0, int(math.Floor(float64(limit)-level+1e-9))),
+		reset:     time.Duration(level * perUnit),
+	}
+	if !allowed {
+		decision.retryAfter = time.Duration((level + float64(cost) - float64(limit)) * perUnit)
+	}
+	return decision
+}
+
+// whole seconds rounded up so clients never retry too early
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// the path template of the matched route, costs and usage are kept per route rather than per url
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// one bucket per client under this prefix
+const redisRateLimitPrefix = "ratelimit:"
+
+// how long a request waits for redis before it is limited in this process instead
+const redisRateLimitTimeout = 100 * time.Millisecond
+
+// drains the bucket for the time since it was last charged and adds the cost when it fits
+// replica clocks may disagree a little so the bucket never drains backwards
+var rateLimitScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local cost = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local window = tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "level", "updated")
+local level = tonumber(state[1]) or 0
+local updated = tonumber(state[2]) or now
+level = math.max(0, level - math.max(0, now - updated) * limit / window)
+local allowed = 0
+if level + cost <= limit then
+	level = level + cost
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "level", tostring(level), "updated", tostring(math.max(now, updated)))
+redis.call("PEXPIRE", KEYS[1], math.ceil(level * window / limit) + 1)
+return {allowed, tostring(level)}
+`)
+
+// charges the bucket every replica shares and returns its level afterwards
+func (rl *RateLimiter) takeShared(ctx context.Context, clientID string, cost, limit int, window time.Duration, now time.Time) (float64, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisRateLimitTimeout)
+	defer cancel()
+
+	// the braces make the client id a hash tag so each bucket stays in a single slot should redis ever be clustered
+	result, err := rateLimitScript.Run(ctx, rl.Redis, []string{redisRateLimitPrefix + "{" + clientID + "}"},
+		now.UnixMilli(), cost, limit, window.Milliseconds()).Slice()
+	if err != nil {
+		return 0, false, err
+	}
+	allowed, _ := result[0].(int64)
+	raw, _ := result[1].(string)
+	level, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return level, allowed == 1, nil
+}
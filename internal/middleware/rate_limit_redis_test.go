@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter_WhenRedisShared_ReplicasDrawFromOneBudget(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+	replicas := make([]http.HandlerFunc, 2)
+	for i := range replicas {
+		limiter := NewRateLimiter(3, time.Minute)
+		limiter.Redis = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		replicas[i] = limiter.Middleware(handler)
+	}
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.RemoteAddr = "192.168.2.1:8080"
+
+	// Act
+	codes := make([]int, 4)
+	var last *httptest.ResponseRecorder
+	for i := range codes {
+		last = httptest.NewRecorder()
+		replicas[i%2].ServeHTTP(last, req)
+		codes[i] = last.Code
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+	assert.Equal(t, "20", last.Header().Get("Retry-After"))
+	assert.True(t, mr.Exists("ratelimit:{192.168.2.1}"))
+}
+
+func TestRateLimiter_WhenRedisUnavailable_LimitsInThisProcess(t *testing.T) {
+	// Arrange
+	mr := miniredis.RunT(t)
+	addr := mr.Addr()
+	mr.Close()
+	limiter := NewRateLimiter(1, time.Minute)
+	limiter.Redis = redis.NewClient(&redis.Options{Addr: addr, MaxRetries: -1})
+	middleware := limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.RemoteAddr = "192.168.2.2:8080"
+
+	// Act
+	first := httptest.NewRecorder()
+	middleware.ServeHTTP(first, req)
+	second := httptest.NewRecorder()
+	middleware.ServeHTTP(second, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, http.StatusTooManyRequests, second.Code)
+}
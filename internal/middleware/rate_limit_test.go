@@ -1,29 +1,26 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
-	"yard-backend/internal/config"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/apikeys"
+	"yard-backend/internal/clientip"
 )
 
 func TestRateLimitMiddleware_WhenWithinLimit_AllowsRequest(t *testing.T) {
 	// Arrange
-	originalMaxRequests := config.APIRateLimitMaxRequests
-	config.APIRateLimitMaxRequests = 3
-	defer func() {
-		config.APIRateLimitMaxRequests = originalMaxRequests
-		rateLimitMutex.Lock()
-		rateLimitMap = make(map[string]*rateLimitEntry)
-		rateLimitMutex.Unlock()
-	}()
-
+	limiter := NewRateLimiter(3, time.Minute)
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
-	middleware := RateLimitMiddleware(handler)
+	middleware := limiter.Middleware(handler)
 	req := httptest.NewRequest("GET", "/", nil)
 	req.RemoteAddr = "192.168.1.1:8080"
 
@@ -37,19 +34,11 @@ func TestRateLimitMiddleware_WhenWithinLimit_AllowsRequest(t *testing.T) {
 
 func TestRateLimitMiddleware_WhenExceedsLimit_ReturnsTooManyRequests(t *testing.T) {
 	// Arrange
-	originalMaxRequests := config.APIRateLimitMaxRequests
-	config.APIRateLimitMaxRequests = 2
-	defer func() {
-		config.APIRateLimitMaxRequests = originalMaxRequests
-		rateLimitMutex.Lock()
-		rateLimitMap = make(map[string]*rateLimitEntry)
-		rateLimitMutex.Unlock()
-	}()
-
+	limiter := NewRateLimiter(2, time.Minute)
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
-	middleware := RateLimitMiddleware(handler)
+	middleware := limiter.Middleware(handler)
 	req := httptest.NewRequest("GET", "/", nil)
 	req.RemoteAddr = "192.168.1.2:8080"
 
@@ -66,3 +55,207 @@ func TestRateLimitMiddleware_WhenExceedsLimit_ReturnsTooManyRequests(t *testing.
 	// Assert
 	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
 }
+
+func TestRateLimiterSetLimit_WhenLimitRaised_AllowsMoreRequests(t *testing.T) {
+	// Arrange
+	limiter := NewRateLimiter(1, time.Minute)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	middleware := limiter.Middleware(handler)
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "192.168.1.3:8080"
+	middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Act
+	limiter.SetLimit(5, time.Minute)
+	rr := httptest.NewRecorder()
+	middleware.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRateLimitMiddlewareV2_WhenExceedsLimit_AnswersWithEnvelope(t *testing.T) {
+	// Arrange
+	limiter := NewRateLimiter(1, time.Minute)
+	handler := RequestID(limiter.MiddlewareV2(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest("GET", "/api/v2/reforges", nil)
+	req.RemoteAddr = "192.168.1.3:8080"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), `"code":"rate_limited"`)
+	assert.Contains(t, rr.Body.String(), `"requestId":"`+rr.Header().Get("X-Request-ID")+`"`)
+}
+
+func TestRateLimitMiddleware_WhenRouteCostsMore_UsesBudgetFaster(t *testing.T) {
+	// Arrange
+	limiter := NewRateLimiter(5, time.Minute)
+	limiter.SetRouteCosts(map[string]int{"/api/item/{itemId}": 3})
+	router := mux.NewRouter()
+	router.HandleFunc("/api/item/{itemId}", limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest("GET", "/api/item/AMBER", nil)
+	req.RemoteAddr = "192.168.1.4:8080"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Act
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
+
+func TestRateLimitMiddleware_WhenAPIKeySent_UsesTierBudgetAndRecordsUsage(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	keys := apikeys.NewMemory()
+	key, token, err := apikeys.Issue(ctx, keys, "partner site", apikeys.TierPartner, time.Now())
+	require.NoError(t, err)
+	limiter := NewRateLimiter(1, time.Minute)
+	limiter.SetTierLimits(map[string]int{apikeys.TierPartner: 3})
+	limiter.Keys = keys
+	middleware := limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Act
+	codes := make([]int, 4)
+	for i := range codes {
+		req := httptest.NewRequest("GET", "/api/reforges", nil)
+		req.RemoteAddr = "192.168.1.5:8080"
+		req.Header.Set(apikeys.Header, token)
+		rr := httptest.NewRecorder()
+		middleware.ServeHTTP(rr, req)
+		codes[i] = rr.Code
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+	usage, err := keys.Usage(ctx, key.ID, time.Now(), time.Now())
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, int64(3), usage[0].Requests)
+	assert.Equal(t, int64(1), usage[0].Rejected)
+	assert.Equal(t, int64(3), usage[0].Routes["/api/reforges"].Units)
+}
+
+func TestRateLimitMiddlewareV2_WhenAPIKeyRevoked_ReturnsUnauthorized(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	keys := apikeys.NewMemory()
+	key, token, err := apikeys.Issue(ctx, keys, "old client", apikeys.TierFree, time.Now())
+	require.NoError(t, err)
+	_, err = keys.Revoke(ctx, key.ID, time.Now())
+	require.NoError(t, err)
+	limiter := NewRateLimiter(10, time.Minute)
+	limiter.Keys = keys
+	handler := limiter.MiddlewareV2(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest("GET", "/api/v2/reforges", nil)
+	req.Header.Set(apikeys.Header, token)
+
+	// Act
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"code":"invalid_api_key"`)
+}
+
+func TestRateLimitMiddleware_WhenBudgetUsed_ReportsRateLimitHeadersAndRetryAfter(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(4, time.Minute)
+	limiter.now = func() time.Time { return now }
+	middleware := limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.RemoteAddr = "192.168.1.6:8080"
+
+	// Act
+	first := httptest.NewRecorder()
+	middleware.ServeHTTP(first, req)
+	for i := 0; i < 3; i++ {
+		middleware.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	now = now.Add(5 * time.Second)
+	rejected := httptest.NewRecorder()
+	middleware.ServeHTTP(rejected, req)
+
+	// Assert
+	assert.Equal(t, "4", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "3", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "15", first.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, "0", rejected.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "55", rejected.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "10", rejected.Header().Get("Retry-After"))
+}
+
+func TestRateLimitMiddleware_WhenPartOfWindowPassed_FreesThatShareOfTheBudget(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(4, time.Minute)
+	limiter.now = func() time.Time { return now }
+	middleware := limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest("GET", "/api/reforges", nil)
+	req.RemoteAddr = "192.168.1.7:8080"
+	for i := 0; i < 4; i++ {
+		middleware.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Act
+	now = now.Add(30 * time.Second)
+	codes := make([]int, 3)
+	for i := range codes {
+		rr := httptest.NewRecorder()
+		middleware.ServeHTTP(rr, req)
+		codes[i] = rr.Code
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestRateLimitMiddleware_WhenForwardedForSpoofed_StillLimitsTheClient(t *testing.T) {
+	// Arrange
+	proxies, err := clientip.ParsePrefixes([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	clientip.SetTrustedProxies(proxies)
+	t.Cleanup(func() { clientip.SetTrustedProxies(nil) })
+	limiter := NewRateLimiter(1, time.Minute)
+	middleware := limiter.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Act
+	codes := make([]int, 3)
+	for i, spoofed := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		req := httptest.NewRequest("GET", "/api/reforges", nil)
+		req.RemoteAddr = "10.0.0.2:443"
+		req.Header.Set("X-Forwarded-For", spoofed+", 198.51.100.9")
+		rr := httptest.NewRecorder()
+		middleware.ServeHTTP(rr, req)
+		codes[i] = rr.Code
+	}
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}, codes)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID_WhenProxySentAnID_KeepsIt(t *testing.T) {
+	// Arrange
+	var seen string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("X-Request-ID")
+	}))
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "edge-42.a_b")
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, "edge-42.a_b", rr.Header().Get("X-Request-ID"))
+	assert.Equal(t, "edge-42.a_b", seen)
+}
+
+func TestRequestID_WhenIDMissingOrUnsafe_GeneratesOne(t *testing.T) {
+	// Arrange
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	missing := httptest.NewRequest("GET", "/", nil)
+	unsafe := httptest.NewRequest("GET", "/", nil)
+	unsafe.Header.Set("X-Request-ID", "id\nAUDIT admin outcome=granted")
+
+	for _, req := range []*http.Request{missing, unsafe} {
+		rr := httptest.NewRecorder()
+
+		// Act
+		handler.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Regexp(t, `^[0-9a-f]{32}$`, rr.Header().Get("X-Request-ID"))
+	}
+}
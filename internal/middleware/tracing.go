@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"yard-backend/internal/envelope"
+)
+
+const httpTracerName = "yard-backend/http"
+
+// records the status code written by a handler for the request span
+type tracingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *tracingResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *tracingResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// lets http.ResponseController reach the writers underneath
+func (w *tracingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// starts a server span for every request, continuing the trace named in its traceparent header
+// spans are named after the route template so item ids don't create a span name per item
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		name := r.Method
+		attrs := []attribute.KeyValue{
+			semconv.HTTPRequestMethodKey.String(r.Method),
+			semconv.URLPath(r.URL.Path),
+			attribute.String("http.request_id", r.Header.Get(envelope.RequestIDHeader)),
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				name += " " + template
+				attrs = append(attrs, semconv.HTTPRoute(template))
+			}
+		}
+
+		ctx, span := otel.Tracer(httpTracerName).Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		tw := &tracingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(tw.statusCode))
+		if tw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(tw.statusCode))
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"yard-backend/internal/openapi"
+)
+
+func newValidatedRouter() *mux.Router {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := mux.NewRouter()
+	r.Use(ValidateParams(openapi.Spec()))
+	r.HandleFunc("/api/changes", ok).Methods("GET")
+	r.HandleFunc("/api/item/{itemId}", ok).Methods("GET", "OPTIONS")
+	r.HandleFunc("/undocumented/{id}", ok).Methods("GET")
+	return r
+}
+
+func TestValidateParams_WhenParameterIsInvalid_RejectsWithJSONError(t *testing.T) {
+	// Arrange
+	router := newValidatedRouter()
+	req := httptest.NewRequest("GET", "/api/changes?since=last-week", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(rr, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `"error":"invalid parameter"`)
+	assert.Contains(t, rr.Body.String(), "invalid query parameter since")
+}
+
+func TestValidateParams_WhenValidOrUndocumented_PassesThrough(t *testing.T) {
+	// Arrange
+	router := newValidatedRouter()
+	requests := []*http.Request{
+		httptest.NewRequest("GET", "/api/changes?since=1767225600", nil),
+		httptest.NewRequest("GET", "/api/item/DRAGON_CLAW", nil),
+		httptest.NewRequest("OPTIONS", "/api/item/%3Cscript%3E", nil),
+		httptest.NewRequest("GET", "/undocumented/%3Cscript%3E", nil),
+	}
+
+	for _, req := range requests {
+		rr := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(rr, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, rr.Code, req.URL.String())
+	}
+}
@@ -5,9 +5,16 @@ import "time"
 type HealthResponse struct {
 	Status  string    `json:"status"`
 	Message string    `json:"message"`
+	Ready   bool      `json:"ready"`
+	Leader  bool      `json:"leader"`
 	Time    time.Time `json:"time"`
 }
 
+// readyresponse reports whether the instance has finished warming up
+type ReadyResponse struct {
+	Ready bool `json:"ready"`
+}
+
 type HypixelAPIResponse struct {
 	Success     bool   `json:"success"`
 	LastUpdated int64  `json:"lastUpdated"`
@@ -75,6 +82,8 @@ type Item struct {
 	BazaarBuyOrders []BazaarOrder          `json:"bazaar_buy_orders,omitempty"`
 	BazaarSellOrders []BazaarOrder         `json:"bazaar_sell_orders,omitempty"`
 	ReforgeEffect   *ReforgeEffect         `json:"reforge_effect,omitempty"`
+	// set once hypixel stops listing the stone, it is dropped after the storage.delisted_grace period
+	DelistedAt      *time.Time             `json:"delisted_at,omitempty"`
 }
 
 type ReforgeStonesResponse struct {
@@ -107,3 +116,217 @@ type ReforgesResponse struct {
 	Reforges    []Reforge `json:"reforges"`
 }
 
+
+// jobstatus reports the progress of a background job started through the admin api
+type JobStatus struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Target     string     `json:"target,omitempty"`
+	State      string     `json:"state"`
+	Processed  int        `json:"processed"`
+	Total      int        `json:"total"`
+	Errors     []string   `json:"errors,omitempty"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	ETASeconds *float64   `json:"etaSeconds,omitempty"`
+}
+
+// jobresponse wraps a single job status
+type JobResponse struct {
+	Success bool      `json:"success"`
+	Job     JobStatus `json:"job"`
+}
+
+// jobsresponse lists recent jobs newest first
+type JobsResponse struct {
+	Success bool        `json:"success"`
+	Count   int         `json:"count"`
+	Jobs    []JobStatus `json:"jobs"`
+}
+
+// configreloadresponse reports which settings a config reload applied and which need a restart
+type ConfigReloadResponse struct {
+	Success         bool     `json:"success"`
+	Applied         []string `json:"applied"`
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// snapshot describes one published version of the stone catalog
+type Snapshot struct {
+	ID          int64     `json:"id"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Stones      int       `json:"stones"`
+	Current     bool      `json:"current"`
+}
+
+// snapshotsresponse lists the kept snapshots newest first
+type SnapshotsResponse struct {
+	Success   bool       `json:"success"`
+	Count     int        `json:"count"`
+	Snapshots []Snapshot `json:"snapshots"`
+}
+
+// changeset is the diff of the stone catalog produced by one hypixel fetch
+type ChangeSet struct {
+	At       time.Time    `json:"at"`
+	Added    []ItemRef    `json:"added"`
+	Removed  []ItemRef    `json:"removed"`
+	Modified []ItemChange `json:"modified"`
+}
+
+// itemref names an item in a changeset
+type ItemRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Tier string `json:"tier"`
+}
+
+// itemchange lists the fields of one item that hypixel changed
+type ItemChange struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// fieldchange holds the old and new value of a single item field
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// changesresponse lists catalog changes newest first
+type ChangesResponse struct {
+	Success bool        `json:"success"`
+	Count   int         `json:"count"`
+	Changes []ChangeSet `json:"changes"`
+}
+
+// balancechange is one value neu changed for a reforge, rarity is empty for changes that apply to every rarity
+type BalanceChange struct {
+	Rarity string      `json:"rarity,omitempty"`
+	Stat   string      `json:"stat"`
+	Old    interface{} `json:"old"`
+	New    interface{} `json:"new"`
+}
+
+// reforgehistoryentry lists what one neu repository version changed about a reforge
+type ReforgeHistoryEntry struct {
+	Version string          `json:"version"`
+	Date    time.Time       `json:"date"`
+	Status  string          `json:"status"`
+	Changes []BalanceChange `json:"changes"`
+}
+
+// reforgehistoryresponse is the balance history of a single reforge, newest first
+type ReforgeHistoryResponse struct {
+	Success bool                  `json:"success"`
+	Reforge string                `json:"reforge"`
+	Count   int                   `json:"count"`
+	History []ReforgeHistoryEntry `json:"history"`
+}
+
+// pricepoint is the price of a stone after a refresh that changed it
+type PricePoint struct {
+	At              time.Time `json:"at"`
+	AuctionPrice    *int64    `json:"auction_price,omitempty"`
+	BazaarBuyPrice  *float64  `json:"bazaar_buy_price,omitempty"`
+	BazaarSellPrice *float64  `json:"bazaar_sell_price,omitempty"`
+}
+
+// statinfo describes one reforge stat, key is its name in reforge_stats
+type StatInfo struct {
+	Key     string `json:"key"`
+	Name    string `json:"name"`
+	Percent bool   `json:"percent"`
+}
+
+// errorresponse is the json error body written by the middleware and the admin api
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// envelope wraps every /api/v2 response, data is null when error is set and the other way round
+type Envelope struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data"`
+	Error   *APIError   `json:"error"`
+	Meta    Meta        `json:"meta"`
+}
+
+// apierror is the error of a /api/v2 response, code is stable and meant for programs
+type APIError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// meta describes the data in a /api/v2 response, version is set on every success and changes whenever the data behind it does
+type Meta struct {
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+	Version     string     `json:"version,omitempty"`
+}
+
+// reforgehistory is the data of /api/v2/reforges/{name}/history
+type ReforgeHistory struct {
+	Reforge string                `json:"reforge"`
+	History []ReforgeHistoryEntry `json:"history"`
+}
+
+// apikey describes an issued api key, the key itself is only shown once when it is created
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Tier      string     `json:"tier"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// apikeyrequest is the body of POST /admin/api-keys
+type APIKeyRequest struct {
+	Name string `json:"name"`
+	Tier string `json:"tier"`
+}
+
+// apikeycreatedresponse carries a new key, token is what clients send in X-API-Key
+type APIKeyCreatedResponse struct {
+	Success bool   `json:"success"`
+	Key     APIKey `json:"key"`
+	Token   string `json:"token"`
+}
+
+// apikeyresponse carries a single key
+type APIKeyResponse struct {
+	Success bool   `json:"success"`
+	Key     APIKey `json:"key"`
+}
+
+// apikeysresponse lists every issued key, revoked ones included
+type APIKeysResponse struct {
+	Success bool     `json:"success"`
+	Count   int      `json:"count"`
+	Keys    []APIKey `json:"keys"`
+}
+
+// apikeyusage is what one key used on one utc day, units are requests weighted by route cost
+type APIKeyUsage struct {
+	Date     string                `json:"date"`
+	Requests int64                 `json:"requests"`
+	Units    int64                 `json:"units"`
+	Rejected int64                 `json:"rejected"`
+	Routes   map[string]RouteUsage `json:"routes"`
+}
+
+// routeusage is what one key used on one route
+type RouteUsage struct {
+	Requests int64 `json:"requests"`
+	Units    int64 `json:"units"`
+}
+
+// apikeyusageresponse reports the usage of a key newest day first, days without requests are left out
+type APIKeyUsageResponse struct {
+	Success bool          `json:"success"`
+	Key     APIKey        `json:"key"`
+	Days    []APIKeyUsage `json:"days"`
+}
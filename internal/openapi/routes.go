@@ -0,0 +1,521 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+
+	"yard-backend/internal/apikeys"
+	"yard-backend/internal/gql"
+	"yard-backend/internal/models"
+)
+
+const (
+	// item ids as hypixel hands them out, images also accept names with spaces
+	itemIDPattern = `^[A-Za-z0-9_:;. -]+$`
+	// since and until take RFC 3339 times or unix seconds
+	timeParamPattern = `^(-?[0-9]+|[0-9]{4}-[0-9]{2}-[0-9]{2}T[^ ]+)$`
+)
+
+// describes every route main registers, main_test checks the two stay in step
+func build() *Document {
+	b := &builder{
+		schemas: newSchemas(map[reflect.Type]string{
+			reflect.TypeOf(gql.Request{}):              "GraphQLRequest",
+			reflect.TypeOf(gql.Extensions{}):           "GraphQLRequestExtensions",
+			reflect.TypeOf(graphql.Result{}):           "GraphQLResult",
+			reflect.TypeOf(gqlerrors.FormattedError{}): "GraphQLError",
+			reflect.TypeOf(location.SourceLocation{}):  "GraphQLLocation",
+		}),
+		paths: make(map[string]PathItem),
+	}
+
+	b.add(http.MethodGet, "/health", &Operation{
+		OperationID: "getHealth",
+		Summary:     "Report that the server is running",
+		Tags:        []string{"status"},
+		Responses: map[string]*Response{
+			"200": b.json("Server status", models.HealthResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/ready", &Operation{
+		OperationID: "getReady",
+		Summary:     "Report whether warm-up has finished",
+		Tags:        []string{"status"},
+		Responses: map[string]*Response{
+			"200": b.json("Ready to serve traffic", models.ReadyResponse{}),
+			"503": b.json("Still warming up or shutting down", models.ReadyResponse{}),
+		},
+	})
+
+	b.add(http.MethodGet, "/api/reforge-stones", b.public(&Operation{
+		OperationID: "listReforgeStones",
+		Summary:     "List every reforge stone with prices",
+		Description: "Answers conditional requests with 304 using ETag and Last-Modified.",
+		Tags:        []string{"reforges"},
+		Responses: map[string]*Response{
+			"200": b.json("Every cached reforge stone", models.ReforgeStonesResponse{}),
+			"304": {Description: "The client copy is current"},
+			"500": text("Storage is not available"),
+		},
+	}))
+	b.add(http.MethodGet, "/api/reforges", b.public(&Operation{
+		OperationID: "listReforges",
+		Summary:     "List every reforge with its stats and stone",
+		Description: "Answers conditional requests with 304 using ETag and Last-Modified.",
+		Tags:        []string{"reforges"},
+		Responses: map[string]*Response{
+			"200": b.json("Every reforge sorted by name", models.ReforgesResponse{}),
+			"304": {Description: "The client copy is current"},
+		},
+	}))
+	b.add(http.MethodGet, "/api/reforges/{name}/history", b.public(&Operation{
+		OperationID: "getReforgeHistory",
+		Summary:     "List the NEU balance changes of one reforge",
+		Tags:        []string{"reforges"},
+		Parameters: []Parameter{
+			pathParam("name", "Reforge name, matched case-insensitively", (&Schema{Type: "string"}).length(1, 64)),
+		},
+		Responses: map[string]*Response{
+			"200": b.json("Balance history newest first", models.ReforgeHistoryResponse{}),
+			"404": text("Neither NEU nor the recorded history knows a reforge with that name"),
+			"500": text("Storage is not available"),
+		},
+	}))
+
+	timeRange := []Parameter{
+		queryParam("since", "Only changes at or after this RFC 3339 time or unix second", timeParam()),
+		queryParam("until", "Only changes before this RFC 3339 time or unix second", timeParam()),
+	}
+	b.add(http.MethodGet, "/api/changes", b.public(&Operation{
+		OperationID: "listChanges",
+		Summary:     "List catalog changes newest first",
+		Tags:        []string{"changes"},
+		Parameters:  timeRange,
+		Responses: map[string]*Response{
+			"200": b.json("Catalog changes", models.ChangesResponse{}),
+			"400": text("since or until is not a valid time"),
+			"500": text("Storage is not available"),
+		},
+	}))
+	b.add(http.MethodGet, "/api/changes.atom", b.public(&Operation{
+		OperationID: "getChangesFeed",
+		Summary:     "Catalog changes as an Atom feed",
+		Tags:        []string{"changes"},
+		Parameters:  timeRange,
+		Responses: map[string]*Response{
+			"200": {Description: "The latest changes", Content: map[string]MediaType{
+				"application/atom+xml": {Schema: &Schema{Type: "string"}},
+			}},
+			"400": text("since or until is not a valid time"),
+			"500": text("Storage is not available"),
+		},
+	}))
+
+	graphQLErrors := &Schema{
+		Type:     "object",
+		Required: []string{"errors"},
+		Properties: map[string]*Schema{
+			"errors": {Type: "array", Items: &Schema{
+				Type:       "object",
+				Required:   []string{"message"},
+				Properties: map[string]*Schema{"message": {Type: "string"}},
+			}},
+		},
+	}
+	graphQLResponses := func() map[string]*Response {
+		return map[string]*Response{
+			"200": b.json("Query result, errors are reported inside it", graphql.Result{}),
+			"400": {Description: "The request could not be decoded", Content: map[string]MediaType{
+				"application/json": {Schema: graphQLErrors},
+			}},
+			"501": text("GraphQL is not enabled"),
+		}
+	}
+	b.add(http.MethodGet, "/graphql", b.public(&Operation{
+		OperationID: "queryGraphQL",
+		Summary:     "Run a GraphQL query sent as parameters",
+		Description: "Lets CDNs cache persisted queries. See the GraphQL section of the README for limits.",
+		Tags:        []string{"graphql"},
+		Parameters: []Parameter{
+			queryParam("query", "GraphQL document", &Schema{Type: "string"}),
+			queryParam("operationName", "Operation to run when the document holds several", &Schema{Type: "string"}),
+			queryParam("variables", "JSON encoded variables", &Schema{Type: "string"}),
+			queryParam("extensions", "JSON encoded extensions, used for persisted queries", &Schema{Type: "string"}),
+		},
+		Responses: graphQLResponses(),
+	}))
+	b.add(http.MethodPost, "/graphql", b.public(&Operation{
+		OperationID: "postGraphQL",
+		Summary:     "Run a GraphQL query sent as JSON",
+		Tags:        []string{"graphql"},
+		RequestBody: &RequestBody{Required: true, Content: map[string]MediaType{
+			"application/json": {Schema: b.schemas.of(reflect.TypeOf(gql.Request{}))},
+		}},
+		Responses: graphQLResponses(),
+	}))
+
+	itemID := (&Schema{Type: "string"}).length(1, 100).matching(itemIDPattern)
+	b.add(http.MethodGet, "/api/item/{itemId}", b.public(&Operation{
+		OperationID: "getItemImage",
+		Summary:     "Render an item texture from the resource pack",
+		Tags:        []string{"items"},
+		Parameters:  []Parameter{pathParam("itemId", "Item id, spaces are read as underscores", itemID)},
+		Responses: func() map[string]*Response {
+			responses := imageResponses()
+			responses["500"] = text("The texture could not be upscaled")
+			return responses
+		}(),
+	}))
+	b.add(http.MethodGet, "/api/item-data/{itemId}", b.public(&Operation{
+		OperationID: "getItemImageByData",
+		Summary:     "Render a cached stone from the resource pack or its skull skin",
+		Tags:        []string{"items"},
+		Parameters:  []Parameter{pathParam("itemId", "Id of a cached reforge stone", itemID)},
+		Responses: func() map[string]*Response {
+			responses := imageResponses()
+			responses["500"] = text("Storage is not available")
+			return responses
+		}(),
+	}))
+
+	b.add(http.MethodGet, "/api/v2/reforge-stones", b.v2(&Operation{
+		OperationID: "listReforgeStonesV2",
+		Summary:     "List every reforge stone with prices",
+		Description: "Answers conditional requests with 304 using ETag and Last-Modified.",
+		Tags:        []string{"v2"},
+		Responses: map[string]*Response{
+			"200": b.envelope("Every cached reforge stone", []models.Item{}),
+			"304": {Description: "The client copy is current"},
+			"503": b.envelopeError("Storage is not available"),
+		},
+	}))
+	b.add(http.MethodGet, "/api/v2/reforges", b.v2(&Operation{
+		OperationID: "listReforgesV2",
+		Summary:     "List every reforge with its stats and stone",
+		Description: "Answers conditional requests with 304 using ETag and Last-Modified.",
+		Tags:        []string{"v2"},
+		Responses: map[string]*Response{
+			"200": b.envelope("Every reforge sorted by name", []models.Reforge{}),
+			"304": {Description: "The client copy is current"},
+		},
+	}))
+	b.add(http.MethodGet, "/api/v2/reforges/{name}/history", b.v2(&Operation{
+		OperationID: "getReforgeHistoryV2",
+		Summary:     "List the NEU balance changes of one reforge",
+		Tags:        []string{"v2"},
+		Parameters: []Parameter{
+			pathParam("name", "Reforge name, matched case-insensitively", (&Schema{Type: "string"}).length(1, 64)),
+		},
+		Responses: map[string]*Response{
+			"200": b.envelope("Balance history newest first", models.ReforgeHistory{}),
+			"404": b.envelopeError("reforge_not_found: neither NEU nor the recorded history knows a reforge with that name"),
+			"503": b.envelopeError("Storage is not available"),
+		},
+	}))
+	b.add(http.MethodGet, "/api/v2/changes", b.v2(&Operation{
+		OperationID: "listChangesV2",
+		Summary:     "List catalog changes newest first",
+		Tags:        []string{"v2"},
+		Parameters:  timeRange,
+		Responses: map[string]*Response{
+			"200": b.envelope("Catalog changes", []models.ChangeSet{}),
+			"400": b.envelopeError("invalid_parameter: since or until is not a valid time"),
+			"503": b.envelopeError("Storage is not available"),
+		},
+	}))
+	b.add(http.MethodGet, "/api/v2/item/{itemId}", b.v2(&Operation{
+		OperationID: "getItemImageV2",
+		Summary:     "Render an item texture from the resource pack",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{pathParam("itemId", "Item id, spaces are read as underscores", itemID)},
+		Responses: map[string]*Response{
+			"200": imageResponses()["200"],
+			"404": b.envelopeError("texture_not_found: no texture for that item"),
+			"500": b.envelopeError("The texture could not be upscaled"),
+		},
+	}))
+	b.add(http.MethodGet, "/api/v2/item-data/{itemId}", b.v2(&Operation{
+		OperationID: "getItemImageByDataV2",
+		Summary:     "Render a cached stone from the resource pack or its skull skin",
+		Tags:        []string{"v2"},
+		Parameters:  []Parameter{pathParam("itemId", "Id of a cached reforge stone", itemID)},
+		Responses: map[string]*Response{
+			"200": imageResponses()["200"],
+			"404": b.envelopeError("item_not_found or texture_not_found"),
+			"503": b.envelopeError("Storage is not available"),
+		},
+	}))
+
+	b.add(http.MethodGet, "/openapi.json", &Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "This document",
+		Tags:        []string{"docs"},
+		Responses: map[string]*Response{
+			"200": {Description: "OpenAPI 3 document", Content: map[string]MediaType{
+				"application/json": {Schema: &Schema{Type: "object"}},
+			}},
+		},
+	})
+	b.add(http.MethodGet, "/docs", &Operation{
+		OperationID: "getDocs",
+		Summary:     "Interactive documentation for this document",
+		Tags:        []string{"docs"},
+		Responses: map[string]*Response{
+			"200": {Description: "Swagger UI page", Content: map[string]MediaType{
+				"text/html": {Schema: &Schema{Type: "string"}},
+			}},
+		},
+	})
+	b.add(http.MethodGet, "/metrics", &Operation{
+		OperationID: "getMetrics",
+		Summary:     "Prometheus metrics",
+		Description: "Only registered when metrics are enabled and limited to the METRICS_IP_WHITELIST acl.",
+		Tags:        []string{"status"},
+		Responses: map[string]*Response{
+			"200": text("Metrics in the Prometheus text format"),
+			"403": b.json("The client address is not allowed by the acl", models.ErrorResponse{}),
+		},
+	})
+
+	b.addAdmin(http.MethodPost, "/admin/refresh/hypixel", "refreshHypixel", "Fetch Hypixel items and refresh every price", b.job("409"))
+	b.addAdmin(http.MethodPost, "/admin/refresh/prices", "refreshPrices", "Refresh the prices of every stone", b.job("409"))
+	b.addAdmin(http.MethodPost, "/admin/refresh/prices/{stoneId}", "refreshStonePrices", "Refresh the prices of one stone", b.job("404", "409", "503"),
+		pathParam("stoneId", "Id of a cached reforge stone", itemID))
+	b.addAdmin(http.MethodPost, "/admin/reload/neu", "reloadNEU", "Reload the NEU reforge files on this instance", b.job())
+	b.addAdmin(http.MethodPost, "/admin/reload/config", "reloadConfig", "Re-read the config file on this instance", map[string]*Response{
+		"200": b.json("Which settings were applied", models.ConfigReloadResponse{}),
+		"400": b.json("The config file is invalid", models.ErrorResponse{}),
+		"501": b.json("Config reload is not enabled", models.ErrorResponse{}),
+	})
+	b.addAdmin(http.MethodPost, "/admin/reload/resource-pack", "reloadResourcePack", "Rescan the resource pack on this instance", b.job())
+	b.addAdmin(http.MethodPost, "/admin/cache/purge", "purgeCache", "Drop every cached stone", b.job("409"))
+	b.addAdmin(http.MethodGet, "/admin/snapshots", "listSnapshots", "List the kept catalog snapshots", map[string]*Response{
+		"200": b.json("Kept snapshots newest first", models.SnapshotsResponse{}),
+		"503": b.json("Storage is not available", models.ErrorResponse{}),
+	})
+	b.addAdmin(http.MethodPost, "/admin/snapshots/{version}/rollback", "rollback", "Serve an earlier snapshot again", map[string]*Response{
+		"200": b.json("Kept snapshots after the rollback", models.SnapshotsResponse{}),
+		"404": b.json("The snapshot is not kept anymore", models.ErrorResponse{}),
+		"409": b.json("Another instance is the scheduler leader", models.ErrorResponse{}),
+		"503": b.json("Storage is not available", models.ErrorResponse{}),
+	}, pathParam("version", "Snapshot id", (&Schema{Type: "integer", Format: "int64"}).atLeast(1)))
+	b.addAdmin(http.MethodGet, "/admin/jobs", "listJobs", "List recent admin jobs", map[string]*Response{
+		"200": b.json("Recent jobs newest first", models.JobsResponse{}),
+	})
+	b.addAdmin(http.MethodGet, "/admin/jobs/{jobId}", "getJob", "Poll one admin job", map[string]*Response{
+		"200": b.json("The job", models.JobResponse{}),
+		"404": b.json("No job with that id", models.ErrorResponse{}),
+	}, pathParam("jobId", "Id returned when the job was started", (&Schema{Type: "string"}).length(1, 64)))
+
+	keyID := pathParam("keyId", "Id of an issued api key", (&Schema{Type: "string"}).length(1, 64))
+	b.addAdmin(http.MethodPost, "/admin/api-keys", "createAPIKey", "Issue an api key", map[string]*Response{
+		"201": b.json("The new key and its token, the token is only ever returned here", models.APIKeyCreatedResponse{}),
+		"400": b.json("The name or tier is invalid", models.ErrorResponse{}),
+		"503": b.json("Storage is not available", models.ErrorResponse{}),
+	})
+	b.paths["/admin/api-keys"]["post"].RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+		"application/json": {Schema: b.schemas.of(reflect.TypeOf(models.APIKeyRequest{}))},
+	}}
+	b.addAdmin(http.MethodGet, "/admin/api-keys", "listAPIKeys", "List every issued api key", map[string]*Response{
+		"200": b.json("Every key oldest first, revoked ones included", models.APIKeysResponse{}),
+		"503": b.json("Storage is not available", models.ErrorResponse{}),
+	})
+	b.addAdmin(http.MethodPost, "/admin/api-keys/{keyId}/revoke", "revokeAPIKey", "Revoke an api key", map[string]*Response{
+		"200": b.json("The revoked key", models.APIKeyResponse{}),
+		"404": b.json("No key with that id", models.ErrorResponse{}),
+		"503": b.json("Storage is not available", models.ErrorResponse{}),
+	}, keyID)
+	b.addAdmin(http.MethodGet, "/admin/api-keys/{keyId}/usage", "getAPIKeyUsage", "Report what an api key used per day", map[string]*Response{
+		"200": b.json("Usage newest day first, days without requests are left out", models.APIKeyUsageResponse{}),
+		"404": b.json("No key with that id", models.ErrorResponse{}),
+		"503": b.json("Storage is not available", models.ErrorResponse{}),
+	}, keyID, queryParam("days", "How many days back to report, 30 by default and at most 90", (&Schema{Type: "integer"}).atLeast(1)))
+
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "YARD Backend",
+			Description: "Reforge stones, reforges and market prices for Hypixel SkyBlock.",
+			Version:     "1.0.0",
+		},
+		Paths: b.paths,
+		Components: Components{
+			Schemas: b.schemas.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				"adminToken": {Type: "http", Scheme: "bearer"},
+				"apiKey":     {Type: "apiKey", In: "header", Name: apikeys.Header},
+			},
+		},
+		Tags: []Tag{
+			{Name: "status", Description: "Health, readiness and metrics"},
+			{Name: "reforges", Description: "Reforges and reforge stones"},
+			{Name: "changes", Description: "What changed in the stone catalog"},
+			{Name: "items", Description: "Item images"},
+			{Name: "v2", Description: "Every response, errors included, wrapped in the same envelope"},
+			{Name: "graphql", Description: "GraphQL access to the same data"},
+			{Name: "docs", Description: "This documentation"},
+			{Name: "admin", Description: "Only registered when ADMIN_TOKEN is set"},
+		},
+	}
+}
+
+type builder struct {
+	schemas *schemas
+	paths   map[string]PathItem
+}
+
+func (b *builder) add(method, path string, op *Operation) {
+	if b.paths[path] == nil {
+		b.paths[path] = make(PathItem)
+	}
+	b.paths[path][strings.ToLower(method)] = op
+}
+
+// rate limited routes take an optional api key, requests without one get the anonymous budget
+var optionalAPIKey = []map[string][]string{{}, {"apiKey": {}}}
+
+// adds the error envelopes every /api/v2 route can send
+// a 400 the handler writes itself carries the same invalid_parameter code, so its description is kept next to the validator's
+func (b *builder) v2(op *Operation) *Operation {
+	op.Security = optionalAPIKey
+	op.Responses["401"] = b.envelopeError("invalid_api_key: the api key is unknown or was revoked")
+	op.Responses["403"] = b.envelopeError("forbidden: the client address is not allowed by the acl of this route")
+	op.Responses["429"] = b.envelopeError("rate_limited: too many requests from this client")
+	if len(op.Parameters) > 0 {
+		description := "invalid_parameter: a parameter does not match this document"
+		if existing := op.Responses["400"]; existing != nil {
+			description += ", or " + strings.TrimPrefix(existing.Description, "invalid_parameter: ")
+		}
+		op.Responses["400"] = b.envelopeError(description)
+	}
+	return op
+}
+
+// a successful /api/v2 response carrying data of the given type
+func (b *builder) envelope(description string, data interface{}) *Response {
+	return &Response{Description: description, Content: map[string]MediaType{
+		"application/json": {Schema: &Schema{
+			Type:     "object",
+			Required: []string{"success", "data", "error", "meta"},
+			Properties: map[string]*Schema{
+				"success": {Type: "boolean"},
+				"data":    b.schemas.of(reflect.TypeOf(data)),
+				"error":   {Type: "object", Nullable: true, Description: "Always null on success"},
+				"meta":    b.schemas.of(reflect.TypeOf(models.Meta{})),
+			},
+		}},
+	}}
+}
+
+// a failed /api/v2 response, the description names the error codes it can carry
+func (b *builder) envelopeError(description string) *Response {
+	if _, ok := b.schemas.components["ErrorEnvelope"]; !ok {
+		b.schemas.components["ErrorEnvelope"] = &Schema{
+			Type:     "object",
+			Required: []string{"success", "data", "error", "meta"},
+			Properties: map[string]*Schema{
+				"success": {Type: "boolean"},
+				"data":    {Type: "object", Nullable: true, Description: "Always null on errors"},
+				"error":   b.schemas.of(reflect.TypeOf(models.APIError{})),
+				"meta":    b.schemas.of(reflect.TypeOf(models.Meta{})),
+			},
+		}
+	}
+	return &Response{Description: description, Content: map[string]MediaType{
+		"application/json": {Schema: &Schema{Ref: schemaRefPrefix + "ErrorEnvelope"}},
+	}}
+}
+
+// adds the responses every rate limited route can send
+func (b *builder) public(op *Operation) *Operation {
+	op.Security = optionalAPIKey
+	op.Responses["401"] = b.json("The api key is unknown or was revoked", models.ErrorResponse{})
+	op.Responses["403"] = b.json("The client address is not allowed by the acl of this route", models.ErrorResponse{})
+	op.Responses["429"] = b.json("Too many requests from this client", models.ErrorResponse{})
+	if len(op.Parameters) > 0 {
+		op.Responses["400"] = b.invalidParams(op.Responses["400"])
+	}
+	return op
+}
+
+// documents the 400 the validation middleware sends, next to any 400 the handler writes itself
+func (b *builder) invalidParams(existing *Response) *Response {
+	response := b.json("A parameter does not match this document", models.ErrorResponse{})
+	if existing != nil {
+		for mediaType, media := range existing.Content {
+			response.Content[mediaType] = media
+		}
+	}
+	return response
+}
+
+func (b *builder) addAdmin(method, path, id, summary string, responses map[string]*Response, params ...Parameter) {
+	responses["401"] = b.json("A valid admin token is required", models.ErrorResponse{})
+	responses["403"] = b.json("The client address is not allowed by ADMIN_ACL", models.ErrorResponse{})
+	if len(params) > 0 {
+		responses["400"] = b.invalidParams(responses["400"])
+	}
+	b.add(method, path, &Operation{
+		OperationID: id,
+		Summary:     summary,
+		Tags:        []string{"admin"},
+		Parameters:  params,
+		Responses:   responses,
+		Security:    []map[string][]string{{"adminToken": {}}},
+	})
+}
+
+// responses of admin routes that start a background job
+func (b *builder) job(statuses ...string) map[string]*Response {
+	responses := map[string]*Response{
+		"202": b.json("The job was started, poll it at the Location header", models.JobResponse{}),
+	}
+	descriptions := map[string]string{
+		"404": "No cached reforge stone with that id",
+		"409": "Another instance is the scheduler leader",
+		"503": "Storage is not available",
+	}
+	for _, status := range statuses {
+		responses[status] = b.json(descriptions[status], models.ErrorResponse{})
+	}
+	return responses
+}
+
+func (b *builder) json(description string, value interface{}) *Response {
+	return &Response{Description: description, Content: map[string]MediaType{
+		"application/json": {Schema: b.schemas.of(reflect.TypeOf(value))},
+	}}
+}
+
+// http.Error responses
+func text(description string) *Response {
+	return &Response{Description: description, Content: map[string]MediaType{
+		"text/plain": {Schema: &Schema{Type: "string"}},
+	}}
+}
+
+func imageResponses() map[string]*Response {
+	return map[string]*Response{
+		"200": {Description: "PNG upscaled to the configured size", Content: map[string]MediaType{
+			"image/png": {Schema: &Schema{Type: "string", Format: "binary"}},
+		}},
+		"404": text("No texture for that item"),
+	}
+}
+
+func pathParam(name, description string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "path", Description: description, Required: true, Schema: schema}
+}
+
+func queryParam(name, description string, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: schema}
+}
+
+func timeParam() *Schema {
+	return (&Schema{Type: "string"}).matching(timeParamPattern)
+}
@@ -0,0 +1,155 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const schemaRefPrefix = "#/components/schemas/"
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// sets the pattern a string has to match, compiled once here instead of per request
+func (s *Schema) matching(expr string) *Schema {
+	s.Pattern = expr
+	s.pattern = regexp.MustCompile(expr)
+	return s
+}
+
+// limits the length of a string
+func (s *Schema) length(min, max int) *Schema {
+	s.MinLength, s.MaxLength = &min, &max
+	return s
+}
+
+func (s *Schema) atLeast(min float64) *Schema {
+	s.Minimum = &min
+	return s
+}
+
+func (s *Schema) describe(description string) *Schema {
+	s.Description = description
+	return s
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// derives component schemas from go types the way encoding/json would encode them
+type schemas struct {
+	components map[string]*Schema
+	// component names for types whose go name would be ambiguous in the spec
+	names map[reflect.Type]string
+	seen  map[string]reflect.Type
+}
+
+func newSchemas(names map[reflect.Type]string) *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      names,
+		seen:       make(map[string]reflect.Type),
+	}
+}
+
+// returns the schema for values of type t, structs are added as components and referenced
+func (s *schemas) of(t reflect.Type) *Schema {
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return s.of(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	case reflect.Interface:
+		// anything goes, an empty schema matches every value
+		return &Schema{}
+	case reflect.Struct:
+		return &Schema{Ref: schemaRefPrefix + s.component(t)}
+	}
+	panic(fmt.Sprintf("openapi: no schema for %s", t))
+}
+
+func (s *schemas) component(t reflect.Type) string {
+	name, ok := s.names[t]
+	if !ok {
+		name = t.Name()
+	}
+	if other, ok := s.seen[name]; ok {
+		if other != t {
+			panic(fmt.Sprintf("openapi: %s and %s would both be named %s", other, t, name))
+		}
+		return name
+	}
+	s.seen[name] = t
+
+	// registered before the fields so self references end
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.components[name] = schema
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		key, options, _ := strings.Cut(tag, ",")
+		if key == "" {
+			key = field.Name
+		}
+
+		property := s.of(field.Type)
+		if strings.Contains(options, "omitempty") {
+			schema.Properties[key] = property
+			continue
+		}
+		// encoding/json writes null for nil slices, maps and pointers without omitempty
+		switch field.Type.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Pointer:
+			if property.Ref == "" {
+				property.Nullable = true
+			}
+		}
+		schema.Properties[key] = property
+		schema.Required = append(schema.Required, key)
+	}
+	return name
+}
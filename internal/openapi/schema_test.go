@@ -0,0 +1,40 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/models"
+)
+
+func TestSchemas_WhenDerivingFromModels_FollowsJSONTags(t *testing.T) {
+	// Arrange
+	schemas := newSchemas(nil)
+
+	// Act
+	ref := schemas.of(reflect.TypeOf(models.ReforgesResponse{}))
+
+	// Assert
+	assert.Equal(t, schemaRefPrefix+"ReforgesResponse", ref.Ref)
+	reforge := schemas.components["Reforge"]
+	require.NotNil(t, reforge)
+	assert.Contains(t, reforge.Required, "reforge_name")
+	assert.NotContains(t, reforge.Required, "stone_price")
+	assert.Equal(t, "integer", reforge.Properties["stone_price"].Type)
+	assert.Equal(t, "int64", reforge.Properties["stone_price"].Format)
+	assert.True(t, reforge.Properties["required_rarities"].Nullable)
+	assert.Equal(t, schemaRefPrefix+"ReforgeStats", reforge.Properties["reforge_stats"].AdditionalProperties.Ref)
+	assert.Equal(t, "date-time", schemas.components["ReforgesResponse"].Properties["lastUpdated"].Format)
+}
+
+func TestSchemas_WhenTwoTypesShareAName_Panics(t *testing.T) {
+	// Arrange
+	type Item struct{}
+	schemas := newSchemas(nil)
+	schemas.of(reflect.TypeOf(models.Item{}))
+
+	// Act & Assert
+	assert.Panics(t, func() { schemas.of(reflect.TypeOf(Item{})) })
+}
@@ -0,0 +1,227 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// checks a decoded json value against a schema, references are resolved in the document's components
+func (d *Document) Validate(schema *Schema, value interface{}) error {
+	return d.validate(schema, value, "")
+}
+
+func (d *Document) validate(s *Schema, value interface{}, path string) error {
+	if s.Ref != "" {
+		target, ok := d.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+		if !ok {
+			return fmt.Errorf("%s: unknown schema %s", at(path), s.Ref)
+		}
+		return d.validate(target, value, path)
+	}
+	if s.Type == "" {
+		return nil
+	}
+	if value == nil {
+		if s.Nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: must not be null", at(path))
+	}
+
+	switch s.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		if s.MinLength != nil && utf8.RuneCountInString(str) < *s.MinLength {
+			return fmt.Errorf("%s: must be at least %d characters", at(path), *s.MinLength)
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(str) > *s.MaxLength {
+			return fmt.Errorf("%s: must be at most %d characters", at(path), *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			return fmt.Errorf("%s: must match %s", at(path), s.Pattern)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				return fmt.Errorf("%s: must be an RFC 3339 time", at(path))
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		if s.Type == "integer" && n != math.Trunc(n) {
+			return typeError(path, s.Type, value)
+		}
+		if s.Minimum != nil && n < *s.Minimum {
+			return fmt.Errorf("%s: must be at least %v", at(path), *s.Minimum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(path, s.Type, value)
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		for i, item := range list {
+			if err := d.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(path, s.Type, value)
+		}
+		for _, key := range s.Required {
+			if _, ok := object[key]; !ok {
+				return fmt.Errorf("%s: missing required property %s", at(path), key)
+			}
+		}
+		for key, property := range object {
+			schema, ok := s.Properties[key]
+			if !ok {
+				schema = s.AdditionalProperties
+			}
+			if schema == nil {
+				continue
+			}
+			if err := d.validate(schema, property, join(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checks the path and query parameters of a request against an operation
+// raw values are converted to the parameter's type first, empty query values count as absent like the handlers treat them
+func (d *Document) CheckParams(op *Operation, pathValues map[string]string, query url.Values) error {
+	for _, param := range op.Parameters {
+		var raw string
+		switch param.In {
+		case "path":
+			raw = pathValues[param.Name]
+		case "query":
+			raw = query.Get(param.Name)
+		default:
+			continue
+		}
+		if raw == "" {
+			if param.Required {
+				return fmt.Errorf("missing %s parameter %s", param.In, param.Name)
+			}
+			continue
+		}
+
+		value, err := parseParam(param.Schema, raw)
+		if err != nil {
+			return fmt.Errorf("invalid %s parameter %s: %w", param.In, param.Name, err)
+		}
+		// the error already starts with the parameter name
+		if err := d.validate(param.Schema, value, param.Name); err != nil {
+			return fmt.Errorf("invalid %s parameter %w", param.In, err)
+		}
+	}
+	return nil
+}
+
+func parseParam(s *Schema, raw string) (interface{}, error) {
+	switch s.Type {
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be an integer")
+		}
+		return float64(n), nil
+	case "number":
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a number")
+		}
+		return n, nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return b, nil
+	}
+	return raw, nil
+}
+
+// checks a response a handler wrote against what the spec documents for its route and status
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	op := d.Operation(method, path)
+	if op == nil {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		return fmt.Errorf("%s %s does not document status %d", method, path, status)
+	}
+	if len(response.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("%s %s documents no body for status %d", method, path, status)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid content type %q", method, path, contentType)
+	}
+	media, ok := response.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s does not document %s for status %d", method, path, mediaType, status)
+	}
+	if media.Schema == nil || mediaType != "application/json" {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: decoding body: %w", method, path, err)
+	}
+	if err := d.Validate(media.Schema, value); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func typeError(path, want string, value interface{}) error {
+	return fmt.Errorf("%s: must be %s %s, got %T", at(path), article(want), want, value)
+}
+
+func article(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+func at(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
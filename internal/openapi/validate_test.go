@@ -0,0 +1,58 @@
+package openapi
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckParams_WhenValuesMatch_AcceptsThem(t *testing.T) {
+	// Arrange
+	spec := Spec()
+	rollback := spec.Operation(http.MethodPost, "/admin/snapshots/{version}/rollback")
+	changes := spec.Operation(http.MethodGet, "/api/changes")
+
+	// Act
+	pathErr := spec.CheckParams(rollback, map[string]string{"version": "12"}, nil)
+	queryErr := spec.CheckParams(changes, nil, url.Values{"since": {"2026-01-01T00:00:00Z"}, "until": {"1767225600"}, "other": {"x"}})
+
+	// Assert
+	assert.NoError(t, pathErr)
+	assert.NoError(t, queryErr)
+}
+
+func TestCheckParams_WhenValuesDoNotMatch_NamesTheParameter(t *testing.T) {
+	// Arrange
+	spec := Spec()
+	rollback := spec.Operation(http.MethodPost, "/admin/snapshots/{version}/rollback")
+	changes := spec.Operation(http.MethodGet, "/api/changes")
+	image := spec.Operation(http.MethodGet, "/api/item/{itemId}")
+
+	// Act
+	notInteger := spec.CheckParams(rollback, map[string]string{"version": "latest"}, nil)
+	tooSmall := spec.CheckParams(rollback, map[string]string{"version": "0"}, nil)
+	badTime := spec.CheckParams(changes, nil, url.Values{"since": {"yesterday"}})
+	missing := spec.CheckParams(image, map[string]string{}, nil)
+
+	// Assert
+	assert.EqualError(t, notInteger, "invalid path parameter version: must be an integer")
+	assert.EqualError(t, tooSmall, "invalid path parameter version: must be at least 1")
+	assert.ErrorContains(t, badTime, "invalid query parameter since: must match")
+	assert.EqualError(t, missing, "missing path parameter itemId")
+}
+
+func TestValidateResponse_WhenBodyBreaksTheSchema_ReportsWhere(t *testing.T) {
+	// Arrange
+	spec := Spec()
+	body := []byte(`{"success":true,"count":1,"lastUpdated":"2026-01-01T00:00:00Z","reforgeStones":[{"name":"Dragon Claw","category":"REFORGE_STONE","tier":"EPIC","id":"DRAGON_CLAW","auction_price":"cheap"}]}`)
+
+	// Act
+	err := spec.ValidateResponse(http.MethodGet, "/api/reforge-stones", http.StatusOK, "application/json", body)
+	undocumented := spec.ValidateResponse(http.MethodGet, "/api/reforge-stones", http.StatusTeapot, "text/plain", nil)
+
+	// Assert
+	assert.EqualError(t, err, "GET /api/reforge-stones: reforgeStones[0].auction_price: must be an integer, got string")
+	assert.EqualError(t, undocumented, "GET /api/reforge-stones does not document status 418")
+}
@@ -1,21 +1,21 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
-	"time"
 
-	"yard-backend/internal/config"
+	"yard-backend/internal/metrics"
 	"yard-backend/internal/models"
-	"yard-backend/internal/utils"
+	"yard-backend/internal/upstream"
 )
 
 // fetches reforge stones from the hypixel api and filters for reforge stone category
-func FetchReforgeStones() ([]models.Item, int64, error) {
-	resp, err := http.Get(config.APIURL)
+func (svc *Service) FetchReforgeStones(ctx context.Context) ([]models.Item, int64, error) {
+	resp, err := svc.client.Get(ctx, svc.settings().Upstream.HypixelURL, upstream.HypixelPolicy())
 	if err != nil {
 		return nil, 0, err
 	}
@@ -45,15 +45,17 @@ func FetchReforgeStones() ([]models.Item, int64, error) {
 }
 
 // fetches the lowest auction price for an item from skycofl api
-func FetchAuctionPrice(itemTag string) *int64 {
-	url := fmt.Sprintf("%s/api/auctions/tag/%s/active/bin", config.SkyCoflURL, itemTag)
-	resp, err := http.Get(url)
+func (svc *Service) FetchAuctionPrice(ctx context.Context, itemTag string) *int64 {
+	url := fmt.Sprintf("%s/api/auctions/tag/%s/active/bin", svc.settings().Upstream.CoflnetURL, itemTag)
+	resp, err := svc.client.Get(ctx, url, upstream.CoflnetPolicy(svc.throttle.Wait))
 	if err != nil {
+		metrics.RecordPriceFailure("auction")
 		return nil
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		metrics.RecordPriceFailure("auction")
 		return nil
 	}
 
@@ -64,6 +66,7 @@ func FetchAuctionPrice(itemTag string) *int64 {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&auctions); err != nil {
+		metrics.RecordPriceFailure("auction")
 		return nil
 	}
 
@@ -94,61 +97,24 @@ func FetchAuctionPrice(itemTag string) *int64 {
 }
 
 // fetches bazaar price data
-// retries indefinitely until success when rate limited
-func FetchBazaarPriceWithRetry(itemTag string, normalizedTag string) (*http.Response, error) {
-	baseDelay := 2 * time.Second
-	maxDelay := 60 * time.Second
-	attempt := 0
-
-	for {
-		utils.RateLimitWait()
-
-		url := fmt.Sprintf("%s/api/bazaar/%s/snapshot", config.SkyCoflURL, normalizedTag)
-		resp, err := http.Get(url)
-		if err != nil {
-			return nil, err
-		}
-
-		if resp.StatusCode == http.StatusOK {
-			return resp, nil
-		}
-
-		if resp.StatusCode == 429 {
-			resp.Body.Close()
-			attempt++
-
-			retryAfter := baseDelay * time.Duration(1<<attempt)
-			if retryAfter > maxDelay {
-				retryAfter = maxDelay
-			}
-
-			if resetTimeStr := resp.Header.Get("X-RateLimit-Reset"); resetTimeStr != "" {
-				if resetTime, err := time.Parse(time.RFC3339, resetTimeStr); err == nil {
-					waitTime := time.Until(resetTime)
-					if waitTime > 0 && waitTime < retryAfter {
-						retryAfter = waitTime
-					}
-				}
-			}
-
-			if attempt >= 5 {
-				log.Printf("Rate limited for %s (attempt %d), waiting %v", itemTag, attempt, retryAfter)
-			}
-			time.Sleep(retryAfter)
-			continue
-		}
-
-		return resp, nil
+// retries rate limited responses a bounded number of times honouring retry-after and x-ratelimit-reset
+func (svc *Service) FetchBazaarPriceWithRetry(ctx context.Context, itemTag string, normalizedTag string) (*http.Response, error) {
+	url := fmt.Sprintf("%s/api/bazaar/%s/snapshot", svc.settings().Upstream.CoflnetURL, normalizedTag)
+	resp, err := svc.client.Get(ctx, url, upstream.CoflnetPolicy(svc.throttle.Wait))
+	if err != nil {
+		return nil, fmt.Errorf("bazaar snapshot for %s: %w", itemTag, err)
 	}
+	return resp, nil
 }
 
 // fetches bazaar buy and sell prices along with top buy and sell orders for an item
-func FetchBazaarPrice(itemTag string) (*float64, *float64, []models.BazaarOrder, []models.BazaarOrder) {
+func (svc *Service) FetchBazaarPrice(ctx context.Context, itemTag string) (*float64, *float64, []models.BazaarOrder, []models.BazaarOrder) {
 	normalizedTag := strings.ToLower(itemTag)
 
-	resp, err := FetchBazaarPriceWithRetry(itemTag, normalizedTag)
+	resp, err := svc.FetchBazaarPriceWithRetry(ctx, itemTag, normalizedTag)
 	if err != nil {
 		log.Printf("Error fetching bazaar data for %s (tried %s): %v", itemTag, normalizedTag, err)
+		metrics.RecordPriceFailure("bazaar")
 		return nil, nil, nil, nil
 	}
 	defer resp.Body.Close()
@@ -156,9 +122,10 @@ func FetchBazaarPrice(itemTag string) (*float64, *float64, []models.BazaarOrder,
 	if resp.StatusCode != http.StatusOK {
 		if resp.StatusCode != 429 && normalizedTag != itemTag {
 			resp.Body.Close()
-			resp2, err2 := FetchBazaarPriceWithRetry(itemTag, itemTag)
+			resp2, err2 := svc.FetchBazaarPriceWithRetry(ctx, itemTag, itemTag)
 			if err2 != nil {
 				log.Printf("Bazaar API error for %s (tried both %s and %s): %v", itemTag, normalizedTag, itemTag, err2)
+				metrics.RecordPriceFailure("bazaar")
 				return nil, nil, nil, nil
 			}
 			defer resp2.Body.Close()
@@ -168,6 +135,7 @@ func FetchBazaarPrice(itemTag string) (*float64, *float64, []models.BazaarOrder,
 				if resp2.StatusCode != 429 {
 					log.Printf("Bazaar API returned status %d for %s (tried both %s and %s)", resp2.StatusCode, itemTag, normalizedTag, itemTag)
 				}
+				metrics.RecordPriceFailure("bazaar")
 				return nil, nil, nil, nil
 			}
 		} else {
@@ -176,6 +144,7 @@ func FetchBazaarPrice(itemTag string) (*float64, *float64, []models.BazaarOrder,
 			} else {
 				log.Printf("Bazaar API returned status %d for %s", resp.StatusCode, itemTag)
 			}
+			metrics.RecordPriceFailure("bazaar")
 			return nil, nil, nil, nil
 		}
 	}
@@ -197,6 +166,7 @@ func FetchBazaarPrice(itemTag string) (*float64, *float64, []models.BazaarOrder,
 
 	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
 		log.Printf("Error decoding bazaar snapshot for %s: %v", itemTag, err)
+		metrics.RecordPriceFailure("bazaar")
 		return nil, nil, nil, nil
 	}
 
@@ -227,7 +197,7 @@ func FetchBazaarPrice(itemTag string) (*float64, *float64, []models.BazaarOrder,
 			}
 		}
 
-		count := 3
+		count := svc.settings().API.OrderBookDepth
 		if len(buyOrders) < count {
 			count = len(buyOrders)
 		}
@@ -253,7 +223,7 @@ func FetchBazaarPrice(itemTag string) (*float64, *float64, []models.BazaarOrder,
 			}
 		}
 
-		count := 3
+		count := svc.settings().API.OrderBookDepth
 		if len(sellOrders) < count {
 			count = len(sellOrders)
 		}
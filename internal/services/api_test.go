@@ -1,20 +1,23 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"yard-backend/internal/config"
 	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
 )
 
 func TestFetchReforgeStones_WhenApiReturnsSuccess_ReturnsStones(t *testing.T) {
 	// Arrange
-	originalAPIURL := config.APIURL
-	defer func() { config.APIURL = originalAPIURL }()
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response := models.HypixelAPIResponse{
@@ -29,10 +32,12 @@ func TestFetchReforgeStones_WhenApiReturnsSuccess_ReturnsStones(t *testing.T) {
 	}))
 	defer server.Close()
 
-	config.APIURL = server.URL
+	cfg := config.Default()
+	cfg.Upstream.HypixelURL = server.URL
+	svc := New(cfg, storage.NewMemory())
 
 	// Act
-	stones, _, err := FetchReforgeStones()
+	stones, _, err := svc.FetchReforgeStones(context.Background())
 
 	// Assert
 	assert.NoError(t, err)
@@ -43,18 +48,18 @@ func TestFetchReforgeStones_WhenApiReturnsSuccess_ReturnsStones(t *testing.T) {
 
 func TestFetchReforgeStones_WhenApiReturnsError_ReturnsError(t *testing.T) {
 	// Arrange
-	originalAPIURL := config.APIURL
-	defer func() { config.APIURL = originalAPIURL }()
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	}))
 	defer server.Close()
 
-	config.APIURL = server.URL
+	cfg := config.Default()
+	cfg.Upstream.HypixelURL = server.URL
+	svc := New(cfg, storage.NewMemory())
 
 	// Act
-	stones, _, err := FetchReforgeStones()
+	stones, _, err := svc.FetchReforgeStones(context.Background())
 
 	// Assert
 	assert.Error(t, err)
@@ -63,8 +68,6 @@ func TestFetchReforgeStones_WhenApiReturnsError_ReturnsError(t *testing.T) {
 
 func TestFetchAuctionPrice_WhenAuctionsExist_ReturnsLowestPrice(t *testing.T) {
 	// Arrange
-	originalSkyCoflURL := config.SkyCoflURL
-	defer func() { config.SkyCoflURL = originalSkyCoflURL }()
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		auctions := []map[string]interface{}{
@@ -75,12 +78,42 @@ func TestFetchAuctionPrice_WhenAuctionsExist_ReturnsLowestPrice(t *testing.T) {
 	}))
 	defer server.Close()
 
-	config.SkyCoflURL = server.URL
+	cfg := config.Default()
+	cfg.Upstream.CoflnetURL = server.URL
+	svc := New(cfg, storage.NewMemory())
 
 	// Act
-	price := FetchAuctionPrice("TEST_ITEM")
+	price := svc.FetchAuctionPrice(context.Background(), "TEST_ITEM")
 
 	// Assert
 	assert.NotNil(t, price)
 	assert.Equal(t, int64(1000), *price)
 }
+
+func TestFetchAuctionPrice_WhenCalledBackToBack_KeepsTheCoflnetDelay(t *testing.T) {
+	// Arrange
+	var mu sync.Mutex
+	var requests []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, time.Now())
+		mu.Unlock()
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"startingBid": 1000, "bin": true}})
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Upstream.CoflnetURL = server.URL
+	cfg.Upstream.CoflnetMinDelay = 100 * time.Millisecond
+	svc := New(cfg, storage.NewMemory())
+
+	// Act
+	svc.FetchAuctionPrice(context.Background(), "AMBER")
+	svc.FetchAuctionPrice(context.Background(), "JADERALD")
+
+	// Assert
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 2)
+	assert.GreaterOrEqual(t, requests[1].Sub(requests[0]), 90*time.Millisecond)
+}
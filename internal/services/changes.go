@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+// history series holding one changeset per hypixel fetch that changed something
+const changesSeries = "catalog_changes"
+
+// the item fields hypixel controls, prices and neu effects change on their own schedule and are not patch notes
+var trackedFields = []struct {
+	name  string
+	value func(models.Item) interface{}
+}{
+	{"name", func(item models.Item) interface{} { return item.Name }},
+	{"category", func(item models.Item) interface{} { return item.Category }},
+	{"tier", func(item models.Item) interface{} { return item.Tier }},
+	{"npc_sell_price", func(item models.Item) interface{} { return item.NPCSellPrice }},
+	{"stats", func(item models.Item) interface{} { return item.Stats }},
+	{"skin", func(item models.Item) interface{} { return item.Skin }},
+	{"glowing", func(item models.Item) interface{} { return item.Glowing }},
+	{"soulbound", func(item models.Item) interface{} { return item.Soulbound }},
+	{"requirements", func(item models.Item) interface{} { return item.Requirements }},
+	{"can_auction", func(item models.Item) interface{} { return item.CanAuction }},
+	{"item_specific", func(item models.Item) interface{} { return item.ItemSpecific }},
+}
+
+// compares the catalog before a fetch with the stones hypixel just returned
+// stones coming back from delisting count as added, stones hypixel dropped count as removed once
+func diffCatalog(previous []models.Item, fetched []models.Item, at time.Time) models.ChangeSet {
+	changes := models.ChangeSet{
+		At:       at,
+		Added:    []models.ItemRef{},
+		Removed:  []models.ItemRef{},
+		Modified: []models.ItemChange{},
+	}
+
+	before := make(map[string]models.Item, len(previous))
+	for _, stone := range previous {
+		before[stone.ID] = stone
+	}
+
+	listed := make(map[string]bool, len(fetched))
+	for _, stone := range fetched {
+		listed[stone.ID] = true
+		old, ok := before[stone.ID]
+		if !ok || old.DelistedAt != nil {
+			changes.Added = append(changes.Added, itemRef(stone))
+			continue
+		}
+		if fields := diffFields(old, stone); len(fields) > 0 {
+			changes.Modified = append(changes.Modified, models.ItemChange{ID: stone.ID, Name: stone.Name, Fields: fields})
+		}
+	}
+
+	for _, stone := range previous {
+		if !listed[stone.ID] && stone.DelistedAt == nil {
+			changes.Removed = append(changes.Removed, itemRef(stone))
+		}
+	}
+
+	sort.Slice(changes.Added, func(i, j int) bool { return changes.Added[i].ID < changes.Added[j].ID })
+	sort.Slice(changes.Removed, func(i, j int) bool { return changes.Removed[i].ID < changes.Removed[j].ID })
+	sort.Slice(changes.Modified, func(i, j int) bool { return changes.Modified[i].ID < changes.Modified[j].ID })
+	return changes
+}
+
+func itemRef(stone models.Item) models.ItemRef {
+	return models.ItemRef{ID: stone.ID, Name: stone.Name, Tier: stone.Tier}
+}
+
+func diffFields(old, next models.Item) []models.FieldChange {
+	var fields []models.FieldChange
+	for _, field := range trackedFields {
+		oldValue, newValue := normalizeField(field.value(old)), normalizeField(field.value(next))
+		if sameJSON(oldValue, newValue) {
+			continue
+		}
+		fields = append(fields, models.FieldChange{Field: field.name, Old: oldValue, New: newValue})
+	}
+	return fields
+}
+
+// treats empty maps and slices like missing ones since hypixel omits them either way
+func normalizeField(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		if v.Len() == 0 {
+			return nil
+		}
+	case reflect.Invalid:
+		return nil
+	}
+	return value
+}
+
+// compares through json so numbers decoded as float64 and map ordering do not matter
+func sameJSON(a, b interface{}) bool {
+	left, errLeft := json.Marshal(a)
+	right, errRight := json.Marshal(b)
+	return errLeft == nil && errRight == nil && string(left) == string(right)
+}
+
+func isEmptyChangeSet(changes models.ChangeSet) bool {
+	return len(changes.Added) == 0 && len(changes.Removed) == 0 && len(changes.Modified) == 0
+}
+
+// records a changeset in the change log, failures only cost the patch notes so they are logged
+func (svc *Service) recordChanges(ctx context.Context, changes models.ChangeSet) {
+	data, err := json.Marshal(changes)
+	if err != nil {
+		log.Printf("Error encoding catalog changes: %v", err)
+		return
+	}
+	if err := svc.store.AppendHistory(ctx, changesSeries, storage.HistoryEntry{At: changes.At, Data: data}); err != nil {
+		log.Printf("Error recording catalog changes: %v", err)
+		return
+	}
+	log.Printf("Catalog changed: %d added, %d removed, %d modified",
+		len(changes.Added), len(changes.Removed), len(changes.Modified))
+}
+
+// returns the catalog changes recorded after since and no later than until, newest first
+// a zero until means up to now
+func (svc *Service) Changes(ctx context.Context, since, until time.Time) ([]models.ChangeSet, error) {
+	entries, err := svc.store.History(ctx, changesSeries, since, 0)
+	if err != nil {
+		return nil, fmt.Errorf("loading catalog changes: %w", err)
+	}
+
+	changes := make([]models.ChangeSet, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !until.IsZero() && entry.At.After(until) {
+			continue
+		}
+		var changeSet models.ChangeSet
+		if err := json.Unmarshal(entry.Data, &changeSet); err != nil {
+			log.Printf("Skipping undecodable catalog change from %s: %v", entry.At.Format(time.RFC3339), err)
+			continue
+		}
+		changes = append(changes, changeSet)
+	}
+	return changes, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffCatalog_WhenStonesChange_ReportsAddedRemovedAndModifiedFields(t *testing.T) {
+	// Arrange
+	delistedAt := time.Now()
+	previous := []models.Item{
+		{ID: "AMBER", Name: "Amber", Tier: "RARE", Stats: map[string]interface{}{}},
+		{ID: "JADE", Name: "Jade", Tier: "EPIC", NPCSellPrice: 10.0},
+		{ID: "GONE", Name: "Gone"},
+		{ID: "BACK", Name: "Back", DelistedAt: &delistedAt},
+	}
+	fetched := []models.Item{
+		{ID: "AMBER", Name: "Amber", Tier: "RARE"},
+		{ID: "JADE", Name: "Jade", Tier: "LEGENDARY", NPCSellPrice: 12.0},
+		{ID: "BACK", Name: "Back"},
+		{ID: "NEW", Name: "New"},
+	}
+
+	// Act
+	changes := diffCatalog(previous, fetched, time.Now())
+
+	// Assert
+	require.Len(t, changes.Added, 2)
+	assert.Equal(t, "BACK", changes.Added[0].ID)
+	assert.Equal(t, "NEW", changes.Added[1].ID)
+	require.Len(t, changes.Removed, 1)
+	assert.Equal(t, "GONE", changes.Removed[0].ID)
+	require.Len(t, changes.Modified, 1)
+	assert.Equal(t, "JADE", changes.Modified[0].ID)
+	assert.Equal(t, []models.FieldChange{
+		{Field: "tier", Old: "EPIC", New: "LEGENDARY"},
+		{Field: "npc_sell_price", Old: 10.0, New: 12.0},
+	}, changes.Modified[0].Fields)
+}
+
+func TestStoreReforgeStones_WhenCatalogChanges_RecordsChangeSet(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := storage.NewMemory()
+	svc := New(config.Default(), store)
+	require.NoError(t, svc.StoreReforgeStones(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}}))
+
+	// Act
+	err := svc.StoreReforgeStones(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}, {ID: "JADE", Name: "Jade"}})
+
+	// Assert
+	require.NoError(t, err)
+	changes, err := svc.Changes(ctx, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Len(t, changes[0].Added, 1)
+	assert.Equal(t, "JADE", changes[0].Added[0].ID)
+}
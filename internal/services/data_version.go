@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+// identifies the data behind the read endpoints, one of the fields moves whenever their json would change
+type DataVersion struct {
+	Snapshot       int64
+	SnapshotAt     time.Time
+	HypixelUpdated time.Time
+	PricesUpdated  time.Time
+	// digest of the loaded neu files so replicas that loaded the same files agree on etags
+	NEUDigest string
+}
+
+// returns the version of the data currently served
+func (svc *Service) DataVersion(ctx context.Context) (DataVersion, error) {
+	var version DataVersion
+
+	versions, err := svc.store.Versions(ctx)
+	if err != nil {
+		return version, fmt.Errorf("loading snapshot versions: %w", err)
+	}
+	for _, v := range versions {
+		if v.Current {
+			version.Snapshot = v.ID
+			version.SnapshotAt = v.PublishedAt
+		}
+	}
+
+	if version.HypixelUpdated, err = svc.store.Timestamp(ctx, storage.HypixelUpdated); err != nil {
+		return version, fmt.Errorf("loading hypixel fetch time: %w", err)
+	}
+	if version.PricesUpdated, err = svc.store.Timestamp(ctx, storage.PricesUpdated); err != nil {
+		return version, fmt.Errorf("loading price refresh time: %w", err)
+	}
+	version.NEUDigest = svc.neuDigest()
+	return version, nil
+}
+
+// returns the version of the loaded neu files alone, for responses built while storage is down
+func (svc *Service) NEUOnlyVersion() DataVersion {
+	return DataVersion{NEUDigest: svc.neuDigest()}
+}
+
+// records a successful load of a neu file
+func (svc *Service) markNEULoaded(name string, data []byte) {
+	svc.neuMutex.Lock()
+	defer svc.neuMutex.Unlock()
+	if svc.neuDigests == nil {
+		svc.neuDigests = make(map[string][32]byte)
+	}
+	svc.neuDigests[name] = sha256.Sum256(data)
+}
+
+func (svc *Service) neuDigest() string {
+	svc.neuMutex.Lock()
+	defer svc.neuMutex.Unlock()
+
+	names := make([]string, 0, len(svc.neuDigests))
+	for name := range svc.neuDigests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := sha256.New()
+	for _, name := range names {
+		digest := svc.neuDigests[name]
+		hash.Write([]byte(name))
+		hash.Write(digest[:])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// returns the latest change of the stored data, the zero time when nothing was stored yet
+// neu loads are left out since each replica loads the files at its own time, only the etag follows them
+func (v DataVersion) LastModified() time.Time {
+	latest := v.SnapshotAt
+	for _, t := range []time.Time{v.HypixelUpdated, v.PricesUpdated} {
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// returns a strong etag for the named representation of this version
+func (v DataVersion) ETag(representation string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%d|%d|%s", representation, v.Snapshot,
+		v.SnapshotAt.UnixMilli(), v.HypixelUpdated.UnixMilli(), v.PricesUpdated.UnixMilli(), v.NEUDigest)))
+	return `"` + hex.EncodeToString(hash[:12]) + `"`
+}
+
+// returns an opaque id of this version, replicas serving the same data agree on it
+func (v DataVersion) ID() string {
+	return strings.Trim(v.ETag(""), `"`)
+}
+
+// returns the /api/v2 meta for data of this version, a zero lastUpdated is left out
+func (v DataVersion) Meta(lastUpdated time.Time) models.Meta {
+	meta := models.Meta{Version: v.ID()}
+	if !lastUpdated.IsZero() {
+		meta.LastUpdated = &lastUpdated
+	}
+	return meta
+}
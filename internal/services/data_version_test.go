@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataVersion_WhenReplicasLoadSameNEUFilesAtDifferentTimes_AgreeOnValidators(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.NEU.RepoPath = t.TempDir()
+	constants := filepath.Join(cfg.NEU.RepoPath, "constants")
+	require.NoError(t, os.MkdirAll(constants, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(constants, "reforges.json"), []byte(`{"Epic":{"itemTypes":"SWORD"}}`), 0o644))
+	store := storage.NewMemory()
+	_, err := store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}}, 1)
+	require.NoError(t, err)
+	first, second := New(cfg, store), New(cfg, store)
+	require.NoError(t, first.LoadNEUReforges())
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, second.LoadNEUReforges())
+
+	// Act
+	firstVersion, err := first.DataVersion(ctx)
+	require.NoError(t, err)
+	secondVersion, err := second.DataVersion(ctx)
+	require.NoError(t, err)
+
+	// Assert
+	assert.False(t, firstVersion.LastModified().IsZero())
+	assert.Equal(t, firstVersion.LastModified(), secondVersion.LastModified())
+	assert.Equal(t, firstVersion.ETag("reforges"), secondVersion.ETag("reforges"))
+}
@@ -1,23 +1,28 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 
-	"yard-backend/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"yard-backend/internal/metrics"
 	"yard-backend/internal/models"
 	"yard-backend/internal/utils"
 )
 
 // loads reforge stone definitions from the notenoughupdates repository json file
-func LoadNEUReforgeStones() error {
-	config.NEUReforgeStonesMutex.Lock()
-	defer config.NEUReforgeStonesMutex.Unlock()
-	
-	path := fmt.Sprintf("%s/constants/reforgestones.json", config.NEURepoPath)
+func (svc *Service) LoadNEUReforgeStones() (err error) {
+	// loads run at startup and on reload outside any request, so each is a trace of its own
+	_, span := startSpan(context.Background(), "services.LoadNEUReforgeStones", attribute.String("neu.file", "reforgestones.json"))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	path := fmt.Sprintf("%s/constants/reforgestones.json", svc.settings().NEU.RepoPath)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read reforgestones.json: %w", err)
@@ -28,14 +33,19 @@ func LoadNEUReforgeStones() error {
 		return fmt.Errorf("failed to parse reforgestones.json: %w", err)
 	}
 	
-	config.NEUReforgeStones = reforgestones
-	log.Printf("Loaded %d reforge stone definitions from NEU", len(config.NEUReforgeStones))
+	svc.neuDataMutex.Lock()
+	svc.neuReforgeStones = reforgestones
+	svc.neuDataMutex.Unlock()
+	span.SetAttributes(attribute.Int("neu.entries", len(reforgestones)))
+	svc.markNEULoaded("reforgestones.json", data)
+	metrics.SetNEULoaded("reforgestones.json", len(reforgestones), time.Since(start))
+	log.Printf("Loaded %d reforge stone definitions from NEU", len(reforgestones))
 	return nil
 }
 
 // gets item lore data from the notenoughupdates repository for a specific item id
-func GetNEUItemData(itemID string) ([]string, error) {
-	path := fmt.Sprintf("%s/items/%s.json", config.NEURepoPath, itemID)
+func (svc *Service) GetNEUItemData(itemID string) ([]string, error) {
+	path := fmt.Sprintf("%s/items/%s.json", svc.settings().NEU.RepoPath, itemID)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -52,11 +62,11 @@ func GetNEUItemData(itemID string) ([]string, error) {
 }
 
 // gets the reforge effect data for a stone including stats costs abilities and descriptions
-func GetReforgeEffectForStone(itemID string) *models.ReforgeEffect {
-	config.NEUReforgeStonesMutex.RLock()
-	defer config.NEUReforgeStonesMutex.RUnlock()
+func (svc *Service) GetReforgeEffectForStone(itemID string) *models.ReforgeEffect {
+	svc.neuDataMutex.RLock()
+	defer svc.neuDataMutex.RUnlock()
 	
-	stoneData, exists := config.NEUReforgeStones[itemID]
+	stoneData, exists := svc.neuReforgeStones[itemID]
 	if !exists {
 		return nil
 	}
@@ -166,7 +176,7 @@ func GetReforgeEffectForStone(itemID string) *models.ReforgeEffect {
 		}
 	}
 	
-	lore, err := GetNEUItemData(itemID)
+	lore, err := svc.GetNEUItemData(itemID)
 	if err == nil && len(lore) > 0 {
 		effect.Description = lore
 		effect.Obtaining = utils.ExtractObtainingFromLore(lore)
@@ -177,11 +187,13 @@ func GetReforgeEffectForStone(itemID string) *models.ReforgeEffect {
 }
 
 // loads all reforge definitions from the notenoughupdates repository reforges.json file
-func LoadNEUReforges() error {
-	config.NEUReforgesMutex.Lock()
-	defer config.NEUReforgesMutex.Unlock()
-	
-	path := fmt.Sprintf("%s/constants/reforges.json", config.NEURepoPath)
+func (svc *Service) LoadNEUReforges() (err error) {
+	// loads run at startup and on reload outside any request, so each is a trace of its own
+	_, span := startSpan(context.Background(), "services.LoadNEUReforges", attribute.String("neu.file", "reforges.json"))
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	path := fmt.Sprintf("%s/constants/reforges.json", svc.settings().NEU.RepoPath)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return fmt.Errorf("failed to read reforges.json: %w", err)
@@ -192,23 +204,83 @@ func LoadNEUReforges() error {
 		return fmt.Errorf("failed to parse reforges.json: %w", err)
 	}
 	
-	config.NEUReforges = reforges
-	log.Printf("Loaded %d reforge definitions from NEU reforges.json", len(config.NEUReforges))
+	svc.neuDataMutex.Lock()
+	svc.neuReforges = reforges
+	svc.neuDataMutex.Unlock()
+	span.SetAttributes(attribute.Int("neu.entries", len(reforges)))
+	svc.markNEULoaded("reforges.json", data)
+	metrics.SetNEULoaded("reforges.json", len(reforges), time.Since(start))
+	log.Printf("Loaded %d reforge definitions from NEU reforges.json", len(reforges))
 	return nil
 }
 
-// gets all reforges merging data from reforges.json and reforgestones.json
-func GetAllReforges() []models.Reforge {
-	config.NEUReforgesMutex.RLock()
-	defer config.NEUReforgesMutex.RUnlock()
+// gets all reforges merging data from reforges.json and reforgestones.json with cached stone prices
+func (svc *Service) GetAllReforges(ctx context.Context) []models.Reforge {
+	ctx, span := startSpan(ctx, "services.GetAllReforges")
+	defer span.End()
+
+	// build from the NEU data first so the locks are not held while waiting on storage
+	_, buildSpan := startSpan(ctx, "services.buildReforges")
+	reforgeMap := svc.buildReforges()
+	buildSpan.SetAttributes(attribute.Int("reforges", len(reforgeMap)))
+	buildSpan.End()
+
+	stoneIDs := make([]string, 0, len(reforgeMap))
+	for _, reforge := range reforgeMap {
+		if reforge.StoneID != "" {
+			stoneIDs = append(stoneIDs, reforge.StoneID)
+		}
+	}
+
+	// fetch stone prices and details from storage in one go
+	stones, err := svc.store.GetStones(ctx, stoneIDs)
+	if err != nil {
+		log.Printf("Error loading reforge stones: %v", err)
+		span.RecordError(err)
+	}
+	span.SetAttributes(attribute.Int("reforges", len(reforgeMap)), attribute.Int("stones", len(stones)))
+	stonesByID := make(map[string]models.Item, len(stones))
+	for _, stone := range stones {
+		stonesByID[stone.ID] = stone
+	}
+
+	// convert map to slice
+	reforges := make([]models.Reforge, 0, len(reforgeMap))
+	for _, reforge := range reforgeMap {
+		if stone, ok := stonesByID[reforge.StoneID]; ok && reforge.StoneID != "" {
+			reforge.StoneName = stone.Name
+			reforge.StoneTier = stone.Tier
+
+			// get best available price (auction > bazaar buy > bazaar sell)
+			if stone.AuctionPrice != nil {
+				price := int64(*stone.AuctionPrice)
+				reforge.StonePrice = &price
+			} else if stone.BazaarBuyPrice != nil {
+				price := int64(*stone.BazaarBuyPrice)
+				reforge.StonePrice = &price
+			} else if stone.BazaarSellPrice != nil {
+				price := int64(*stone.BazaarSellPrice)
+				reforge.StonePrice = &price
+			}
+		}
+		reforges = append(reforges, *reforge)
+	}
+	
+	// apply manual data corrections for known NEU data issues
+	applyDataCorrections(reforges)
 	
-	config.NEUReforgeStonesMutex.RLock()
-	defer config.NEUReforgeStonesMutex.RUnlock()
+	return reforges
+}
+
+// parses every reforge from the loaded NEU data, holding the NEU read lock only for the copy
+func (svc *Service) buildReforges() map[string]*models.Reforge {
+	svc.neuDataMutex.RLock()
+	defer svc.neuDataMutex.RUnlock()
 	
 	reforgeMap := make(map[string]*models.Reforge)
 	
 	// first load all reforges from reforges.json (blacksmith reforges)
-	for reforgeName, reforgeData := range config.NEUReforges {
+	for reforgeName, reforgeData := range svc.neuReforges {
 		reforgeDataMap, ok := reforgeData.(map[string]interface{})
 		if !ok {
 			continue
@@ -221,7 +293,7 @@ func GetAllReforges() []models.Reforge {
 	}
 	
 	// then overlay/add reforges from reforgestones.json (stone reforges have priority)
-	for stoneID, stoneData := range config.NEUReforgeStones {
+	for stoneID, stoneData := range svc.neuReforgeStones {
 		stoneDataMap, ok := stoneData.(map[string]interface{})
 		if !ok {
 			continue
@@ -235,46 +307,11 @@ func GetAllReforges() []models.Reforge {
 		reforge := parseReforgeData(reforgeName, stoneDataMap, "Reforge Stone")
 		if reforge != nil {
 			reforge.StoneID = stoneID
-			
-			// fetch stone price and details from redis
-			if config.RDB != nil {
-				key := fmt.Sprintf("reforge_stone:%s", stoneID)
-				stoneJSON, err := config.RDB.Get(config.Ctx, key).Result()
-				if err == nil && stoneJSON != "" {
-					var stone models.Item
-					if err := json.Unmarshal([]byte(stoneJSON), &stone); err == nil {
-						reforge.StoneName = stone.Name
-						reforge.StoneTier = stone.Tier
-						
-						// get best available price (auction > bazaar buy > bazaar sell)
-						if stone.AuctionPrice != nil {
-							price := int64(*stone.AuctionPrice)
-							reforge.StonePrice = &price
-						} else if stone.BazaarBuyPrice != nil {
-							price := int64(*stone.BazaarBuyPrice)
-							reforge.StonePrice = &price
-						} else if stone.BazaarSellPrice != nil {
-							price := int64(*stone.BazaarSellPrice)
-							reforge.StonePrice = &price
-						}
-					}
-				}
-			}
-			
 			reforgeMap[reforgeName] = reforge
 		}
 	}
 	
-	// convert map to slice
-	reforges := make([]models.Reforge, 0, len(reforgeMap))
-	for _, reforge := range reforgeMap {
-		reforges = append(reforges, *reforge)
-	}
-	
-	// apply manual data corrections for known NEU data issues
-	applyDataCorrections(reforges)
-	
-	return reforges
+	return reforgeMap
 }
 
 // applies manual corrections to fix known data issues in NEU repo
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+const (
+	// history series holding the parsed reforges of every neu version that changed them
+	neuSnapshotSeries = "neu_reforges"
+	// history series per reforge, suffixed with the lowercase reforge name
+	reforgeHistoryPrefix = "reforge_history:"
+)
+
+// statuses of a reforge history entry
+const (
+	ReforgeAdded   = "added"
+	ReforgeChanged = "changed"
+	ReforgeRemoved = "removed"
+)
+
+// stored form of a history entry, the name answers lookups of reforges neu no longer has
+type reforgeHistoryRecord struct {
+	Reforge string `json:"reforge"`
+	models.ReforgeHistoryEntry
+}
+
+type neuSnapshot struct {
+	Version  string                    `json:"version"`
+	Reforges map[string]models.Reforge `json:"reforges"`
+}
+
+// diffs the reforges of the loaded neu data against the last recorded snapshot
+// and appends an entry to the history of every reforge that changed
+// the first snapshot only becomes the baseline, so history starts with the next neu update
+func (svc *Service) RecordReforgeHistory(ctx context.Context) error {
+	reforgeMap := svc.buildReforges()
+	if len(reforgeMap) == 0 {
+		return nil
+	}
+	reforges := make([]models.Reforge, 0, len(reforgeMap))
+	for _, reforge := range reforgeMap {
+		reforges = append(reforges, *reforge)
+	}
+	applyDataCorrections(reforges)
+
+	current := neuSnapshot{
+		Version:  svc.neuVersion(),
+		Reforges: make(map[string]models.Reforge, len(reforges)),
+	}
+	for _, reforge := range reforges {
+		current.Reforges[reforge.ReforgeName] = reforge
+	}
+
+	previous, err := svc.lastNEUSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if previous == nil {
+		log.Printf("Recorded NEU reforge baseline for version %s", current.Version)
+		return svc.appendNEUSnapshot(ctx, current, now)
+	}
+
+	entries := diffReforges(previous.Reforges, current.Reforges)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if err := svc.checkWriteFence(ctx); err != nil {
+		return err
+	}
+	for name, entry := range entries {
+		// a run that stopped before the snapshot advanced already wrote some of these, they are not written twice
+		recorded, err := svc.lastRecordedVersion(ctx, name)
+		if err != nil {
+			return err
+		}
+		if recorded == current.Version {
+			continue
+		}
+		entry.Version = current.Version
+		entry.Date = now
+		data, err := json.Marshal(reforgeHistoryRecord{Reforge: name, ReforgeHistoryEntry: *entry})
+		if err != nil {
+			return err
+		}
+		if err := svc.store.AppendHistory(ctx, reforgeHistoryKey(name), storage.HistoryEntry{At: now, Data: data}); err != nil {
+			return fmt.Errorf("recording history of %s: %w", name, err)
+		}
+	}
+	log.Printf("NEU version %s changed %d reforges", current.Version, len(entries))
+	return svc.appendNEUSnapshot(ctx, current, now)
+}
+
+// returns the neu version of the newest history entry of a reforge, empty when there is none
+func (svc *Service) lastRecordedVersion(ctx context.Context, name string) (string, error) {
+	records, err := svc.store.History(ctx, reforgeHistoryKey(name), time.Time{}, 1)
+	if err != nil {
+		return "", fmt.Errorf("loading history of %s: %w", name, err)
+	}
+	if len(records) == 0 {
+		return "", nil
+	}
+	var record reforgeHistoryRecord
+	if err := json.Unmarshal(records[0].Data, &record); err != nil {
+		return "", nil
+	}
+	return record.Version, nil
+}
+
+// reads only the newest snapshot, the series grows with every neu update
+func (svc *Service) lastNEUSnapshot(ctx context.Context) (*neuSnapshot, error) {
+	entries, err := svc.store.History(ctx, neuSnapshotSeries, time.Time{}, 1)
+	if err != nil {
+		return nil, fmt.Errorf("loading neu snapshot: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	var snapshot neuSnapshot
+	if err := json.Unmarshal(entries[len(entries)-1].Data, &snapshot); err != nil {
+		return nil, fmt.Errorf("decoding neu snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func (svc *Service) appendNEUSnapshot(ctx context.Context, snapshot neuSnapshot, at time.Time) error {
+	if err := svc.checkWriteFence(ctx); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return svc.store.AppendHistory(ctx, neuSnapshotSeries, storage.HistoryEntry{At: at, Data: data})
+}
+
+// returns the balance history of a reforge newest first
+// reforges neu removed are still found through their history, false when neither knows the name
+func (svc *Service) ReforgeHistory(ctx context.Context, name string) (string, []models.ReforgeHistoryEntry, bool, error) {
+	canonical, known := svc.findReforgeName(name)
+
+	// the series key is lowercase, so the lookup is case insensitive for removed reforges too
+	records, err := svc.store.History(ctx, reforgeHistoryKey(name), time.Time{}, 0)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("loading history of %s: %w", name, err)
+	}
+	if !known && len(records) == 0 {
+		return "", nil, false, nil
+	}
+
+	history := make([]models.ReforgeHistoryEntry, 0, len(records))
+	for i := len(records) - 1; i >= 0; i-- {
+		var record reforgeHistoryRecord
+		if err := json.Unmarshal(records[i].Data, &record); err != nil {
+			log.Printf("Skipping undecodable history of %s: %v", name, err)
+			continue
+		}
+		if canonical == "" {
+			canonical = record.Reforge
+		}
+		history = append(history, record.ReforgeHistoryEntry)
+	}
+	// entries written before the name was stored leave only the spelling of the request
+	if canonical == "" {
+		canonical = name
+	}
+	return canonical, history, true, nil
+}
+
+// matches a reforge name from a url case insensitively against the loaded neu data
+func (svc *Service) findReforgeName(name string) (string, bool) {
+	for canonical := range svc.buildReforges() {
+		if strings.EqualFold(canonical, name) {
+			return canonical, true
+		}
+	}
+	return "", false
+}
+
+func reforgeHistoryKey(name string) string {
+	return reforgeHistoryPrefix + strings.ToLower(name)
+}
+
+// compares two snapshots reforge by reforge, returning an entry for every reforge that changed
+func diffReforges(previous, current map[string]models.Reforge) map[string]*models.ReforgeHistoryEntry {
+	entries := make(map[string]*models.ReforgeHistoryEntry)
+	for name, reforge := range current {
+		old, ok := previous[name]
+		if !ok {
+			entries[name] = &models.ReforgeHistoryEntry{Status: ReforgeAdded, Changes: diffReforge(models.Reforge{}, reforge)}
+			continue
+		}
+		if changes := diffReforge(old, reforge); len(changes) > 0 {
+			entries[name] = &models.ReforgeHistoryEntry{Status: ReforgeChanged, Changes: changes}
+		}
+	}
+	for name, old := range previous {
+		if _, ok := current[name]; !ok {
+			entries[name] = &models.ReforgeHistoryEntry{Status: ReforgeRemoved, Changes: diffReforge(old, models.Reforge{})}
+		}
+	}
+	return entries
+}
+
+// lists stat, cost and ability differences, stats are compared per rarity
+func diffReforge(old, next models.Reforge) []models.BalanceChange {
+	changes := []models.BalanceChange{}
+
+	for _, rarity := range unionKeys(old.ReforgeStats, next.ReforgeStats) {
+		oldStats, newStats := StatValues(old.ReforgeStats[rarity]), StatValues(next.ReforgeStats[rarity])
+		for _, stat := range unionKeys(oldStats, newStats) {
+			oldValue, hadOld := oldStats[stat]
+			newValue, hasNew := newStats[stat]
+			if hadOld && hasNew && oldValue == newValue {
+				continue
+			}
+			changes = append(changes, models.BalanceChange{Rarity: rarity, Stat: stat, Old: optional(oldValue, hadOld), New: optional(newValue, hasNew)})
+		}
+	}
+
+	for _, rarity := range unionKeys(old.ReforgeCosts, next.ReforgeCosts) {
+		oldCost, hadOld := old.ReforgeCosts[rarity]
+		newCost, hasNew := next.ReforgeCosts[rarity]
+		if hadOld && hasNew && oldCost == newCost {
+			continue
+		}
+		changes = append(changes, models.BalanceChange{Rarity: rarity, Stat: "reforge_cost", Old: optional(oldCost, hadOld), New: optional(newCost, hasNew)})
+	}
+
+	if !sameJSON(normalizeField(old.ReforgeAbility), normalizeField(next.ReforgeAbility)) {
+		changes = append(changes, models.BalanceChange{Stat: "reforge_ability", Old: normalizeField(old.ReforgeAbility), New: normalizeField(next.ReforgeAbility)})
+	}
+	if old.ItemTypes != next.ItemTypes {
+		changes = append(changes, models.BalanceChange{Stat: "item_types", Old: old.ItemTypes, New: next.ItemTypes})
+	}
+	if !sameJSON(normalizeField(old.RequiredRarities), normalizeField(next.RequiredRarities)) {
+		changes = append(changes, models.BalanceChange{Stat: "required_rarities", Old: normalizeField(old.RequiredRarities), New: normalizeField(next.RequiredRarities)})
+	}
+	return changes
+}
+
+func unionKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func optional[V any](value V, ok bool) interface{} {
+	if !ok {
+		return nil
+	}
+	return value
+}
+
+// identifies the loaded neu data by the repository commit, falling back to a hash of the reforge files
+func (svc *Service) neuVersion() string {
+	repoPath := svc.settings().NEU.RepoPath
+	if commit := gitCommit(repoPath); commit != "" {
+		return commit
+	}
+
+	hash := sha256.New()
+	for _, name := range []string{"reforges.json", "reforgestones.json"} {
+		data, err := os.ReadFile(filepath.Join(repoPath, "constants", name))
+		if err == nil {
+			hash.Write(data)
+		}
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+// reads the checked out commit of a git repository or submodule without running git
+func gitCommit(repoPath string) string {
+	gitDir := filepath.Join(repoPath, ".git")
+	if data, err := os.ReadFile(gitDir); err == nil {
+		// submodules keep a file pointing at their git directory
+		dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+		if !ok {
+			return ""
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(repoPath, dir)
+		}
+		gitDir = dir
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return ""
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !ok {
+		return shortCommit(ref)
+	}
+	if data, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))); err == nil {
+		return shortCommit(strings.TrimSpace(string(data)))
+	}
+
+	packed, err := os.Open(filepath.Join(gitDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer packed.Close()
+	scanner := bufio.NewScanner(packed)
+	for scanner.Scan() {
+		if sha, name, ok := strings.Cut(scanner.Text(), " "); ok && name == ref {
+			return shortCommit(sha)
+		}
+	}
+	return ""
+}
+
+func shortCommit(sha string) string {
+	if len(sha) < 12 {
+		return ""
+	}
+	return sha[:12]
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// swaps in neu reforge data as if reforges.json had just been loaded
+func setNEUReforges(svc *Service, reforges map[string]interface{}) {
+	svc.neuDataMutex.Lock()
+	defer svc.neuDataMutex.Unlock()
+	svc.neuReforges = reforges
+	svc.neuReforgeStones = map[string]interface{}{}
+}
+
+func fierce(critDamage float64) map[string]interface{} {
+	return map[string]interface{}{
+		"Fierce": map[string]interface{}{
+			"reforgeStats": map[string]interface{}{
+				"LEGENDARY": map[string]interface{}{"crit_damage": critDamage, "strength": 6.0},
+			},
+		},
+	}
+}
+
+func TestRecordReforgeHistory_WhenStatChanges_RecordsStatLevelDiff(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.NEU.RepoPath = t.TempDir()
+	svc := New(cfg, storage.NewMemory())
+	setNEUReforges(svc, fierce(18))
+	require.NoError(t, svc.RecordReforgeHistory(ctx))
+	setNEUReforges(svc, fierce(20))
+
+	// Act
+	err := svc.RecordReforgeHistory(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	name, history, found, err := svc.ReforgeHistory(ctx, "fierce")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Fierce", name)
+	require.Len(t, history, 1)
+	assert.Equal(t, ReforgeChanged, history[0].Status)
+	assert.NotEmpty(t, history[0].Version)
+	assert.Equal(t, []models.BalanceChange{{Rarity: "LEGENDARY", Stat: "crit_damage", Old: 18.0, New: 20.0}}, history[0].Changes)
+}
+
+func TestRecordReforgeHistory_WhenNothingChanged_RecordsNothing(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.NEU.RepoPath = t.TempDir()
+	svc := New(cfg, storage.NewMemory())
+	setNEUReforges(svc, fierce(18))
+	require.NoError(t, svc.RecordReforgeHistory(ctx))
+
+	// Act
+	err := svc.RecordReforgeHistory(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	_, history, _, err := svc.ReforgeHistory(ctx, "Fierce")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestGitCommit_WhenSubmoduleCheckedOutOnBranch_ReadsCommitFromRef(t *testing.T) {
+	// Arrange
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	gitDir := filepath.Join(root, "modules", "repo")
+	require.NoError(t, os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0o755))
+	require.NoError(t, os.MkdirAll(repo, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, ".git"), []byte("gitdir: ../modules/repo\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/master\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(gitDir, "refs", "heads", "master"), []byte("0123456789abcdef0123456789abcdef01234567\n"), 0o644))
+
+	// Act
+	commit := gitCommit(repo)
+
+	// Assert
+	assert.Equal(t, "0123456789ab", commit)
+}
+
+func TestReforgeHistory_WhenNEURemovedReforge_StillServesItsHistory(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.NEU.RepoPath = t.TempDir()
+	svc := New(cfg, storage.NewMemory())
+	reforges := fierce(18)
+	reforges["Spicy"] = fierce(18)["Fierce"]
+	setNEUReforges(svc, reforges)
+	require.NoError(t, svc.RecordReforgeHistory(ctx))
+	setNEUReforges(svc, fierce(18))
+	require.NoError(t, svc.RecordReforgeHistory(ctx))
+
+	// Act
+	name, history, found, err := svc.ReforgeHistory(ctx, "SPICY")
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "Spicy", name)
+	require.Len(t, history, 1)
+	assert.Equal(t, ReforgeRemoved, history[0].Status)
+	_, _, found, err = svc.ReforgeHistory(ctx, "Nope")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestRecordReforgeHistory_WhenRetriedAfterSnapshotFailed_RecordsEachChangeOnce(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	cfg := config.Default()
+	cfg.NEU.RepoPath = t.TempDir()
+	svc := New(cfg, storage.NewMemory())
+	setNEUReforges(svc, fierce(18))
+	require.NoError(t, svc.RecordReforgeHistory(ctx))
+	setNEUReforges(svc, fierce(20))
+	fenceChecks := 0
+	svc.WriteFence = func(ctx context.Context) error {
+		fenceChecks++
+		// lets the history entries through and fails the snapshot that follows them
+		if fenceChecks > 1 {
+			return errors.New("deposed")
+		}
+		return nil
+	}
+	require.Error(t, svc.RecordReforgeHistory(ctx))
+	svc.WriteFence = nil
+
+	// Act
+	err := svc.RecordReforgeHistory(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	_, history, _, err := svc.ReforgeHistory(ctx, "Fierce")
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
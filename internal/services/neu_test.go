@@ -9,20 +9,15 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"yard-backend/internal/config"
+	"yard-backend/internal/storage"
 )
 
 func TestLoadNEUReforgeStones_WhenFileExists_LoadsData(t *testing.T) {
 	// Arrange
-	originalNEURepoPath := config.NEURepoPath
-	originalNEUReforgeStones := config.NEUReforgeStones
-	defer func() {
-		config.NEURepoPath = originalNEURepoPath
-		config.NEUReforgeStones = originalNEUReforgeStones
-	}()
-
 	tempDir := t.TempDir()
-	config.NEURepoPath = tempDir
-	config.NEUReforgeStones = make(map[string]interface{})
+	cfg := config.Default()
+	cfg.NEU.RepoPath = tempDir
+	svc := New(cfg, storage.NewMemory())
 
 	path := filepath.Join(tempDir, "constants")
 	require.NoError(t, os.MkdirAll(path, 0755))
@@ -37,23 +32,23 @@ func TestLoadNEUReforgeStones_WhenFileExists_LoadsData(t *testing.T) {
 	require.NoError(t, os.WriteFile(filepath.Join(path, "reforgestones.json"), jsonData, 0644))
 
 	// Act
-	err = LoadNEUReforgeStones()
+	err = svc.LoadNEUReforgeStones()
 
 	// Assert
 	assert.NoError(t, err)
-	assert.NotEmpty(t, config.NEUReforgeStones)
+	assert.NotNil(t, svc.GetReforgeEffectForStone("STONE1"))
 }
 
 func TestLoadNEUReforgeStones_WhenFileNotFound_ReturnsError(t *testing.T) {
 	// Arrange
-	originalNEURepoPath := config.NEURepoPath
-	defer func() { config.NEURepoPath = originalNEURepoPath }()
 
 	tempDir := t.TempDir()
-	config.NEURepoPath = tempDir
+	cfg := config.Default()
+	cfg.NEU.RepoPath = tempDir
+	svc := New(cfg, storage.NewMemory())
 
 	// Act
-	err := LoadNEUReforgeStones()
+	err := svc.LoadNEUReforgeStones()
 
 	// Assert
 	assert.Error(t, err)
@@ -61,10 +56,8 @@ func TestLoadNEUReforgeStones_WhenFileNotFound_ReturnsError(t *testing.T) {
 
 func TestGetReforgeEffectForStone_WhenStoneExists_ReturnsEffect(t *testing.T) {
 	// Arrange
-	originalNEUReforgeStones := config.NEUReforgeStones
-	defer func() { config.NEUReforgeStones = originalNEUReforgeStones }()
-
-	config.NEUReforgeStones = map[string]interface{}{
+	svc := New(config.Default(), storage.NewMemory())
+	svc.neuReforgeStones = map[string]interface{}{
 		"TEST_STONE": map[string]interface{}{
 			"reforgeName": "Test Reforge",
 			"itemTypes":   "SWORD",
@@ -72,7 +65,7 @@ func TestGetReforgeEffectForStone_WhenStoneExists_ReturnsEffect(t *testing.T) {
 	}
 
 	// Act
-	effect := GetReforgeEffectForStone("TEST_STONE")
+	effect := svc.GetReforgeEffectForStone("TEST_STONE")
 
 	// Assert
 	assert.NotNil(t, effect)
@@ -81,13 +74,11 @@ func TestGetReforgeEffectForStone_WhenStoneExists_ReturnsEffect(t *testing.T) {
 
 func TestGetReforgeEffectForStone_WhenStoneNotFound_ReturnsNil(t *testing.T) {
 	// Arrange
-	originalNEUReforgeStones := config.NEUReforgeStones
-	defer func() { config.NEUReforgeStones = originalNEUReforgeStones }()
-
-	config.NEUReforgeStones = make(map[string]interface{})
+	svc := New(config.Default(), storage.NewMemory())
+	svc.neuReforgeStones = make(map[string]interface{})
 
 	// Act
-	effect := GetReforgeEffectForStone("NONEXISTENT")
+	effect := svc.GetReforgeEffectForStone("NONEXISTENT")
 
 	// Assert
 	assert.Nil(t, effect)
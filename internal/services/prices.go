@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+)
+
+// history series holding the price points of one stone
+const pricesSeriesPrefix = "prices:"
+
+func pricePoint(stone models.Item, at time.Time) models.PricePoint {
+	return models.PricePoint{
+		At:              at,
+		AuctionPrice:    stone.AuctionPrice,
+		BazaarBuyPrice:  stone.BazaarBuyPrice,
+		BazaarSellPrice: stone.BazaarSellPrice,
+	}
+}
+
+// reports whether a refresh left the prices of a stone as they were
+func samePrices(a, b models.Item) bool {
+	return equalPtr(a.AuctionPrice, b.AuctionPrice) &&
+		equalPtr(a.BazaarBuyPrice, b.BazaarBuyPrice) &&
+		equalPtr(a.BazaarSellPrice, b.BazaarSellPrice)
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// appends a price point for every stone whose prices moved and drops the points past the retention
+// failures only cost history
+func (svc *Service) recordPrices(ctx context.Context, stones []models.Item, at time.Time) {
+	maxAge := svc.settings().Storage.PriceHistoryMaxAge
+	for _, stone := range stones {
+		data, err := json.Marshal(pricePoint(stone, at))
+		if err != nil {
+			log.Printf("Error encoding price point for %s: %v", stone.ID, err)
+			continue
+		}
+		series := pricesSeriesPrefix + stone.ID
+		if err := svc.store.AppendHistory(ctx, series, storage.HistoryEntry{At: at, Data: data}); err != nil {
+			log.Printf("Error recording price point for %s: %v", stone.ID, err)
+			continue
+		}
+		if maxAge > 0 {
+			if err := svc.store.TrimHistory(ctx, series, at.Add(-maxAge)); err != nil {
+				log.Printf("Error trimming price history of %s: %v", stone.ID, err)
+			}
+		}
+	}
+}
+
+// returns the price points of a stone recorded after since, newest first
+// a positive limit returns only the newest limit points
+func (svc *Service) PriceHistory(ctx context.Context, stoneID string, since time.Time, limit int) ([]models.PricePoint, error) {
+	entries, err := svc.store.History(ctx, pricesSeriesPrefix+stoneID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("loading price history: %w", err)
+	}
+
+	points := make([]models.PricePoint, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		var point models.PricePoint
+		if err := json.Unmarshal(entries[i].Data, &point); err != nil {
+			log.Printf("Skipping malformed price point for %s: %v", stoneID, err)
+			continue
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshPrices_WhenPriceMoves_RecordsPricePointsNewestFirst(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	bids := []int64{1000, 1000, 1500}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/auctions/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"startingBid": bids[0], "bin": true}})
+	}))
+	defer server.Close()
+
+	store := storage.NewMemory()
+	store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}}, 1)
+	cfg := config.Default()
+	cfg.Upstream.CoflnetURL = server.URL
+	cfg.Upstream.CoflnetMinDelay = 0
+	svc := New(cfg, store)
+
+	// Act
+	for range 3 {
+		svc.RefreshPrices(ctx)
+		bids = bids[1:]
+	}
+
+	// Assert
+	points, err := svc.PriceHistory(ctx, "AMBER", time.Time{}, 0)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, int64(1500), *points[0].AuctionPrice)
+	assert.Equal(t, int64(1000), *points[1].AuctionPrice)
+}
+
+func TestRefreshPrices_WhenPointsOutliveRetention_DropsThem(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/auctions/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"startingBid": 1200, "bin": true}})
+	}))
+	defer server.Close()
+
+	store := storage.NewMemory()
+	store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}}, 1)
+	store.AppendHistory(ctx, "prices:AMBER", storage.HistoryEntry{At: time.Now().Add(-48 * time.Hour), Data: []byte(`{"auction_price":900}`)})
+	store.AppendHistory(ctx, "prices:AMBER", storage.HistoryEntry{At: time.Now().Add(-time.Hour), Data: []byte(`{"auction_price":1000}`)})
+	cfg := config.Default()
+	cfg.Upstream.CoflnetURL = server.URL
+	cfg.Upstream.CoflnetMinDelay = 0
+	cfg.Storage.PriceHistoryMaxAge = 24 * time.Hour
+	svc := New(cfg, store)
+
+	// Act
+	svc.RefreshPrices(ctx)
+
+	// Assert
+	points, err := svc.PriceHistory(ctx, "AMBER", time.Time{}, 0)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, int64(1200), *points[0].AuctionPrice)
+	assert.Equal(t, int64(1000), *points[1].AuctionPrice)
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"yard-backend/internal/compress"
+	"yard-backend/internal/models"
+)
+
+// the read endpoints served straight from memory, rebuilt whenever the data behind them changes
+type ReadModel struct {
+	Version  DataVersion
+	BuiltAt  time.Time
+	Reforges []models.Reforge
+	Stones   []models.Item
+
+	ReforgesBody Body
+	StonesBody   Body
+	// the same data in the /api/v2 envelope
+	ReforgesV2Body Body
+	StonesV2Body   Body
+}
+
+// a json response encoded once, with a compressed copy for every supported content encoding
+type Body struct {
+	ETag         string
+	LastModified time.Time
+	JSON         []byte
+	Encoded      map[string][]byte
+}
+
+// returns the bytes to send for the encoding the client accepts, empty encoding means the plain json
+func (b Body) Negotiate(acceptEncoding string) ([]byte, string) {
+	encoding := compress.Negotiate(acceptEncoding)
+	if data, ok := b.Encoded[encoding]; ok {
+		return data, encoding
+	}
+	return b.JSON, ""
+}
+
+// returns the current read model, building it first when nothing has been built yet
+func (svc *Service) ReadModel(ctx context.Context) (*ReadModel, error) {
+	if model := svc.readModel.Load(); model != nil {
+		return model, nil
+	}
+	return svc.RebuildReadModel(ctx)
+}
+
+// rebuilds the read model from storage and the loaded neu files
+func (svc *Service) RebuildReadModel(ctx context.Context) (model *ReadModel, err error) {
+	ctx, span := startSpan(ctx, "services.RebuildReadModel")
+	defer func() { endSpan(span, err) }()
+
+	svc.readModelMutex.Lock()
+	defer svc.readModelMutex.Unlock()
+
+	// the version is read before the data so a change in between is caught by the next check
+	version, err := svc.DataVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stones, err := svc.store.AllStones(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading reforge stones: %w", err)
+	}
+
+	reforges := svc.GetAllReforges(ctx)
+	sort.Slice(reforges, func(i, j int) bool {
+		return reforges[i].ReforgeName < reforges[j].ReforgeName
+	})
+
+	model = &ReadModel{
+		Version:  version,
+		BuiltAt:  time.Now(),
+		Reforges: reforges,
+		Stones:   stones,
+	}
+
+	// stone prices in the response are as fresh as the last price refresh
+	// falling back to the data version keeps the body identical for an unchanged etag
+	lastUpdated := version.PricesUpdated
+	if lastUpdated.IsZero() {
+		lastUpdated = version.LastModified()
+	}
+	if lastUpdated.IsZero() {
+		lastUpdated = model.BuiltAt
+	}
+
+	model.StonesBody, err = encodeBody(ctx, version, "reforge-stones", models.ReforgeStonesResponse{
+		Success:       true,
+		Count:         len(stones),
+		LastUpdated:   version.PricesUpdated,
+		ReforgeStones: stones,
+	})
+	if err != nil {
+		return nil, err
+	}
+	model.ReforgesBody, err = encodeBody(ctx, version, "reforges", models.ReforgesResponse{
+		Success:     true,
+		Count:       len(reforges),
+		LastUpdated: lastUpdated,
+		Reforges:    reforges,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// v2 always sends lists, never null
+	if stones == nil {
+		stones = []models.Item{}
+	}
+	model.StonesV2Body, err = encodeBody(ctx, version, "v2/reforge-stones", models.Envelope{
+		Success: true,
+		Data:    stones,
+		Meta:    version.Meta(version.PricesUpdated),
+	})
+	if err != nil {
+		return nil, err
+	}
+	model.ReforgesV2Body, err = encodeBody(ctx, version, "v2/reforges", models.Envelope{
+		Success: true,
+		Data:    reforges,
+		Meta:    version.Meta(lastUpdated),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	svc.readModel.Store(model)
+	return model, nil
+}
+
+func encodeBody(ctx context.Context, version DataVersion, representation string, response interface{}) (body Body, err error) {
+	_, span := startSpan(ctx, "services.encodeBody", attribute.String("representation", representation))
+	defer func() {
+		span.SetAttributes(attribute.Int("body.bytes", len(body.JSON)))
+		endSpan(span, err)
+	}()
+
+	body = Body{
+		ETag:         version.ETag(representation),
+		LastModified: version.LastModified(),
+		Encoded:      make(map[string][]byte, len(compress.Encodings)),
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		return body, fmt.Errorf("encoding %s: %w", representation, err)
+	}
+	// same trailing newline json.Encoder wrote before responses were precomputed
+	body.JSON = append(data, '\n')
+
+	for _, encoding := range compress.Encodings {
+		if body.Encoded[encoding], err = compress.Bytes(encoding, body.JSON); err != nil {
+			return body, err
+		}
+	}
+	return body, nil
+}
+
+// rebuilds the read model after this instance changed the data, failures keep the previous model
+func (svc *Service) dataChanged(ctx context.Context) {
+	if _, err := svc.RebuildReadModel(ctx); err != nil {
+		log.Printf("Error rebuilding read model: %v", err)
+	}
+}
+
+// rebuilds the read model whenever storage holds a newer version than the one served
+// this is how replicas that don't run the scheduler pick up what the leader published
+func (svc *Service) WatchReadModel(ctx context.Context) {
+	for {
+		timer := time.NewTimer(svc.settings().Scheduler.ReadModelCheckInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		version, err := svc.DataVersion(ctx)
+		if err != nil {
+			log.Printf("Error checking data version: %v", err)
+			continue
+		}
+		if model := svc.readModel.Load(); model != nil && model.Version.ETag("") == version.ETag("") {
+			continue
+		}
+		svc.dataChanged(ctx)
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRebuildReadModel_WhenStonesStored_EncodesEveryVariant(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := storage.NewMemory()
+	store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}}, 1)
+	svc := New(config.Default(), store)
+
+	// Act
+	model, err := svc.RebuildReadModel(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, model.Stones, 1)
+	assert.Contains(t, string(model.StonesBody.JSON), `"AMBER"`)
+	assert.NotEmpty(t, model.StonesBody.Encoded["gzip"])
+	assert.NotEmpty(t, model.StonesBody.Encoded["zstd"])
+	assert.NotEqual(t, model.StonesBody.ETag, model.ReforgesBody.ETag)
+}
+
+func TestStoreReforgeStones_WhenPublished_RebuildsReadModel(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	svc := New(config.Default(), storage.NewMemory())
+	before, err := svc.ReadModel(ctx)
+	require.NoError(t, err)
+
+	// Act
+	err = svc.StoreReforgeStones(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}})
+
+	// Assert
+	require.NoError(t, err)
+	after, _ := svc.ReadModel(ctx)
+	assert.Empty(t, before.Stones)
+	assert.Len(t, after.Stones, 1)
+}
+
+func TestWatchReadModel_WhenAnotherInstancePublishes_RebuildsReadModel(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := storage.NewMemory()
+	cfg := config.Default()
+	cfg.Scheduler.ReadModelCheckInterval = 5 * time.Millisecond
+	svc := New(cfg, store)
+	_, err := svc.RebuildReadModel(ctx)
+	require.NoError(t, err)
+
+	// Act
+	go svc.WatchReadModel(ctx)
+	store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}}, 1)
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		model, _ := svc.ReadModel(ctx)
+		return len(model.Stones) == 1
+	}, time.Second, 5*time.Millisecond)
+}
@@ -1,29 +1,102 @@
 package services
 
 import (
+	"context"
 	"log"
+	"sync"
 	"time"
 )
 
-// starts the schedulers for hypixel data (5h) and coflnet prices (5m)
-func StartScheduler() {
-	// initial fetch of stone list from hypixel + prices
-	FetchAndStoreReforgeStones(false)
+// runs the background hypixel and price jobs and owns their tickers
+type Scheduler struct {
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	hypixelTicker *time.Ticker
+	priceTicker   *time.Ticker
+	stopOnce      sync.Once
+}
+
+// starts the schedulers for hypixel data and coflnet prices in the background
+// neu reforge history is recorded first, onWarm is called once the initial fetch finishes, cancelling ctx or calling stop aborts in flight work
+// a reloaded config re-arms the tickers of the running scheduler
+func (svc *Service) StartScheduler(ctx context.Context, onWarm func()) *Scheduler {
+	ctx, cancel := context.WithCancel(ctx)
 
-	// hypixel scheduler: check every hour but only fetch if > 5 hours old
-	hypixelTicker := time.NewTicker(1 * time.Hour)
+	// registered under the lock so a concurrent reload either sees this scheduler or its intervals
+	svc.schedulerMutex.Lock()
+	s := &Scheduler{
+		cancel: cancel,
+		// hypixel scheduler: check regularly but only fetch once the data is stale
+		hypixelTicker: time.NewTicker(svc.settings().Scheduler.HypixelCheckInterval),
+		// price scheduler: refresh prices on every tick
+		priceTicker: time.NewTicker(svc.settings().Scheduler.PriceInterval),
+	}
+	svc.scheduler = s
+	svc.schedulerMutex.Unlock()
+
+	s.wg.Add(1)
 	go func() {
-		for range hypixelTicker.C {
-			FetchAndStoreReforgeStones(false)
+		defer s.wg.Done()
+		defer func() {
+			svc.schedulerMutex.Lock()
+			if svc.scheduler == s {
+				svc.scheduler = nil
+			}
+			svc.schedulerMutex.Unlock()
+		}()
+
+		// recorded here rather than at startup so only the leader diffs neu, behind the write fence
+		if err := svc.RecordReforgeHistory(ctx); err != nil {
+			log.Printf("Warning: Failed to record NEU reforge history: %v", err)
+		}
+
+		// initial fetch of stone list from hypixel + prices
+		svc.FetchAndStoreReforgeStones(ctx, false)
+		if ctx.Err() == nil && onWarm != nil {
+			onWarm()
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.hypixelTicker.C:
+				svc.FetchAndStoreReforgeStones(ctx, false)
+			case <-s.priceTicker.C:
+				log.Println("Starting scheduled price refresh...")
+				svc.RefreshPrices(ctx)
+			}
 		}
 	}()
 
-	// price scheduler: refresh prices every 5 minutes
-	priceTicker := time.NewTicker(5 * time.Minute)
+	return s
+}
+
+// re-arms the tickers with new intervals, the next tick comes one full interval from now
+func (s *Scheduler) Reset(hypixelInterval, priceInterval time.Duration) {
+	s.hypixelTicker.Reset(hypixelInterval)
+	s.priceTicker.Reset(priceInterval)
+	log.Printf("Scheduler intervals updated: hypixel check every %v, prices every %v", hypixelInterval, priceInterval)
+}
+
+// stops the tickers, cancels any in flight refresh and waits for it to return or ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		s.hypixelTicker.Stop()
+		s.priceTicker.Stop()
+		s.cancel()
+	})
+
+	done := make(chan struct{})
 	go func() {
-		for range priceTicker.C {
-			log.Println("Starting scheduled price refresh...")
-			RefreshPrices()
-		}
+		s.wg.Wait()
+		close(done)
 	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartScheduler_WhenStopped_ReturnsPromptly(t *testing.T) {
+	// Arrange
+	warmed := make(chan struct{})
+	s := New(config.Default(), storage.NewMemory()).StartScheduler(context.Background(), func() { close(warmed) })
+	<-warmed
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Act
+	err := s.Stop(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+func TestServiceApply_WhenPriceIntervalShortened_RearmsRunningScheduler(t *testing.T) {
+	// Arrange
+	store := storage.NewMemory()
+	store.Publish(context.Background(), []models.Item{{ID: "STONE1", Name: "Stone 1"}}, 1)
+	store.SetTimestamp(context.Background(), storage.HypixelUpdated, time.Now())
+
+	var priceRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priceRequests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Upstream.CoflnetURL = server.URL
+	cfg.Upstream.CoflnetMinDelay = 0
+	svc := New(cfg, store)
+
+	warmed := make(chan struct{})
+	s := svc.StartScheduler(context.Background(), func() { close(warmed) })
+	defer s.Stop(context.Background())
+	<-warmed
+	afterWarmUp := priceRequests.Load()
+
+	// Act
+	reloaded := *cfg
+	reloaded.Scheduler.PriceInterval = 20 * time.Millisecond
+	svc.Apply(&reloaded)
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		return priceRequests.Load() > afterWarmUp
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+// a service with a fresh catalog and a price api answering 404, so the scheduler warms up without the network
+func newOfflineService(t *testing.T) (*Service, storage.Store) {
+	store := storage.NewMemory()
+	store.Publish(context.Background(), []models.Item{{ID: "STONE1", Name: "Stone 1"}}, 1)
+	store.SetTimestamp(context.Background(), storage.HypixelUpdated, time.Now())
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	cfg := config.Default()
+	cfg.NEU.RepoPath = t.TempDir()
+	cfg.Upstream.CoflnetURL = server.URL
+	cfg.Upstream.CoflnetMinDelay = 0
+	return New(cfg, store), store
+}
+
+func TestStartScheduler_WhenFenceRejects_RecordsNoReforgeHistory(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	svc, store := newOfflineService(t)
+	setNEUReforges(svc, fierce(18))
+	fenced := make(chan struct{}, 1)
+	svc.WriteFence = func(ctx context.Context) error {
+		select {
+		case fenced <- struct{}{}:
+		default:
+		}
+		return errors.New("deposed")
+	}
+
+	// Act
+	warmed := make(chan struct{})
+	s := svc.StartScheduler(ctx, func() { close(warmed) })
+	defer s.Stop(ctx)
+	<-warmed
+
+	// Assert
+	require.Len(t, fenced, 1, "the neu history write went through the fence")
+	snapshots, err := store.History(ctx, neuSnapshotSeries, time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Empty(t, snapshots)
+}
+
+func TestStartScheduler_WhenLeading_RecordsReforgeHistoryBeforeWarm(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	svc, store := newOfflineService(t)
+	setNEUReforges(svc, fierce(18))
+
+	// Act
+	warmed := make(chan struct{})
+	s := svc.StartScheduler(ctx, func() { close(warmed) })
+	defer s.Stop(ctx)
+	<-warmed
+
+	// Assert
+	snapshots, err := store.History(ctx, neuSnapshotSeries, time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Len(t, snapshots, 1)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/storage"
+	"yard-backend/internal/upstream"
+	"yard-backend/internal/utils"
+)
+
+// owns the settings, storage and upstream client used by every fetch refresh and neu lookup
+type Service struct {
+	cfg          atomic.Pointer[config.Config]
+	store        storage.Store
+	client       *upstream.Client
+	throttle     *utils.Throttle
+	refreshMutex sync.Mutex
+
+	schedulerMutex sync.Mutex
+	scheduler      *Scheduler
+
+	// a digest of the loaded neu files, part of the data version
+	neuMutex   sync.Mutex
+	neuDigests map[string][32]byte
+
+	// the parsed neu files, each replaced whole when it is loaded again
+	neuDataMutex     sync.RWMutex
+	neuReforgeStones map[string]interface{}
+	neuReforges      map[string]interface{}
+
+	// the read endpoints precomputed from the data above, swapped whole on every rebuild
+	readModel      atomic.Pointer[ReadModel]
+	readModelMutex sync.Mutex
+
+	// checked before writes so a deposed scheduler leader stops before clobbering the new leader's data
+	// the store itself rejects writes racing a lease change, nil allows every write, which is what a single instance wants
+	WriteFence func(ctx context.Context) error
+}
+
+// creates a service from the given config that keeps its data in store
+func New(cfg *config.Config, store storage.Store) *Service {
+	svc := &Service{
+		store: store,
+		client: upstream.NewClient(upstream.Options{
+			RequestTimeout:   cfg.Upstream.RequestTimeout,
+			FailureThreshold: cfg.Upstream.BreakerThreshold,
+			BreakerCooldown:  cfg.Upstream.BreakerCooldown,
+		}),
+		throttle: utils.NewThrottle(cfg.Upstream.CoflnetMinDelay),
+	}
+	svc.cfg.Store(cfg)
+	return svc
+}
+
+// returns the config currently in effect
+func (svc *Service) settings() *config.Config {
+	return svc.cfg.Load()
+}
+
+// switches to a reloaded config, re-arming the running scheduler when its intervals changed
+func (svc *Service) Apply(cfg *config.Config) {
+	previous := svc.cfg.Swap(cfg)
+	if previous.Scheduler.HypixelCheckInterval == cfg.Scheduler.HypixelCheckInterval &&
+		previous.Scheduler.PriceInterval == cfg.Scheduler.PriceInterval {
+		return
+	}
+
+	svc.schedulerMutex.Lock()
+	defer svc.schedulerMutex.Unlock()
+	if svc.scheduler != nil {
+		svc.scheduler.Reset(cfg.Scheduler.HypixelCheckInterval, cfg.Scheduler.PriceInterval)
+	}
+}
+
+// returns the store so handlers read the same data the service writes
+func (svc *Service) Store() storage.Store {
+	return svc.store
+}
+
+// returns the shared upstream client so handlers fetching textures go through the same breakers
+func (svc *Service) Client() *upstream.Client {
+	return svc.client
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+
+	"yard-backend/internal/models"
+)
+
+// stats shown with a percent sign in game
+var percentStats = map[string]bool{
+	"crit_chance":         true,
+	"crit_damage":         true,
+	"attack_speed":        true,
+	"bonus_attack_speed":  true,
+	"sea_creature_chance": true,
+	"ability_damage":      true,
+}
+
+// describes every stat a reforge can give, in the order of models.ReforgeStats
+func StatMetadata() []models.StatInfo {
+	t := reflect.TypeOf(models.ReforgeStats{})
+	stats := make([]models.StatInfo, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		key := statKey(t.Field(i))
+		words := strings.Split(key, "_")
+		for j, word := range words {
+			words[j] = strings.ToUpper(word[:1]) + word[1:]
+		}
+		stats = append(stats, models.StatInfo{Key: key, Name: strings.Join(words, " "), Percent: percentStats[key]})
+	}
+	return stats
+}
+
+// flattens the set fields of a reforgestats into their json names
+func StatValues(stats models.ReforgeStats) map[string]float64 {
+	values := make(map[string]float64)
+	v := reflect.ValueOf(stats)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.IsNil() {
+			continue
+		}
+		values[statKey(v.Type().Field(i))] = field.Elem().Float()
+	}
+	return values
+}
+
+func statKey(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
@@ -1,171 +1,290 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"strconv"
 	"time"
 
-	"yard-backend/internal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"yard-backend/internal/metrics"
 	"yard-backend/internal/models"
-
-	"github.com/redis/go-redis/v9"
+	"yard-backend/internal/storage"
 )
 
-// stores reforge stones metadata in redis (without prices)
-func StoreReforgeStones(reforgeStones []models.Item) error {
-	if config.RDB == nil {
-		return fmt.Errorf("redis client not initialized")
-	}
-
-	existingIDs, err := config.RDB.SMembers(config.Ctx, "reforge_stones:ids").Result()
-	if err != nil && err != redis.Nil {
+// publishes the stone list from hypixel as a new snapshot, keeping the last known prices
+// stones hypixel no longer lists are marked delisted and dropped once the grace period has passed
+func (svc *Service) StoreReforgeStones(ctx context.Context, reforgeStones []models.Item) error {
+	current, err := svc.store.AllStones(ctx)
+	if err != nil {
 		return err
 	}
 
-	existingMap := make(map[string]bool)
-	for _, id := range existingIDs {
-		existingMap[id] = true
+	existing := make(map[string]models.Item, len(current))
+	for _, stone := range current {
+		existing[stone.ID] = stone
 	}
 
+	next := make([]models.Item, 0, len(reforgeStones)+len(current))
+	listed := make(map[string]bool, len(reforgeStones))
 	newCount := 0
-	for i := range reforgeStones {
-		stone := &reforgeStones[i]
-
+	for _, stone := range reforgeStones {
 		// add reforge effect from NEU data
-		reforgeEffect := GetReforgeEffectForStone(stone.ID)
+		reforgeEffect := svc.GetReforgeEffectForStone(stone.ID)
 		if reforgeEffect != nil {
 			stone.ReforgeEffect = reforgeEffect
 		}
 
-		stoneJSON, err := json.Marshal(stone)
-		if err != nil {
-			log.Printf("Error marshaling stone %s: %v", stone.ID, err)
-			continue
+		if previous, ok := existing[stone.ID]; ok {
+			// hypixel has no prices, keep serving the old ones until the next price refresh
+			copyPrices(&stone, previous)
+		} else {
+			newCount++
+			log.Printf("New reforge stone found: %s (%s)", stone.Name, stone.ID)
 		}
 
-		key := fmt.Sprintf("reforge_stone:%s", stone.ID)
-		err = config.RDB.Set(config.Ctx, key, stoneJSON, 0).Err()
-		if err != nil {
-			log.Printf("Error storing stone %s: %v", stone.ID, err)
+		listed[stone.ID] = true
+		next = append(next, stone)
+	}
+
+	now := time.Now()
+	grace := svc.settings().Storage.DelistedGrace
+	delistedCount := 0
+	for _, stone := range current {
+		if listed[stone.ID] {
 			continue
 		}
-
-		if !existingMap[stone.ID] {
-			newCount++
-			log.Printf("New reforge stone found: %s (%s)", stone.Name, stone.ID)
+		if stone.DelistedAt == nil {
+			delistedAt := now
+			stone.DelistedAt = &delistedAt
+			log.Printf("Reforge stone delisted by Hypixel: %s (%s)", stone.Name, stone.ID)
+		} else if now.Sub(*stone.DelistedAt) >= grace {
+			log.Printf("Removing reforge stone delisted since %s: %s (%s)", stone.DelistedAt.Format(time.RFC3339), stone.Name, stone.ID)
+			continue
 		}
+		delistedCount++
+		next = append(next, stone)
+	}
+
+	// the first fetch has nothing to compare against, listing every stone as added is not a patch note
+	changes := diffCatalog(current, reforgeStones, now)
+
+	version, err := svc.store.Publish(ctx, next, svc.settings().Storage.KeepVersions)
+	if err != nil {
+		return fmt.Errorf("publishing reforge stones: %w", err)
+	}
 
-		config.RDB.SAdd(config.Ctx, "reforge_stones:ids", stone.ID)
+	if len(current) > 0 && !isEmptyChangeSet(changes) {
+		svc.recordChanges(ctx, changes)
 	}
 
 	if newCount > 0 {
-		log.Printf("Stored %d new reforge stones (total: %d)", newCount, len(reforgeStones))
+		log.Printf("Stored %d new reforge stones (total: %d, delisted: %d, version %d)", newCount, len(reforgeStones), delistedCount, version.ID)
 	} else {
-		log.Printf("No new reforge stones found (total: %d)", len(reforgeStones))
+		log.Printf("No new reforge stones found (total: %d, delisted: %d, version %d)", len(reforgeStones), delistedCount, version.ID)
 	}
 
 	// track when hypixel data was last fetched
-	currentTime := time.Now().UnixMilli()
-	config.RDB.Set(config.Ctx, "reforge_stones:hypixel_updated", currentTime, 0)
-	config.RDB.Set(config.Ctx, "reforge_stones:count", len(reforgeStones), 0)
-
+	if err := svc.store.SetTimestamp(ctx, storage.HypixelUpdated, now); err != nil {
+		return err
+	}
+	svc.dataChanged(ctx)
 	return nil
 }
 
+// carries prices and order books over from an earlier copy of the same stone
+func copyPrices(stone *models.Item, from models.Item) {
+	stone.AuctionPrice = from.AuctionPrice
+	stone.BazaarBuyPrice = from.BazaarBuyPrice
+	stone.BazaarSellPrice = from.BazaarSellPrice
+	stone.BazaarBuyOrders = from.BazaarBuyOrders
+	stone.BazaarSellOrders = from.BazaarSellOrders
+}
+
+// returns an error when this instance is no longer allowed to write
+func (svc *Service) checkWriteFence(ctx context.Context) error {
+	if svc.WriteFence == nil {
+		return nil
+	}
+	return svc.WriteFence(ctx)
+}
+
+// receives progress updates from long running refreshes, used by admin jobs
+type Progress interface {
+	SetTotal(total int)
+	Advance()
+	Fail(item string, err error)
+}
+
+// discards progress updates for scheduled runs
+type noProgress struct{}
+
+func (noProgress) SetTotal(int)       {}
+func (noProgress) Advance()           {}
+func (noProgress) Fail(string, error) {}
+
 // refreshes prices from coflnet for all cached stones
-func RefreshPrices() {
-	if config.RDB == nil {
-		log.Println("Redis not initialized, skipping price refresh")
-		return
+// stops early and keeps what was already written when the context is cancelled
+func (svc *Service) RefreshPrices(ctx context.Context) {
+	if err := svc.RefreshPricesWithProgress(ctx, nil, noProgress{}); err != nil {
+		log.Printf("Price refresh failed: %v", err)
 	}
+}
 
-	ids, err := config.RDB.SMembers(config.Ctx, "reforge_stones:ids").Result()
-	if err != nil || len(ids) == 0 {
-		log.Println("No stones cached, skipping price refresh")
-		return
+// refreshes prices for the given stones, or every cached stone when ids is empty, reporting progress as it goes
+func (svc *Service) RefreshPricesWithProgress(ctx context.Context, ids []string, progress Progress) error {
+	svc.refreshMutex.Lock()
+	defer svc.refreshMutex.Unlock()
+	return svc.refreshPrices(ctx, ids, progress)
+}
+
+// prices are collected against the current snapshot and published as one new version at the end
+// so readers never see a half refreshed catalog, nothing is published when the refresh is cut short
+func (svc *Service) refreshPrices(ctx context.Context, ids []string, progress Progress) (err error) {
+	ctx, span := startSpan(ctx, "services.RefreshPrices", attribute.Int("stones.requested", len(ids)))
+	startTime := time.Now()
+	defer func() {
+		metrics.ObservePriceRefresh(err != nil, time.Since(startTime))
+		endSpan(span, err)
+	}()
+
+	current, err := svc.store.AllStones(ctx)
+	if err != nil {
+		return fmt.Errorf("loading cached stones: %w", err)
+	}
+
+	index := make(map[string]int, len(current))
+	for i, stone := range current {
+		index[stone.ID] = i
 	}
 
+	refreshAll := len(ids) == 0
+	if refreshAll {
+		// delisted stones keep their last prices, they are no longer traded
+		for _, stone := range current {
+			if stone.DelistedAt == nil {
+				ids = append(ids, stone.ID)
+			}
+		}
+		if len(ids) == 0 {
+			log.Println("No stones cached, skipping price refresh")
+			return nil
+		}
+	}
+
+	progress.SetTotal(len(ids))
 	log.Printf("Refreshing prices for %d stones from Coflnet...", len(ids))
-	startTime := time.Now()
 	updatedCount := 0
+	var moved []models.Item
 
 	for _, stoneID := range ids {
-		key := fmt.Sprintf("reforge_stone:%s", stoneID)
-		stoneJSON, err := config.RDB.Get(config.Ctx, key).Result()
-		if err != nil {
-			continue
+		if ctx.Err() != nil {
+			log.Printf("Price refresh cancelled after %d/%d stones: %v", updatedCount, len(ids), ctx.Err())
+			return ctx.Err()
 		}
 
-		var stone models.Item
-		if err := json.Unmarshal([]byte(stoneJSON), &stone); err != nil {
+		i, ok := index[stoneID]
+		if !ok {
+			progress.Fail(stoneID, storage.ErrNotFound)
 			continue
 		}
+		stone := svc.fetchStonePrices(ctx, current[i])
 
-		// fetch fresh prices from coflnet
-		auctionPrice := FetchAuctionPrice(stone.ID)
-		if auctionPrice != nil {
-			stone.AuctionPrice = auctionPrice
+		if err := svc.checkWriteFence(ctx); err != nil {
+			log.Printf("Price refresh aborted after %d/%d stones: %v", updatedCount, len(ids), err)
+			return err
 		}
 
-		buyPrice, sellPrice, buyOrders, sellOrders := FetchBazaarPrice(stone.ID)
-		if buyPrice != nil {
-			stone.BazaarBuyPrice = buyPrice
-		}
-		if sellPrice != nil {
-			stone.BazaarSellPrice = sellPrice
-		}
-		if len(buyOrders) > 0 {
-			stone.BazaarBuyOrders = buyOrders
-		}
-		if len(sellOrders) > 0 {
-			stone.BazaarSellOrders = sellOrders
+		if !samePrices(current[i], stone) {
+			moved = append(moved, stone)
 		}
+		current[i] = stone
+		updatedCount++
+		progress.Advance()
+	}
 
-		// save updated stone back to redis
-		updatedJSON, err := json.Marshal(stone)
-		if err != nil {
-			continue
+	span.SetAttributes(attribute.Int("stones.updated", updatedCount), attribute.Int("stones.moved", len(moved)))
+	if err := svc.checkWriteFence(ctx); err != nil {
+		log.Printf("Price refresh finished but not publishing: %v", err)
+		return err
+	}
+	if updatedCount > 0 {
+		// save updated stones back to storage as the next snapshot
+		if _, err := svc.store.Publish(ctx, current, svc.settings().Storage.KeepVersions); err != nil {
+			return fmt.Errorf("publishing prices: %w", err)
 		}
+		svc.recordPrices(ctx, moved, time.Now())
+	}
 
-		if err := config.RDB.Set(config.Ctx, key, updatedJSON, 0).Err(); err != nil {
-			continue
+	elapsed := time.Since(startTime)
+	if refreshAll {
+		if err := svc.store.SetTimestamp(ctx, storage.PricesUpdated, time.Now()); err != nil {
+			return fmt.Errorf("recording price refresh: %w", err)
 		}
+	}
+	log.Printf("Price refresh complete: %d/%d stones updated in %v", updatedCount, len(ids), elapsed.Round(time.Second))
+	svc.dataChanged(ctx)
+	return nil
+}
 
-		updatedCount++
+// returns stone with fresh prices from coflnet, prices coflnet has no answer for keep their last value
+func (svc *Service) fetchStonePrices(ctx context.Context, stone models.Item) models.Item {
+	ctx, span := startSpan(ctx, "services.RefreshPrices.stone", attribute.String("stone.id", stone.ID))
+	defer span.End()
+
+	auctionPrice := svc.FetchAuctionPrice(ctx, stone.ID)
+	if auctionPrice != nil {
+		stone.AuctionPrice = auctionPrice
 	}
 
-	elapsed := time.Since(startTime)
-	config.RDB.Set(config.Ctx, "reforge_stones:prices_updated", time.Now().UnixMilli(), 0)
-	log.Printf("Price refresh complete: %d/%d stones updated in %v", updatedCount, len(ids), elapsed.Round(time.Second))
+	buyPrice, sellPrice, buyOrders, sellOrders := svc.FetchBazaarPrice(ctx, stone.ID)
+	if buyPrice != nil {
+		stone.BazaarBuyPrice = buyPrice
+	}
+	if sellPrice != nil {
+		stone.BazaarSellPrice = sellPrice
+	}
+	if len(buyOrders) > 0 {
+		stone.BazaarBuyOrders = buyOrders
+	}
+	if len(sellOrders) > 0 {
+		stone.BazaarSellOrders = sellOrders
+	}
+
+	span.SetAttributes(
+		attribute.Bool("price.auction_found", auctionPrice != nil),
+		attribute.Bool("price.bazaar_found", buyPrice != nil || sellPrice != nil),
+	)
+	return stone
 }
 
-// fetches reforge stone list from hypixel api (runs every 5 hours)
-func FetchAndStoreReforgeStones(force bool) {
-	if config.RDB == nil {
-		log.Println("Redis not initialized, skipping fetch")
-		return
+// fetches reforge stone list from hypixel api once the cached list is stale
+func (svc *Service) FetchAndStoreReforgeStones(ctx context.Context, force bool) {
+	if err := svc.FetchAndStoreReforgeStonesWithProgress(ctx, force, noProgress{}); err != nil {
+		log.Printf("Hypixel fetch failed: %v", err)
 	}
+}
+
+// fetches and stores the stone list then refreshes prices, reporting price progress as it goes
+func (svc *Service) FetchAndStoreReforgeStonesWithProgress(ctx context.Context, force bool, progress Progress) error {
+	svc.refreshMutex.Lock()
+	defer svc.refreshMutex.Unlock()
 
 	if !force {
-		lastUpdatedStr, err := config.RDB.Get(config.Ctx, "reforge_stones:hypixel_updated").Result()
-		if err == nil && lastUpdatedStr != "" {
-			if timestamp, err := strconv.ParseInt(lastUpdatedStr, 10, 64); err == nil {
-				lastUpdatedTime := time.Unix(timestamp/1000, 0)
-				timeSinceUpdate := time.Since(lastUpdatedTime)
-
-				// hypixel data only needs refresh every 5 hours
-				if timeSinceUpdate < 5*time.Hour {
-					ids, _ := config.RDB.SMembers(config.Ctx, "reforge_stones:ids").Result()
-					if len(ids) > 0 {
-						log.Printf("Hypixel data is fresh (updated %v ago, %d stones cached). Skipping fetch.",
-							timeSinceUpdate.Round(time.Minute), len(ids))
-						return
-					}
-				} else {
-					log.Printf("Hypixel data is stale (updated %v ago). Fetching new stone list...", timeSinceUpdate.Round(time.Hour))
+		lastUpdated, err := svc.store.Timestamp(ctx, storage.HypixelUpdated)
+		if err == nil && !lastUpdated.IsZero() {
+			timeSinceUpdate := time.Since(lastUpdated)
+
+			// hypixel data only needs refresh once it is older than the configured staleness
+			if timeSinceUpdate < svc.settings().Scheduler.HypixelStaleAfter {
+				ids, _ := svc.store.StoneIDs(ctx)
+				if len(ids) > 0 {
+					log.Printf("Hypixel data is fresh (updated %v ago, %d stones cached). Skipping fetch.",
+						timeSinceUpdate.Round(time.Minute), len(ids))
+					return nil
 				}
+			} else {
+				log.Printf("Hypixel data is stale (updated %v ago). Fetching new stone list...", timeSinceUpdate.Round(time.Hour))
 			}
 		} else {
 			log.Println("No existing data found. Fetching initial data from Hypixel...")
@@ -175,18 +294,59 @@ func FetchAndStoreReforgeStones(force bool) {
 	}
 
 	log.Println("Fetching reforge stones from Hypixel API...")
-	reforgeStones, _, err := FetchReforgeStones()
+	reforgeStones, _, err := svc.FetchReforgeStones(ctx)
 	if err != nil {
 		log.Printf("Error fetching reforge stones: %v", err)
-		return
+		return fmt.Errorf("fetching reforge stones: %w", err)
+	}
+
+	if err := svc.checkWriteFence(ctx); err != nil {
+		log.Printf("Skipping store of reforge stones: %v", err)
+		return err
 	}
 
-	if err := StoreReforgeStones(reforgeStones); err != nil {
+	if err := svc.StoreReforgeStones(ctx, reforgeStones); err != nil {
 		log.Printf("Error storing reforge stones: %v", err)
-		return
+		return fmt.Errorf("storing reforge stones: %w", err)
 	}
 
 	log.Printf("Successfully stored %d reforge stones from Hypixel", len(reforgeStones))
 
-	RefreshPrices()
+	return svc.refreshPrices(ctx, nil, progress)
+}
+
+// deletes every cached stone and timestamp so the next fetch starts from scratch
+func (svc *Service) PurgeCache(ctx context.Context) (int, error) {
+	svc.refreshMutex.Lock()
+	defer svc.refreshMutex.Unlock()
+
+	if err := svc.checkWriteFence(ctx); err != nil {
+		return 0, err
+	}
+
+	purged, err := svc.store.Purge(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Printf("Purged %d cached reforge stones", purged)
+	svc.dataChanged(ctx)
+	return purged, nil
+}
+
+// points readers back at an earlier snapshot, the next fetch or price refresh publishes on top of it
+func (svc *Service) Rollback(ctx context.Context, version int64) error {
+	svc.refreshMutex.Lock()
+	defer svc.refreshMutex.Unlock()
+
+	if err := svc.checkWriteFence(ctx); err != nil {
+		return err
+	}
+	if err := svc.store.Rollback(ctx, version); err != nil {
+		return err
+	}
+
+	log.Printf("Rolled back to snapshot version %d", version)
+	svc.dataChanged(ctx)
+	return nil
 }
@@ -1,24 +1,103 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"yard-backend/internal/config"
 	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestStoreReforgeStones_WhenRedisNotInitialized_ReturnsError(t *testing.T) {
+func TestStoreReforgeStones_WhenStonesGiven_StoresThemAndRecordsFetchTime(t *testing.T) {
 	// Arrange
-	originalRDB := config.RDB
-	config.RDB = nil
-	defer func() { config.RDB = originalRDB }()
+	store := storage.NewMemory()
+	svc := New(config.Default(), store)
 
 	// Act
-	err := StoreReforgeStones([]models.Item{})
+	err := svc.StoreReforgeStones(context.Background(), []models.Item{{ID: "STONE1", Name: "Stone 1"}})
 
 	// Assert
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "redis client not initialized")
+	assert.NoError(t, err)
+	stone, err := store.GetStone(context.Background(), "STONE1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Stone 1", stone.Name)
+	updated, _ := store.Timestamp(context.Background(), storage.HypixelUpdated)
+	assert.False(t, updated.IsZero())
+}
+
+func TestStoreReforgeStones_WhenStoneMissingFromHypixel_MarksItDelistedAndKeepsPrices(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := storage.NewMemory()
+	price := int64(1000)
+	store.Publish(ctx, []models.Item{
+		{ID: "STONE1", Name: "Stone 1", AuctionPrice: &price},
+		{ID: "STONE2", Name: "Stone 2"},
+	}, 1)
+	svc := New(config.Default(), store)
+
+	// Act
+	err := svc.StoreReforgeStones(ctx, []models.Item{{ID: "STONE1", Name: "Stone 1"}})
+
+	// Assert
+	assert.NoError(t, err)
+	kept, _ := store.GetStone(ctx, "STONE1")
+	assert.Equal(t, &price, kept.AuctionPrice)
+	assert.Nil(t, kept.DelistedAt)
+	delisted, err := store.GetStone(ctx, "STONE2")
+	assert.NoError(t, err)
+	assert.NotNil(t, delisted.DelistedAt)
+}
+
+func TestStoreReforgeStones_WhenDelistedPastGrace_RemovesStone(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store := storage.NewMemory()
+	delistedAt := time.Now().Add(-2 * time.Hour)
+	store.Publish(ctx, []models.Item{{ID: "STONE2", Name: "Stone 2", DelistedAt: &delistedAt}}, 1)
+	cfg := config.Default()
+	cfg.Storage.DelistedGrace = time.Hour
+	svc := New(cfg, store)
+
+	// Act
+	err := svc.StoreReforgeStones(ctx, []models.Item{{ID: "STONE1", Name: "Stone 1"}})
+
+	// Assert
+	assert.NoError(t, err)
+	_, err = store.GetStone(ctx, "STONE2")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	versions, _ := store.Versions(ctx)
+	assert.Len(t, versions, 2)
+}
+
+func TestRefreshPrices_WhenWriteFenceRejects_DoesNotWrite(t *testing.T) {
+	// Arrange
+	store := storage.NewMemory()
+	store.Publish(context.Background(), []models.Item{{ID: "STONE1", Name: "Stone 1"}}, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	cfg := config.Default()
+	cfg.Upstream.CoflnetURL = server.URL
+	svc := New(cfg, store)
+
+	svc.WriteFence = func(ctx context.Context) error { return errors.New("deposed") }
+
+	// Act
+	svc.RefreshPrices(context.Background())
+
+	// Assert
+	updated, _ := store.Timestamp(context.Background(), storage.PricesUpdated)
+	assert.True(t, updated.IsZero())
+	versions, _ := store.Versions(context.Background())
+	assert.Len(t, versions, 1)
 }
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+	"yard-backend/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRefreshPrices_WhenTraced_StartsSpanPerStoneUnderTheRefresh(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Path, "/auctions/") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{{"startingBid": 1000, "bin": true}})
+	}))
+	defer server.Close()
+
+	store := storage.NewMemory()
+	store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}, {ID: "JADERALD", Name: "Jaderald"}}, 1)
+	cfg := config.Default()
+	cfg.Upstream.CoflnetURL = server.URL
+	cfg.Upstream.CoflnetMinDelay = 0
+	svc := New(cfg, store)
+
+	// Act
+	svc.RefreshPrices(ctx)
+
+	// Assert
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		byName[span.Name()] = append(byName[span.Name()], span)
+	}
+	require.Len(t, byName["services.RefreshPrices"], 1)
+	refresh := byName["services.RefreshPrices"][0]
+	stones := byName["services.RefreshPrices.stone"]
+	require.Len(t, stones, 2)
+	for i, id := range []string{"AMBER", "JADERALD"} {
+		assert.Equal(t, refresh.SpanContext().SpanID(), stones[i].Parent().SpanID())
+		assert.Contains(t, stones[i].Attributes(), attribute.String("stone.id", id))
+		assert.Contains(t, stones[i].Attributes(), attribute.Bool("price.auction_found", true))
+	}
+
+	// every upstream fetch hangs off the span of the stone it was made for
+	fetchesPerStone := map[string]int{}
+	for _, stone := range stones {
+		fetchesPerStone[stone.SpanContext().SpanID().String()] = 0
+	}
+	for name, spans := range byName {
+		if !strings.HasPrefix(name, "GET ") {
+			continue
+		}
+		for _, span := range spans {
+			parent := span.Parent().SpanID().String()
+			require.Contains(t, fetchesPerStone, parent)
+			fetchesPerStone[parent]++
+		}
+	}
+	for _, fetches := range fetchesPerStone {
+		assert.GreaterOrEqual(t, fetches, 2, "auction and bazaar are fetched for each stone")
+	}
+}
@@ -0,0 +1,419 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"yard-backend/internal/models"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// one nested bucket of stones per version keyed by the big endian version id
+	boltVersionsBucket = []byte("versions")
+	// version id to json version metadata, plus the current pointer
+	boltSnapshotsBucket  = []byte("snapshots")
+	boltCurrentKey       = []byte("current")
+	boltTimestampsBucket = []byte("timestamps")
+	boltHistoryBucket    = []byte("history")
+
+	// files written before versioning kept a single stones bucket
+	boltLegacyStonesBucket = []byte("stones")
+)
+
+type boltVersion struct {
+	PublishedAt time.Time `json:"publishedAt"`
+	Stones      int       `json:"stones"`
+}
+
+// keeps everything in a single local bolt file for single binary deployments
+// the file is locked while open so only one process can use it
+type Bolt struct {
+	db *bolt.DB
+}
+
+// opens or creates the database file at path
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening bolt database %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltVersionsBucket, boltSnapshotsBucket, boltTimestampsBucket, boltHistoryBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return migrateBoltStones(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("preparing bolt database %s: %w", path, err)
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// moves the stones bucket of older files into the first version
+func migrateBoltStones(tx *bolt.Tx) error {
+	legacy := tx.Bucket(boltLegacyStonesBucket)
+	if legacy == nil {
+		return nil
+	}
+
+	var stones []models.Item
+	err := legacy.ForEach(func(k, v []byte) error {
+		var stone models.Item
+		if err := json.Unmarshal(v, &stone); err != nil {
+			log.Printf("Dropping undecodable stone %s during migration: %v", k, err)
+			return nil
+		}
+		stones = append(stones, stone)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(stones) > 0 {
+		if _, err := publishBolt(tx, stones, 1); err != nil {
+			return err
+		}
+		log.Printf("Migrated %d stones into a versioned snapshot", len(stones))
+	}
+	return tx.DeleteBucket(boltLegacyStonesBucket)
+}
+
+// returns the stones bucket of the current version, nil when nothing was published
+func currentBoltStones(tx *bolt.Tx) *bolt.Bucket {
+	current := tx.Bucket(boltSnapshotsBucket).Get(boltCurrentKey)
+	if current == nil {
+		return nil
+	}
+	return tx.Bucket(boltVersionsBucket).Bucket(current)
+}
+
+func (s *Bolt) StoneIDs(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := currentBoltStones(tx)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	return ids, err
+}
+
+func (s *Bolt) HasStone(ctx context.Context, id string) (bool, error) {
+	var ok bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := currentBoltStones(tx)
+		ok = bucket != nil && bucket.Get([]byte(id)) != nil
+		return nil
+	})
+	return ok, err
+}
+
+func (s *Bolt) GetStone(ctx context.Context, id string) (models.Item, error) {
+	var stone models.Item
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := currentBoltStones(tx)
+		if bucket == nil {
+			return ErrNotFound
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(data, &stone); err != nil {
+			return fmt.Errorf("decoding stone %s: %w", id, err)
+		}
+		return nil
+	})
+	return stone, err
+}
+
+func (s *Bolt) GetStones(ctx context.Context, ids []string) ([]models.Item, error) {
+	stones := make([]models.Item, 0, len(ids))
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := currentBoltStones(tx)
+		if bucket == nil {
+			return nil
+		}
+		for _, id := range ids {
+			data := bucket.Get([]byte(id))
+			if data == nil {
+				continue
+			}
+			var stone models.Item
+			if err := json.Unmarshal(data, &stone); err != nil {
+				log.Printf("Error decoding stone %s: %v", id, err)
+				continue
+			}
+			stones = append(stones, stone)
+		}
+		return nil
+	})
+	return stones, err
+}
+
+func (s *Bolt) AllStones(ctx context.Context) ([]models.Item, error) {
+	stones := []models.Item{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := currentBoltStones(tx)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var stone models.Item
+			if err := json.Unmarshal(v, &stone); err != nil {
+				log.Printf("Error decoding stone %s: %v", k, err)
+				return nil
+			}
+			stones = append(stones, stone)
+			return nil
+		})
+	})
+	return stones, err
+}
+
+// bolt transactions are atomic so the new version and the pointer switch land together
+func (s *Bolt) Publish(ctx context.Context, stones []models.Item, keep int) (Version, error) {
+	var version Version
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		version, err = publishBolt(tx, stones, keep)
+		return err
+	})
+	return version, err
+}
+
+func publishBolt(tx *bolt.Tx, stones []models.Item, keep int) (Version, error) {
+	versions := tx.Bucket(boltVersionsBucket)
+	snapshots := tx.Bucket(boltSnapshotsBucket)
+
+	id, err := versions.NextSequence()
+	if err != nil {
+		return Version{}, err
+	}
+	key := encodeID(int64(id))
+	bucket, err := versions.CreateBucket(key)
+	if err != nil {
+		return Version{}, err
+	}
+	written := make(map[string]bool, len(stones))
+	for _, stone := range stones {
+		data, err := json.Marshal(stone)
+		if err != nil {
+			return Version{}, fmt.Errorf("encoding stone %s: %w", stone.ID, err)
+		}
+		if err := bucket.Put([]byte(stone.ID), data); err != nil {
+			return Version{}, err
+		}
+		written[stone.ID] = true
+	}
+
+	meta := boltVersion{PublishedAt: time.UnixMilli(time.Now().UnixMilli()), Stones: len(written)}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return Version{}, err
+	}
+	if err := snapshots.Put(key, data); err != nil {
+		return Version{}, err
+	}
+	if err := snapshots.Put(boltCurrentKey, key); err != nil {
+		return Version{}, err
+	}
+
+	var ids []int64
+	cursor := versions.Cursor()
+	for k, _ := cursor.Last(); k != nil; k, _ = cursor.Prev() {
+		ids = append(ids, decodeID(k))
+	}
+	for _, old := range prunable(ids, int64(id), keep) {
+		if err := versions.DeleteBucket(encodeID(old)); err != nil {
+			return Version{}, err
+		}
+		if err := snapshots.Delete(encodeID(old)); err != nil {
+			return Version{}, err
+		}
+	}
+
+	return Version{ID: int64(id), PublishedAt: meta.PublishedAt, Stones: meta.Stones, Current: true}, nil
+}
+
+func (s *Bolt) Versions(ctx context.Context) ([]Version, error) {
+	var versions []Version
+	err := s.db.View(func(tx *bolt.Tx) error {
+		snapshots := tx.Bucket(boltSnapshotsBucket)
+		current := snapshots.Get(boltCurrentKey)
+		cursor := snapshots.Cursor()
+		for k, v := cursor.Last(); k != nil; k, v = cursor.Prev() {
+			if len(k) != 8 {
+				continue
+			}
+			var meta boltVersion
+			if err := json.Unmarshal(v, &meta); err != nil {
+				return fmt.Errorf("decoding version %d: %w", decodeID(k), err)
+			}
+			versions = append(versions, Version{
+				ID:          decodeID(k),
+				PublishedAt: meta.PublishedAt,
+				Stones:      meta.Stones,
+				Current:     string(k) == string(current),
+			})
+		}
+		return nil
+	})
+	return versions, err
+}
+
+func (s *Bolt) Rollback(ctx context.Context, id int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := encodeID(id)
+		if tx.Bucket(boltVersionsBucket).Bucket(key) == nil {
+			return ErrNotFound
+		}
+		return tx.Bucket(boltSnapshotsBucket).Put(boltCurrentKey, key)
+	})
+}
+
+func (s *Bolt) Timestamp(ctx context.Context, name string) (time.Time, error) {
+	var t time.Time
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(boltTimestampsBucket).Get([]byte(name))
+		if len(data) == 8 {
+			t = time.UnixMilli(int64(binary.BigEndian.Uint64(data)))
+		}
+		return nil
+	})
+	return t, err
+}
+
+func (s *Bolt) SetTimestamp(ctx context.Context, name string, t time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltTimestampsBucket).Put([]byte(name), encodeMillis(t))
+	})
+}
+
+// each series is a nested bucket keyed by time then a sequence number so equal times keep their order
+func (s *Bolt) AppendHistory(ctx context.Context, series string, entry HistoryEntry) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(boltHistoryBucket).CreateBucketIfNotExists([]byte(series))
+		if err != nil {
+			return err
+		}
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := append(encodeMillis(entry.At), make([]byte, 8)...)
+		binary.BigEndian.PutUint64(key[8:], seq)
+		return bucket.Put(key, entry.Data)
+	})
+}
+
+func (s *Bolt) History(ctx context.Context, series string, since time.Time, limit int) ([]HistoryEntry, error) {
+	var entries []HistoryEntry
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltHistoryBucket).Bucket([]byte(series))
+		if bucket == nil {
+			return nil
+		}
+
+		// walk back from the newest key so a limit never reads older entries
+		// keys of the since millisecond and before are excluded, times before 1970 read to the start
+		cutoff := encodeMillis(time.UnixMilli(max(since.UnixMilli()+1, 0)))
+		cursor := bucket.Cursor()
+		for k, v := cursor.Last(); k != nil && bytes.Compare(k, cutoff) >= 0; k, v = cursor.Prev() {
+			if limit > 0 && len(entries) == limit {
+				break
+			}
+			entries = append(entries, HistoryEntry{
+				At:   time.UnixMilli(int64(binary.BigEndian.Uint64(k[:8]))),
+				Data: append([]byte(nil), v...),
+			})
+		}
+		return nil
+	})
+	slices.Reverse(entries)
+	return entries, err
+}
+
+func (s *Bolt) TrimHistory(ctx context.Context, series string, before time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltHistoryBucket).Bucket([]byte(series))
+		if bucket == nil {
+			return nil
+		}
+		cutoff := encodeMillis(time.UnixMilli(max(before.UnixMilli(), 0)))
+		cursor := bucket.Cursor()
+		// deleting through the cursor moves it onto the next key
+		for k, _ := cursor.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = cursor.First() {
+			if err := cursor.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Bolt) Purge(ctx context.Context) (int, error) {
+	var purged int
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if bucket := currentBoltStones(tx); bucket != nil {
+			purged = bucket.Stats().KeyN
+		}
+
+		// keep the version sequence so ids are never reused after a purge
+		versions := tx.Bucket(boltVersionsBucket)
+		sequence := versions.Sequence()
+		for _, name := range [][]byte{boltVersionsBucket, boltSnapshotsBucket, boltTimestampsBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(boltVersionsBucket).SetSequence(sequence)
+	})
+	return purged, err
+}
+
+func (s *Bolt) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+func (s *Bolt) Close() error {
+	return s.db.Close()
+}
+
+// encodes a version id as big endian so buckets iterate oldest first
+func encodeID(id int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
+
+func decodeID(b []byte) int64 {
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+// encodes a time as big endian unix milliseconds so keys sort by time
+func encodeMillis(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixMilli()))
+	return b
+}
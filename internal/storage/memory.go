@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"yard-backend/internal/models"
+)
+
+// keeps everything in process memory, for tests and local development
+// nothing survives a restart and replicas do not share data
+type Memory struct {
+	mu         sync.RWMutex
+	versions   map[int64]*memoryVersion
+	current    int64
+	lastID     int64
+	timestamps map[string]time.Time
+	history    map[string][]HistoryEntry
+}
+
+type memoryVersion struct {
+	publishedAt time.Time
+	stones      map[string]models.Item
+}
+
+// creates an empty in memory store
+func NewMemory() *Memory {
+	return &Memory{
+		versions:   make(map[int64]*memoryVersion),
+		timestamps: make(map[string]time.Time),
+		history:    make(map[string][]HistoryEntry),
+	}
+}
+
+// returns the stones of the current version, callers hold the lock
+func (s *Memory) stones() map[string]models.Item {
+	if version, ok := s.versions[s.current]; ok {
+		return version.stones
+	}
+	return nil
+}
+
+func (s *Memory) StoneIDs(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stones := s.stones()
+	ids := make([]string, 0, len(stones))
+	for id := range stones {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *Memory) HasStone(ctx context.Context, id string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.stones()[id]
+	return ok, nil
+}
+
+func (s *Memory) GetStone(ctx context.Context, id string) (models.Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stone, ok := s.stones()[id]
+	if !ok {
+		return models.Item{}, ErrNotFound
+	}
+	return stone, nil
+}
+
+func (s *Memory) GetStones(ctx context.Context, ids []string) ([]models.Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current := s.stones()
+	stones := make([]models.Item, 0, len(ids))
+	for _, id := range ids {
+		if stone, ok := current[id]; ok {
+			stones = append(stones, stone)
+		}
+	}
+	return stones, nil
+}
+
+func (s *Memory) AllStones(ctx context.Context) ([]models.Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	current := s.stones()
+	stones := make([]models.Item, 0, len(current))
+	for _, stone := range current {
+		stones = append(stones, stone)
+	}
+	sort.Slice(stones, func(i, j int) bool { return stones[i].ID < stones[j].ID })
+	return stones, nil
+}
+
+func (s *Memory) Publish(ctx context.Context, stones []models.Item, keep int) (Version, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	version := &memoryVersion{
+		publishedAt: time.UnixMilli(time.Now().UnixMilli()),
+		stones:      make(map[string]models.Item, len(stones)),
+	}
+	for _, stone := range stones {
+		version.stones[stone.ID] = stone
+	}
+	s.lastID++
+	s.versions[s.lastID] = version
+	s.current = s.lastID
+
+	for _, id := range prunable(s.versionIDs(), s.current, keep) {
+		delete(s.versions, id)
+	}
+	return Version{ID: s.current, PublishedAt: version.publishedAt, Stones: len(version.stones), Current: true}, nil
+}
+
+func (s *Memory) Versions(ctx context.Context) ([]Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := s.versionIDs()
+	versions := make([]Version, 0, len(ids))
+	for _, id := range ids {
+		version := s.versions[id]
+		versions = append(versions, Version{
+			ID:          id,
+			PublishedAt: version.publishedAt,
+			Stones:      len(version.stones),
+			Current:     id == s.current,
+		})
+	}
+	return versions, nil
+}
+
+func (s *Memory) Rollback(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.versions[id]; !ok {
+		return ErrNotFound
+	}
+	s.current = id
+	return nil
+}
+
+// returns the kept version ids newest first, callers hold the lock
+func (s *Memory) versionIDs() []int64 {
+	ids := make([]int64, 0, len(s.versions))
+	for id := range s.versions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	return ids
+}
+
+func (s *Memory) Timestamp(ctx context.Context, name string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.timestamps[name], nil
+}
+
+func (s *Memory) SetTimestamp(ctx context.Context, name string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timestamps[name] = time.UnixMilli(t.UnixMilli())
+	return nil
+}
+
+func (s *Memory) AppendHistory(ctx context.Context, series string, entry HistoryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry = HistoryEntry{
+		At:   time.UnixMilli(entry.At.UnixMilli()),
+		Data: append([]byte(nil), entry.Data...),
+	}
+	entries := s.history[series]
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].At.After(entry.At) })
+	entries = append(entries, HistoryEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	s.history[series] = entries
+	return nil
+}
+
+func (s *Memory) History(ctx context.Context, series string, since time.Time, limit int) ([]HistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cutoff := since.UnixMilli()
+	var entries []HistoryEntry
+	for _, entry := range s.history[series] {
+		if entry.At.UnixMilli() > cutoff {
+			entries = append(entries, entry)
+		}
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+func (s *Memory) TrimHistory(ctx context.Context, series string, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.history[series]
+	cutoff := before.UnixMilli()
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].At.UnixMilli() >= cutoff })
+	if i == len(entries) {
+		delete(s.history, series)
+		return nil
+	}
+	s.history[series] = append([]HistoryEntry(nil), entries[i:]...)
+	return nil
+}
+
+func (s *Memory) Purge(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := len(s.stones())
+	s.versions = make(map[int64]*memoryVersion)
+	s.current = 0
+	s.timestamps = make(map[string]time.Time)
+	return purged, nil
+}
+
+func (s *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Memory) Close() error {
+	return nil
+}
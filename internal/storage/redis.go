@@ -0,0 +1,644 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// points at the version readers see
+	redisCurrentKey = "reforge_stones:current"
+	// source of version ids, never reset so ids are not reused
+	redisVersionSeqKey = "reforge_stones:version_seq"
+	// version id to json version metadata
+	redisVersionsKey = "reforge_stones:versions"
+	// every stone of a version as json in one hash keyed by stone id
+	redisVersionDataPrefix = "reforge_stones:data:"
+	// refresh timestamps in unix milliseconds keyed by name
+	redisTimestampsKey = "reforge_stones:timestamps"
+	redisHistoryPrefix = "history:"
+	// bumped whenever the key layout changes so each migration runs exactly once
+	redisLayoutKey     = "reforge_stones:layout"
+	redisLayoutVersion = 3
+
+	// layout 2 kept a single unversioned stones hash
+	redisUnversionedKey = "reforge_stones:data"
+	// layout 1 kept one string key per stone next to a set of ids
+	redisLegacyIDsKey     = "reforge_stones:ids"
+	redisLegacyStoneKey   = "reforge_stone:"
+	redisLegacyCountKey   = "reforge_stones:count"
+	redisLegacyTimestamps = "reforge_stones:"
+)
+
+type redisVersion struct {
+	PublishedAt time.Time `json:"publishedAt"`
+	Stones      int       `json:"stones"`
+}
+
+// how often a fenced write is tried while lease renewals keep touching the watched key
+const fenceAttempts = 3
+
+// stores everything in redis, the only backend several replicas can share
+type Redis struct {
+	client *redis.Client
+	fence  atomic.Pointer[Fence]
+}
+
+// connects to redis, retrying for a while so the backend can start alongside redis
+func OpenRedis(ctx context.Context, cfg config.RedisConfig) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	maxRetries := 10
+	retryDelay := 2 * time.Second
+
+	var err error
+	for i := 0; i < maxRetries; i++ {
+		if err = client.Ping(ctx).Err(); err == nil {
+			log.Println("Connected to Redis successfully")
+			store := &Redis{client: client}
+			if err := store.Migrate(ctx); err != nil {
+				client.Close()
+				return nil, fmt.Errorf("migrating redis layout: %w", err)
+			}
+			return store, nil
+		}
+
+		if i < maxRetries-1 {
+			log.Printf("Failed to connect to Redis (attempt %d/%d): %v. Retrying in %v...", i+1, maxRetries, err, retryDelay)
+			select {
+			case <-ctx.Done():
+				client.Close()
+				return nil, ctx.Err()
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+
+	client.Close()
+	return nil, fmt.Errorf("connecting to redis at %s after %d attempts: %w", cfg.Addr(), maxRetries, err)
+}
+
+// wraps an existing client, used by tests and anything that already holds a connection
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+// returns the underlying client for redis only features such as leader election
+func (s *Redis) Client() *redis.Client {
+	return s.client
+}
+
+// makes every later write check the lease inside its own transaction, so a deposed leader can't write
+// between losing the lease and noticing, migrations run before any lease is held and stay unfenced
+func (s *Redis) SetFence(fence Fence) {
+	s.fence.Store(&fence)
+}
+
+// runs the writes of fn in one MULTI, watching the fence lease and comparing its value first when one is set
+func (s *Redis) tx(ctx context.Context, fn func(pipe redis.Pipeliner) error) error {
+	fence := s.fence.Load()
+	if fence == nil {
+		_, err := s.client.TxPipelined(ctx, fn)
+		return err
+	}
+
+	for attempt := 0; attempt < fenceAttempts; attempt++ {
+		key, value, ok := (*fence)()
+		if !ok {
+			return ErrFenced
+		}
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			current, err := tx.Get(ctx, key).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return err
+			}
+			if current != value {
+				return ErrFenced
+			}
+			_, err = tx.TxPipelined(ctx, fn)
+			return err
+		}, key)
+		// renewing the lease also aborts the transaction, the next attempt finds out whether it is still ours
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return ErrFenced
+}
+
+// returns the hash holding the current version, empty when nothing was published yet
+func (s *Redis) currentKey(ctx context.Context) (string, error) {
+	current, err := s.client.Get(ctx, redisCurrentKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return redisVersionDataPrefix + current, nil
+}
+
+func (s *Redis) StoneIDs(ctx context.Context) ([]string, error) {
+	key, err := s.currentKey(ctx)
+	if err != nil || key == "" {
+		return nil, err
+	}
+	ids, err := s.client.HKeys(ctx, key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *Redis) HasStone(ctx context.Context, id string) (bool, error) {
+	key, err := s.currentKey(ctx)
+	if err != nil || key == "" {
+		return false, err
+	}
+	return s.client.HExists(ctx, key, id).Result()
+}
+
+func (s *Redis) GetStone(ctx context.Context, id string) (models.Item, error) {
+	var stone models.Item
+	key, err := s.currentKey(ctx)
+	if err != nil {
+		return stone, err
+	}
+	if key == "" {
+		return stone, ErrNotFound
+	}
+	data, err := s.client.HGet(ctx, key, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return stone, ErrNotFound
+	}
+	if err != nil {
+		return stone, err
+	}
+	if err := json.Unmarshal(data, &stone); err != nil {
+		return stone, fmt.Errorf("decoding stone %s: %w", id, err)
+	}
+	return stone, nil
+}
+
+// fetches every requested stone with a single HMGET
+func (s *Redis) GetStones(ctx context.Context, ids []string) ([]models.Item, error) {
+	stones := make([]models.Item, 0, len(ids))
+	if len(ids) == 0 {
+		return stones, nil
+	}
+	key, err := s.currentKey(ctx)
+	if err != nil || key == "" {
+		return stones, err
+	}
+
+	values, err := s.client.HMGet(ctx, key, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		raw, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var stone models.Item
+		if err := json.Unmarshal([]byte(raw), &stone); err != nil {
+			log.Printf("Error decoding stone %s: %v", ids[i], err)
+			continue
+		}
+		stones = append(stones, stone)
+	}
+	return stones, nil
+}
+
+// fetches the whole catalog with a single HGETALL
+func (s *Redis) AllStones(ctx context.Context) ([]models.Item, error) {
+	key, err := s.currentKey(ctx)
+	if err != nil || key == "" {
+		return []models.Item{}, err
+	}
+	all, err := s.client.HGetAll(ctx, key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	stones := make([]models.Item, 0, len(all))
+	for id, raw := range all {
+		var stone models.Item
+		if err := json.Unmarshal([]byte(raw), &stone); err != nil {
+			log.Printf("Error decoding stone %s: %v", id, err)
+			continue
+		}
+		stones = append(stones, stone)
+	}
+	sort.Slice(stones, func(i, j int) bool { return stones[i].ID < stones[j].ID })
+	return stones, nil
+}
+
+// writes the new version hash and switches current in one MULTI so readers see either version whole
+func (s *Redis) Publish(ctx context.Context, stones []models.Item, keep int) (Version, error) {
+	fields := make([]interface{}, 0, len(stones)*2)
+	written := make(map[string]bool, len(stones))
+	for _, stone := range stones {
+		data, err := json.Marshal(stone)
+		if err != nil {
+			return Version{}, fmt.Errorf("encoding stone %s: %w", stone.ID, err)
+		}
+		fields = append(fields, stone.ID, data)
+		written[stone.ID] = true
+	}
+
+	id, err := s.client.Incr(ctx, redisVersionSeqKey).Result()
+	if err != nil {
+		return Version{}, err
+	}
+	meta := redisVersion{PublishedAt: time.UnixMilli(time.Now().UnixMilli()), Stones: len(written)}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return Version{}, err
+	}
+
+	field := strconv.FormatInt(id, 10)
+	err = s.tx(ctx, func(pipe redis.Pipeliner) error {
+		if len(fields) > 0 {
+			pipe.HSet(ctx, redisVersionDataPrefix+field, fields...)
+		}
+		pipe.HSet(ctx, redisVersionsKey, field, data)
+		pipe.Set(ctx, redisCurrentKey, field, 0)
+		return nil
+	})
+	if err != nil {
+		return Version{}, err
+	}
+
+	if err := s.prune(ctx, id, keep); err != nil {
+		log.Printf("Error pruning old versions: %v", err)
+	}
+	return Version{ID: id, PublishedAt: meta.PublishedAt, Stones: meta.Stones, Current: true}, nil
+}
+
+func (s *Redis) prune(ctx context.Context, current int64, keep int) error {
+	fields, err := s.client.HKeys(ctx, redisVersionsKey).Result()
+	if err != nil {
+		return err
+	}
+	ids := parseVersionIDs(fields)
+	prune := prunable(ids, current, keep)
+	if len(prune) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(prune))
+	fields = make([]string, len(prune))
+	for i, id := range prune {
+		fields[i] = strconv.FormatInt(id, 10)
+		keys[i] = redisVersionDataPrefix + fields[i]
+	}
+	return s.tx(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, keys...)
+		pipe.HDel(ctx, redisVersionsKey, fields...)
+		return nil
+	})
+}
+
+func (s *Redis) Versions(ctx context.Context) ([]Version, error) {
+	var all *redis.MapStringStringCmd
+	var current *redis.StringCmd
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		all = pipe.HGetAll(ctx, redisVersionsKey)
+		current = pipe.Get(ctx, redisCurrentKey)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	versions := make([]Version, 0, len(all.Val()))
+	for field, raw := range all.Val() {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		var meta redisVersion
+		if err := json.Unmarshal([]byte(raw), &meta); err != nil {
+			return nil, fmt.Errorf("decoding version %d: %w", id, err)
+		}
+		versions = append(versions, Version{
+			ID:          id,
+			PublishedAt: meta.PublishedAt,
+			Stones:      meta.Stones,
+			Current:     field == current.Val(),
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ID > versions[j].ID })
+	return versions, nil
+}
+
+func (s *Redis) Rollback(ctx context.Context, id int64) error {
+	field := strconv.FormatInt(id, 10)
+	exists, err := s.client.HExists(ctx, redisVersionsKey, field).Result()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return s.tx(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, redisCurrentKey, field, 0)
+		return nil
+	})
+}
+
+// parses version ids and orders them newest first
+func parseVersionIDs(fields []string) []int64 {
+	ids := make([]int64, 0, len(fields))
+	for _, field := range fields {
+		if id, err := strconv.ParseInt(field, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+	return ids
+}
+
+func (s *Redis) Timestamp(ctx context.Context, name string) (time.Time, error) {
+	raw, err := s.client.HGet(ctx, redisTimestampsKey, name).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	millis, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing timestamp %s: %w", name, err)
+	}
+	return time.UnixMilli(millis), nil
+}
+
+func (s *Redis) SetTimestamp(ctx context.Context, name string, t time.Time) error {
+	return s.tx(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, redisTimestampsKey, name, t.UnixMilli())
+		return nil
+	})
+}
+
+// history lives in a sorted set scored by time, members carry the time so equal payloads stay distinct
+func (s *Redis) AppendHistory(ctx context.Context, series string, entry HistoryEntry) error {
+	millis := entry.At.UnixMilli()
+	member := strconv.FormatInt(millis, 10) + ":" + string(entry.Data)
+	return s.tx(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, redisHistoryPrefix+series, redis.Z{Score: float64(millis), Member: member})
+		return nil
+	})
+}
+
+func (s *Redis) History(ctx context.Context, series string, since time.Time, limit int) ([]HistoryEntry, error) {
+	// newest first so a limit keeps the latest entries, turned around below
+	members, err := s.client.ZRangeArgs(ctx, redis.ZRangeArgs{
+		Key:     redisHistoryPrefix + series,
+		Start:   "(" + strconv.FormatInt(since.UnixMilli(), 10),
+		Stop:    "+inf",
+		ByScore: true,
+		Rev:     true,
+		Count:   int64(limit),
+	}).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	entries := make([]HistoryEntry, 0, len(members))
+	for i := len(members) - 1; i >= 0; i-- {
+		entry, err := parseHistoryMember(members[i])
+		if err != nil {
+			log.Printf("Skipping malformed history entry in %s: %v", series, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *Redis) TrimHistory(ctx context.Context, series string, before time.Time) error {
+	return s.tx(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(ctx, redisHistoryPrefix+series, "-inf", "("+strconv.FormatInt(before.UnixMilli(), 10))
+		return nil
+	})
+}
+
+func parseHistoryMember(member string) (HistoryEntry, error) {
+	prefix, data, ok := strings.Cut(member, ":")
+	if !ok {
+		return HistoryEntry{}, fmt.Errorf("missing time prefix")
+	}
+	millis, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+	return HistoryEntry{At: time.UnixMilli(millis), Data: []byte(data)}, nil
+}
+
+func (s *Redis) Purge(ctx context.Context) (int, error) {
+	key, err := s.currentKey(ctx)
+	if err != nil {
+		return 0, err
+	}
+	fields, err := s.client.HKeys(ctx, redisVersionsKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	keys := []string{redisVersionsKey, redisCurrentKey, redisTimestampsKey}
+	for _, field := range fields {
+		keys = append(keys, redisVersionDataPrefix+field)
+	}
+
+	var count *redis.IntCmd
+	err = s.tx(ctx, func(pipe redis.Pipeliner) error {
+		if key != "" {
+			count = pipe.HLen(ctx, key)
+		}
+		pipe.Del(ctx, keys...)
+		return nil
+	})
+	if err != nil || count == nil {
+		return 0, err
+	}
+	return int(count.Val()), nil
+}
+
+// upgrades older key layouts step by step, each step only touches keys of the layout before it
+// replicas may race through it, publishing the old hash is atomic so only one of them does
+func (s *Redis) Migrate(ctx context.Context) error {
+	version, err := s.client.Get(ctx, redisLayoutKey).Int()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if version >= redisLayoutVersion {
+		return nil
+	}
+
+	if version < 2 {
+		if err := s.migrateKeysToHash(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.migrateHashToVersion(ctx); err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisLayoutKey, redisLayoutVersion, 0).Err()
+}
+
+// moves layout 1 data (one key per stone, an id set and one key per timestamp) into the layout 2 hashes
+func (s *Redis) migrateKeysToHash(ctx context.Context) error {
+	ids, err := s.legacyStoneIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	legacyKeys := []string{redisLegacyIDsKey, redisLegacyCountKey}
+	stoneKeys := make([]string, len(ids))
+	for i, id := range ids {
+		stoneKeys[i] = redisLegacyStoneKey + id
+	}
+	legacyKeys = append(legacyKeys, stoneKeys...)
+
+	timestampNames := []string{HypixelUpdated, PricesUpdated}
+	timestampKeys := make([]string, len(timestampNames))
+	for i, name := range timestampNames {
+		timestampKeys[i] = redisLegacyTimestamps + name
+	}
+	legacyKeys = append(legacyKeys, timestampKeys...)
+
+	// watched so a replica whose reads predate another replica's migration doesn't write them back
+	migrated := 0
+	err = s.client.Watch(ctx, func(tx *redis.Tx) error {
+		var values []interface{}
+		if len(stoneKeys) > 0 {
+			var err error
+			if values, err = tx.MGet(ctx, stoneKeys...).Result(); err != nil {
+				return err
+			}
+		}
+		timestamps, err := tx.MGet(ctx, timestampKeys...).Result()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, value := range values {
+				if raw, ok := value.(string); ok {
+					pipe.HSetNX(ctx, redisUnversionedKey, ids[i], raw)
+					migrated++
+				}
+			}
+			for i, value := range timestamps {
+				if raw, ok := value.(string); ok {
+					pipe.HSetNX(ctx, redisTimestampsKey, timestampNames[i], raw)
+				}
+			}
+			pipe.Del(ctx, legacyKeys...)
+			return nil
+		})
+		return err
+	}, legacyKeys...)
+	if errors.Is(err, redis.TxFailedErr) {
+		// another replica moved the keys first
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if migrated > 0 {
+		log.Printf("Migrated %d stones from per stone keys", migrated)
+	}
+	return nil
+}
+
+// publishes the layout 2 stones hash as a new version in one step, a replica that finds
+// the hash already gone lost the race to another one and leaves the published version alone
+// MULTI would not do here, it keeps running the later commands when the rename fails
+var migrateHashScript = redis.NewScript(`
+local count = redis.call("HLEN", KEYS[1])
+if count == 0 then
+	return 0
+end
+local id = redis.call("INCR", KEYS[2])
+redis.call("RENAME", KEYS[1], ARGV[1] .. id)
+redis.call("HSET", KEYS[3], id, string.format('{"publishedAt":"%s","stones":%d}', ARGV[2], count))
+redis.call("SET", KEYS[4], id)
+return {id, count}
+`)
+
+// turns the single layout 2 stones hash into the first published version
+func (s *Redis) migrateHashToVersion(ctx context.Context) error {
+	publishedAt := time.UnixMilli(time.Now().UnixMilli()).Format(time.RFC3339Nano)
+	result, err := migrateHashScript.Run(ctx, s.client,
+		[]string{redisUnversionedKey, redisVersionSeqKey, redisVersionsKey, redisCurrentKey},
+		redisVersionDataPrefix, publishedAt,
+	).Result()
+	if err != nil {
+		return err
+	}
+
+	migrated, ok := result.([]interface{})
+	if !ok || len(migrated) != 2 {
+		return nil
+	}
+	log.Printf("Migrated %v stones into version %v", migrated[1], migrated[0])
+	return nil
+}
+
+// collects the ids from the old id set and any stray stone keys the set lost track of
+func (s *Redis) legacyStoneIDs(ctx context.Context) ([]string, error) {
+	members, err := s.client.SMembers(ctx, redisLegacyIDsKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(members))
+	for _, id := range members {
+		seen[id] = true
+	}
+	iter := s.client.Scan(ctx, 0, redisLegacyStoneKey+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		seen[strings.TrimPrefix(iter.Val(), redisLegacyStoneKey)] = true
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *Redis) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *Redis) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"yard-backend/internal/models"
+)
+
+func TestRedisMigrate_WhenLegacyKeysExist_MovesThemIntoHashes(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	mr.SAdd("reforge_stones:ids", "AMBER", "JADE")
+	mr.Set("reforge_stone:AMBER", `{"id":"AMBER","name":"Amber"}`)
+	mr.Set("reforge_stone:JADE", `{"id":"JADE","name":"Jade"}`)
+	mr.Set("reforge_stone:ORPHAN", `{"id":"ORPHAN","name":"Orphan"}`)
+	mr.Set("reforge_stones:count", "2")
+	mr.Set("reforge_stones:prices_updated", "1700000000000")
+	store := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer store.Close()
+
+	// Act
+	err := store.Migrate(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	stones, err := store.AllStones(ctx)
+	require.NoError(t, err)
+	require.Len(t, stones, 3)
+	assert.Equal(t, "Amber", stones[0].Name)
+	assert.Equal(t, "ORPHAN", stones[2].ID)
+
+	updated, err := store.Timestamp(ctx, PricesUpdated)
+	require.NoError(t, err)
+	assert.Equal(t, time.UnixMilli(1700000000000), updated)
+
+	for _, key := range []string{"reforge_stones:ids", "reforge_stone:AMBER", "reforge_stone:ORPHAN", "reforge_stones:count", "reforge_stones:prices_updated", "reforge_stones:data"} {
+		assert.False(t, mr.Exists(key), key)
+	}
+}
+
+func TestRedisMigrate_WhenUnversionedHashExists_PublishesItAsFirstVersion(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	mr.Set("reforge_stones:layout", "2")
+	mr.HSet("reforge_stones:data", "AMBER", `{"id":"AMBER","name":"Amber"}`)
+	store := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer store.Close()
+
+	// Act
+	err := store.Migrate(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	versions, err := store.Versions(ctx)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.True(t, versions[0].Current)
+	stone, err := store.GetStone(ctx, "AMBER")
+	require.NoError(t, err)
+	assert.Equal(t, "Amber", stone.Name)
+}
+
+func TestRedisMigrate_WhenReplicasMigrateTogether_PublishesOneReadableVersion(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	mr.SAdd("reforge_stones:ids", "AMBER", "JADE")
+	mr.Set("reforge_stone:AMBER", `{"id":"AMBER","name":"Amber"}`)
+	mr.Set("reforge_stone:JADE", `{"id":"JADE","name":"Jade"}`)
+	replicas := make([]*Redis, 8)
+	for i := range replicas {
+		replicas[i] = NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		defer replicas[i].Close()
+	}
+
+	// Act
+	errs := make([]error, len(replicas))
+	var wg sync.WaitGroup
+	for i, store := range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = store.Migrate(ctx)
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	versions, err := replicas[0].Versions(ctx)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.True(t, versions[0].Current)
+	stones, err := replicas[0].AllStones(ctx)
+	require.NoError(t, err)
+	assert.Len(t, stones, 2)
+	assert.False(t, mr.Exists("reforge_stones:data"))
+}
+
+func TestRedisMigrate_WhenAlreadyMigrated_LeavesDataAlone(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	store := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	defer store.Close()
+	require.NoError(t, store.Migrate(ctx))
+	mr.Set("reforge_stone:LATE", `{"id":"LATE"}`)
+
+	// Act
+	err := store.Migrate(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.True(t, mr.Exists("reforge_stone:LATE"))
+	ids, err := store.StoneIDs(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+// a fenced store and the lease it checks, held by "leader:1" to begin with
+func newFencedRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	mr.Set("lease", "leader:1")
+	store := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	t.Cleanup(func() { store.Close() })
+	store.SetFence(func() (string, string, bool) { return "lease", "leader:1", true })
+	return store, mr
+}
+
+func TestRedisWrite_WhenLeaseTakenOver_RejectsWithoutWriting(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store, mr := newFencedRedis(t)
+	require.NoError(t, store.SetTimestamp(ctx, PricesUpdated, time.UnixMilli(1000)))
+	mr.Set("lease", "other:2")
+
+	// Act
+	timestampErr := store.SetTimestamp(ctx, PricesUpdated, time.UnixMilli(2000))
+	_, publishErr := store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "Amber"}}, 1)
+	historyErr := store.AppendHistory(ctx, "prices:AMBER", HistoryEntry{At: time.UnixMilli(2000), Data: []byte(`{}`)})
+
+	// Assert
+	assert.ErrorIs(t, timestampErr, ErrFenced)
+	assert.ErrorIs(t, publishErr, ErrFenced)
+	assert.ErrorIs(t, historyErr, ErrFenced)
+	updated, err := store.Timestamp(ctx, PricesUpdated)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), updated.UnixMilli())
+	stones, err := store.AllStones(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stones)
+}
+
+func TestRedisWrite_WhenLeaseChangesMidTransaction_RejectsIt(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	store, mr := newFencedRedis(t)
+	other := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer other.Close()
+
+	// Act
+	err := store.tx(ctx, func(pipe redis.Pipeliner) error {
+		// the new leader takes over after the lease was compared but before the write is applied
+		require.NoError(t, other.Set(ctx, "lease", "other:2", 0).Err())
+		pipe.Set(ctx, "written", "yes", 0)
+		return nil
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrFenced)
+	assert.False(t, mr.Exists("written"))
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+)
+
+// returned when a stone or other record does not exist
+var ErrNotFound = errors.New("storage: not found")
+
+// returned by writes of a fenced store when the lease no longer carries the value of the writer
+var ErrFenced = errors.New("storage: write fence not held")
+
+// reports the key of the lease writes are fenced by and the value it must carry, ok is false while no lease is held
+type Fence func() (key, value string, ok bool)
+
+// names of the timestamps the scheduler records
+const (
+	HypixelUpdated = "hypixel_updated"
+	PricesUpdated  = "prices_updated"
+)
+
+// one record in a history series, times are kept to the millisecond
+type HistoryEntry struct {
+	At   time.Time
+	Data []byte
+}
+
+// one published set of stones
+type Version struct {
+	ID          int64     `json:"id"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Stones      int       `json:"stones"`
+	Current     bool      `json:"current"`
+}
+
+// persists cached stones, the set of known stone ids, refresh timestamps and history series
+// every backend behaves the same so services and handlers never need to know which one is in use
+// reads cost a fixed number of round trips however many stones are cached
+// stones are written as whole versioned snapshots and reads only ever see the current one
+type Store interface {
+	// returns the ids of every cached stone
+	StoneIDs(ctx context.Context) ([]string, error)
+	// reports whether a stone with this id is cached
+	HasStone(ctx context.Context, id string) (bool, error)
+	// returns a single stone or ErrNotFound
+	GetStone(ctx context.Context, id string) (models.Item, error)
+	// returns the stones for ids in the same order, skipping ids that are not cached
+	GetStones(ctx context.Context, ids []string) ([]models.Item, error)
+	// returns every cached stone ordered by id
+	AllStones(ctx context.Context) ([]models.Item, error)
+	// writes stones as a new version and switches current to it in one step
+	// so readers never see a half written set, the newest keep earlier versions stay for rollback
+	Publish(ctx context.Context, stones []models.Item, keep int) (Version, error)
+	// returns every kept version, newest first
+	Versions(ctx context.Context) ([]Version, error)
+	// points current back at a kept version, ErrNotFound once it was pruned
+	Rollback(ctx context.Context, id int64) error
+
+	// returns a named timestamp, the zero time when it was never set
+	Timestamp(ctx context.Context, name string) (time.Time, error)
+	SetTimestamp(ctx context.Context, name string, t time.Time) error
+
+	// appends an entry to a named history series
+	AppendHistory(ctx context.Context, series string, entry HistoryEntry) error
+	// returns the entries of a series recorded after since, oldest first
+	// a positive limit returns only the newest limit of them without reading the rest
+	History(ctx context.Context, series string, since time.Time, limit int) ([]HistoryEntry, error)
+	// drops the entries of a series recorded before before
+	TrimHistory(ctx context.Context, series string, before time.Time) error
+
+	// deletes every version and timestamp and returns how many stones were current
+	// history is kept so a purge does not lose long term data
+	Purge(ctx context.Context) (int, error)
+
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// opens the backend selected in the storage config
+func Open(ctx context.Context, cfg *config.Config) (Store, error) {
+	switch cfg.Storage.Backend {
+	case config.StorageRedis:
+		return OpenRedis(ctx, cfg.Redis)
+	case config.StorageMemory:
+		return NewMemory(), nil
+	case config.StorageBolt:
+		return OpenBolt(cfg.Storage.Path)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
+// returns the ids of the versions to delete so only current and the newest keep others remain
+// versions must be ordered newest first
+func prunable(versions []int64, current int64, keep int) []int64 {
+	var prune []int64
+	kept := 0
+	for _, id := range versions {
+		if id == current {
+			continue
+		}
+		if kept < keep {
+			kept++
+			continue
+		}
+		prune = append(prune, id)
+	}
+	return prune
+}
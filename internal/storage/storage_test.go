@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"yard-backend/internal/config"
+	"yard-backend/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runs the same checks against every backend so they stay interchangeable
+func forEachBackend(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("redis", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		store := NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		defer store.Close()
+		test(t, store)
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemory())
+	})
+	t.Run("bolt", func(t *testing.T) {
+		store, err := OpenBolt(filepath.Join(t.TempDir(), "yard.db"))
+		require.NoError(t, err)
+		defer store.Close()
+		test(t, store)
+	})
+}
+
+func TestStore_WhenStonesPublished_ReturnsThemByID(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		stones := []models.Item{
+			{ID: "AMBER", Name: "Amber"},
+			{ID: "JADE", Name: "Jade"},
+		}
+
+		// Act
+		version, err := store.Publish(ctx, stones, 1)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 2, version.Stones)
+		ids, err := store.StoneIDs(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"AMBER", "JADE"}, ids)
+
+		stone, err := store.GetStone(ctx, "JADE")
+		require.NoError(t, err)
+		assert.Equal(t, "Jade", stone.Name)
+
+		found, err := store.GetStones(ctx, []string{"JADE", "MISSING", "AMBER"})
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, "JADE", found[0].ID)
+		assert.Equal(t, "AMBER", found[1].ID)
+
+		known, err := store.HasStone(ctx, "AMBER")
+		require.NoError(t, err)
+		assert.True(t, known)
+	})
+}
+
+func TestStore_WhenNewVersionPublished_ReplacesTheWholeSetAndPrunesOldVersions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		for _, name := range []string{"first", "second", "third"} {
+			_, err := store.Publish(ctx, []models.Item{{ID: "AMBER", Name: name}, {ID: "JADE"}}, 1)
+			require.NoError(t, err)
+		}
+
+		// Act
+		latest, err := store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "fourth"}}, 1)
+
+		// Assert
+		require.NoError(t, err)
+		stones, err := store.AllStones(ctx)
+		require.NoError(t, err)
+		require.Len(t, stones, 1)
+		assert.Equal(t, "fourth", stones[0].Name)
+
+		versions, err := store.Versions(ctx)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, latest.ID, versions[0].ID)
+		assert.True(t, versions[0].Current)
+		assert.False(t, versions[1].Current)
+		assert.Equal(t, 2, versions[1].Stones)
+	})
+}
+
+func TestStore_WhenRolledBack_ServesThePreviousVersion(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		previous, err := store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "good"}}, 2)
+		require.NoError(t, err)
+		_, err = store.Publish(ctx, []models.Item{{ID: "AMBER", Name: "broken"}}, 2)
+		require.NoError(t, err)
+
+		// Act
+		err = store.Rollback(ctx, previous.ID)
+
+		// Assert
+		require.NoError(t, err)
+		stone, err := store.GetStone(ctx, "AMBER")
+		require.NoError(t, err)
+		assert.Equal(t, "good", stone.Name)
+		assert.ErrorIs(t, store.Rollback(ctx, previous.ID+100), ErrNotFound)
+	})
+}
+
+func TestStore_WhenStoneMissing_ReturnsErrNotFound(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Act
+		_, err := store.GetStone(context.Background(), "MISSING")
+
+		// Assert
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestStore_WhenTimestampSet_RoundTripsToTheMillisecond(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		at := time.Date(2026, 3, 1, 12, 30, 0, 123456789, time.UTC)
+
+		// Act
+		unset, err := store.Timestamp(ctx, PricesUpdated)
+		require.NoError(t, err)
+		require.NoError(t, store.SetTimestamp(ctx, PricesUpdated, at))
+		got, err := store.Timestamp(ctx, PricesUpdated)
+
+		// Assert
+		require.NoError(t, err)
+		assert.True(t, unset.IsZero())
+		assert.True(t, got.Equal(at.Truncate(time.Millisecond)))
+	})
+}
+
+func TestStore_WhenHistoryAppended_ReturnsEntriesAfterSinceInOrder(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, store.AppendHistory(ctx, "changes", HistoryEntry{At: base.Add(2 * time.Hour), Data: []byte(`{"n":3}`)}))
+		require.NoError(t, store.AppendHistory(ctx, "changes", HistoryEntry{At: base, Data: []byte(`{"n":1}`)}))
+		require.NoError(t, store.AppendHistory(ctx, "changes", HistoryEntry{At: base.Add(time.Hour), Data: []byte(`{"n":2}`)}))
+
+		// Act
+		entries, err := store.History(ctx, "changes", base, 0)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, `{"n":2}`, string(entries[0].Data))
+		assert.Equal(t, `{"n":3}`, string(entries[1].Data))
+		assert.True(t, entries[1].At.Equal(base.Add(2*time.Hour)))
+	})
+}
+
+func TestStore_WhenHistoryLimited_ReturnsNewestEntriesOldestFirst(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		for n := range 4 {
+			data := []byte(fmt.Sprintf(`{"n":%d}`, n))
+			require.NoError(t, store.AppendHistory(ctx, "prices:AMBER", HistoryEntry{At: base.Add(time.Duration(n) * time.Hour), Data: data}))
+		}
+
+		// Act
+		entries, err := store.History(ctx, "prices:AMBER", time.Time{}, 2)
+
+		// Assert
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, `{"n":2}`, string(entries[0].Data))
+		assert.Equal(t, `{"n":3}`, string(entries[1].Data))
+	})
+}
+
+func TestStore_WhenHistoryTrimmed_DropsOnlyOlderEntries(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		base := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+		for n := range 3 {
+			data := []byte(fmt.Sprintf(`{"n":%d}`, n))
+			require.NoError(t, store.AppendHistory(ctx, "prices:AMBER", HistoryEntry{At: base.Add(time.Duration(n) * time.Hour), Data: data}))
+		}
+		require.NoError(t, store.AppendHistory(ctx, "prices:JADE", HistoryEntry{At: base, Data: []byte(`{}`)}))
+
+		// Act
+		err := store.TrimHistory(ctx, "prices:AMBER", base.Add(time.Hour))
+
+		// Assert
+		require.NoError(t, err)
+		entries, err := store.History(ctx, "prices:AMBER", time.Time{}, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, `{"n":1}`, string(entries[0].Data))
+		other, err := store.History(ctx, "prices:JADE", time.Time{}, 0)
+		require.NoError(t, err)
+		assert.Len(t, other, 1)
+	})
+}
+
+func TestStore_WhenPurged_RemovesStonesAndTimestampsButKeepsHistory(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, store Store) {
+		// Arrange
+		ctx := context.Background()
+		_, err := store.Publish(ctx, []models.Item{{ID: "AMBER"}, {ID: "JADE"}}, 1)
+		require.NoError(t, err)
+		require.NoError(t, store.SetTimestamp(ctx, HypixelUpdated, time.Now()))
+		require.NoError(t, store.AppendHistory(ctx, "changes", HistoryEntry{At: time.Now(), Data: []byte("{}")}))
+
+		// Act
+		purged, err := store.Purge(ctx)
+
+		// Assert
+		require.NoError(t, err)
+		assert.Equal(t, 2, purged)
+		ids, err := store.StoneIDs(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+		updated, err := store.Timestamp(ctx, HypixelUpdated)
+		require.NoError(t, err)
+		assert.True(t, updated.IsZero())
+		history, err := store.History(ctx, "changes", time.Time{}, 0)
+		require.NoError(t, err)
+		assert.Len(t, history, 1)
+	})
+}
+
+func TestOpen_WhenBackendUnknown_ReturnsError(t *testing.T) {
+	// Arrange
+	cfg := config.Default()
+	cfg.Storage.Backend = "cassandra"
+
+	// Act
+	_, err := Open(context.Background(), cfg)
+
+	// Assert
+	assert.Error(t, err)
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"yard-backend/internal/config"
+)
+
+// installs a provider recording every span for the test, restoring the previous one afterwards
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup_WhenEndpointHasNoPath_ExportsToDefaultTracesPath(t *testing.T) {
+	// Arrange
+	var path atomic.Value
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		path.Store(r.URL.Path)
+	}))
+	defer collector.Close()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	cfg := config.TracingConfig{Endpoint: collector.URL, ServiceName: "yard-test", SampleRatio: 1}
+
+	// Act
+	shutdown, err := Setup(context.Background(), cfg)
+	require.NoError(t, err)
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	err = shutdown(context.Background())
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "/v1/traces", path.Load())
+	assert.Contains(t, otel.GetTextMapPropagator().Fields(), "traceparent")
+}
+
+func TestSampler_WhenParentSampled_FollowsParentWhateverTheRatio(t *testing.T) {
+	// Arrange
+	sampler := Sampler(0)
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+
+	// Act
+	sampled := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: trace.ContextWithSpanContext(context.Background(), parent),
+		TraceID:       parent.TraceID(),
+	})
+	fresh := sampler.ShouldSample(sdktrace.SamplingParameters{
+		ParentContext: context.Background(),
+		TraceID:       trace.TraceID{2},
+	})
+
+	// Assert
+	assert.Equal(t, sdktrace.RecordAndSample, sampled.Decision)
+	assert.Equal(t, sdktrace.Drop, fresh.Decision)
+}
+
+func TestRedisHook_WhenCommandsRun_StartsSpanPerCommandAndPipeline(t *testing.T) {
+	// Arrange
+	recorder := recordSpans(t)
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	require.NoError(t, client.Ping(context.Background()).Err())
+	client.AddHook(RedisHook())
+	ctx := context.Background()
+
+	// Act
+	client.Set(ctx, "k", "v", 0)
+	missing := client.Get(ctx, "missing").Err()
+	pipe := client.Pipeline()
+	pipe.Get(ctx, "k")
+	pipe.Get(ctx, "k")
+	_, err := pipe.Exec(ctx)
+
+	// Assert
+	require.NoError(t, err)
+	assert.ErrorIs(t, missing, redis.Nil)
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, "redis set", spans[0].Name())
+	assert.Equal(t, "redis get", spans[1].Name())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code, "a missing key is not an error")
+	assert.Equal(t, "redis pipeline", spans[2].Name())
+	assert.Contains(t, spans[2].Attributes(), attribute.Int("db.operation.batch.size", 2))
+}
@@ -0,0 +1,115 @@
+package upstream
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// returned when a host's circuit is open and calls are being short circuited
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// tracks consecutive failures for a single host and stops calls while it is unhealthy
+type Breaker struct {
+	mu               sync.Mutex
+	state            breakerState
+	failures         int
+	openedAt         time.Time
+	failureThreshold int
+	cooldown         time.Duration
+	probeInFlight    bool
+}
+
+// creates a breaker that opens after threshold consecutive failures and probes again after cooldown
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		failureThreshold: threshold,
+		cooldown:         cooldown,
+	}
+}
+
+// reports whether a call may proceed, letting a single probe through once the cooldown elapses
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = stateHalfOpen
+		b.probeInFlight = true
+		return nil
+	case stateHalfOpen:
+		if b.probeInFlight {
+			return ErrCircuitOpen
+		}
+		b.probeInFlight = true
+		return nil
+	}
+
+	return nil
+}
+
+// records a successful call and closes the circuit
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = stateClosed
+	b.failures = 0
+	b.probeInFlight = false
+}
+
+// records a failed call and opens the circuit when the threshold is reached or a probe fails
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+	if b.state == stateHalfOpen {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.failureThreshold {
+		b.state = stateOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// releases a probe slot without judging the host, used when the caller gave up on its own
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+}
+
+// returns the current state as a string for logs and health output
+func (b *Breaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
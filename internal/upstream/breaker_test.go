@@ -0,0 +1,52 @@
+package upstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker_WhenThresholdReached_RejectsCalls(t *testing.T) {
+	// Arrange
+	b := NewBreaker(2, time.Minute)
+
+	// Act
+	b.Failure()
+	b.Failure()
+
+	// Assert
+	assert.ErrorIs(t, b.Allow(), ErrCircuitOpen)
+	assert.Equal(t, "open", b.State())
+}
+
+func TestBreaker_WhenCooldownElapsed_AllowsSingleProbe(t *testing.T) {
+	// Arrange
+	b := NewBreaker(1, 10*time.Millisecond)
+	b.Failure()
+	time.Sleep(20 * time.Millisecond)
+
+	// Act
+	first := b.Allow()
+	second := b.Allow()
+
+	// Assert
+	assert.NoError(t, first)
+	assert.ErrorIs(t, second, ErrCircuitOpen)
+	assert.Equal(t, "half-open", b.State())
+}
+
+func TestBreaker_WhenProbeSucceeds_Closes(t *testing.T) {
+	// Arrange
+	b := NewBreaker(1, 10*time.Millisecond)
+	b.Failure()
+	time.Sleep(20 * time.Millisecond)
+	b.Allow()
+
+	// Act
+	b.Success()
+
+	// Assert
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, "closed", b.State())
+}
@@ -0,0 +1,257 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"yard-backend/internal/metrics"
+)
+
+const tracerName = "yard-backend/upstream"
+
+// options used to build a shared upstream client
+type Options struct {
+	RequestTimeout   time.Duration
+	FailureThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// returns sane defaults for talking to hypixel coflnet and the mojang texture server
+func DefaultOptions() Options {
+	return Options{
+		RequestTimeout:   10 * time.Second,
+		FailureThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
+}
+
+// shared http client with per request deadlines bounded retries and a breaker per host
+type Client struct {
+	http     *http.Client
+	opts     Options
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// creates a new upstream client
+func NewClient(opts Options) *Client {
+	return &Client{
+		http:     &http.Client{},
+		opts:     opts,
+		breakers: make(map[string]*Breaker),
+	}
+}
+
+// returns the breaker for a host creating it on first use
+func (c *Client) Breaker(host string) *Breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = NewBreaker(c.opts.FailureThreshold, c.opts.BreakerCooldown)
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// performs a get request following the retry policy
+// on success or a non retryable status the response is returned and the caller must close the body
+// when retries run out on a retryable status the last response is returned so callers can inspect it
+func (c *Client) Get(ctx context.Context, rawURL string, policy RetryPolicy) (*http.Response, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// one span per fetch covering every attempt and the waits between them
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "GET "+parsed.Host,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(http.MethodGet),
+			semconv.ServerAddress(parsed.Host),
+			semconv.URLPath(parsed.Path),
+		),
+	)
+	defer span.End()
+
+	resp, attempts, err := c.get(ctx, parsed, rawURL, policy)
+	span.SetAttributes(attribute.Int("upstream.attempts", attempts))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// runs the attempts of Get and reports how many requests were sent
+func (c *Client) get(ctx context.Context, parsed *url.URL, rawURL string, policy RetryPolicy) (*http.Response, int, error) {
+	breaker := c.Breaker(parsed.Host)
+
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, attempt - 1, err
+		}
+
+		if policy.Wait != nil {
+			if err := policy.Wait(ctx); err != nil {
+				return nil, attempt - 1, err
+			}
+		}
+
+		if err := breaker.Allow(); err != nil {
+			return nil, attempt - 1, fmt.Errorf("%s: %w", parsed.Host, err)
+		}
+
+		start := time.Now()
+		resp, err := c.do(ctx, rawURL)
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		metrics.ObserveUpstream(parsed.Host, status, time.Since(start))
+		if err != nil {
+			if ctx.Err() != nil {
+				breaker.Cancel()
+				return nil, attempt, ctx.Err()
+			}
+			breaker.Failure()
+			if attempt >= maxAttempts {
+				return nil, attempt, err
+			}
+			if err := sleep(ctx, policy.backoff(attempt)); err != nil {
+				return nil, attempt, err
+			}
+			continue
+		}
+
+		if resp.StatusCode >= 500 {
+			breaker.Failure()
+		} else {
+			breaker.Success()
+		}
+
+		if !policy.retryable(resp.StatusCode) || attempt >= maxAttempts {
+			return resp, attempt, nil
+		}
+
+		delay := policy.delayFor(resp, attempt)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if attempt >= policy.LogAfter && policy.LogAfter > 0 {
+			log.Printf("Upstream %s returned %d (attempt %d/%d), waiting %v", parsed.Host, resp.StatusCode, attempt, maxAttempts, delay)
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, attempt, err
+		}
+	}
+}
+
+// sends a single request bounded by the configured request timeout
+func (c *Client) do(ctx context.Context, rawURL string) (*http.Response, error) {
+	reqCtx, cancel := ctx, context.CancelFunc(func() {})
+	if c.opts.RequestTimeout > 0 {
+		reqCtx, cancel = context.WithTimeout(ctx, c.opts.RequestTimeout)
+	}
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// keep the deadline alive until the caller is done reading the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// releases the per request context once the body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// sleeps for d or until the context is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// parses a retry-after header which may be delta seconds or an http date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.Sub(now), true
+	}
+	return 0, false
+}
+
+// parses an x-ratelimit-reset header which may be an rfc3339 time or unix seconds
+func parseRateLimitReset(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Sub(now), true
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0).Sub(now), true
+	}
+	return 0, false
+}
@@ -0,0 +1,191 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+)
+
+func TestGet_WhenAlwaysRateLimited_StopsAfterMaxAttempts(t *testing.T) {
+	// Arrange
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(DefaultOptions())
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	// Act
+	resp, err := client.Get(context.Background(), server.URL, policy)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestGet_WhenRetryAfterPresent_HonoursHint(t *testing.T) {
+	// Arrange
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(DefaultOptions())
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second}
+
+	// Act
+	start := time.Now()
+	resp, err := client.Get(context.Background(), server.URL, policy)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+}
+
+func TestGet_WhenContextCancelled_ReturnsContextError(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := NewClient(DefaultOptions())
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// Act
+	_, err := client.Get(ctx, server.URL, policy)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestGet_WhenUpstreamHangs_TimesOutPerRequest(t *testing.T) {
+	// Arrange
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	opts := DefaultOptions()
+	opts.RequestTimeout = 50 * time.Millisecond
+	client := NewClient(opts)
+
+	// Act
+	start := time.Now()
+	_, err := client.Get(context.Background(), server.URL, NoRetryPolicy())
+
+	// Assert
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestGet_WhenHostKeepsFailing_OpensCircuit(t *testing.T) {
+	// Arrange
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	opts := DefaultOptions()
+	opts.FailureThreshold = 2
+	client := NewClient(opts)
+
+	// Act
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(context.Background(), server.URL, NoRetryPolicy())
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	_, err := client.Get(context.Background(), server.URL, NoRetryPolicy())
+
+	// Assert
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestParseRateLimitReset_WhenUnixSeconds_ReturnsDuration(t *testing.T) {
+	// Arrange
+	now := time.Unix(1000, 0)
+
+	// Act
+	d, ok := parseRateLimitReset("1030", now)
+
+	// Assert
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+}
+
+func TestGet_WhenTraced_RecordsOneSpanPerFetchAndPropagatesIt(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var calls int32
+	var traceparent atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("traceparent"))
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(DefaultOptions())
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+	// Act
+	resp, err := client.Get(context.Background(), server.URL+"/api/bazaar/x/snapshot", policy)
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET "+resp.Request.URL.Host, span.Name())
+	assert.Contains(t, span.Attributes(), attribute.Int("upstream.attempts", 2))
+	assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(http.StatusOK))
+	assert.Contains(t, traceparent.Load(), span.SpanContext().TraceID().String())
+}
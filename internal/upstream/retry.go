@@ -0,0 +1,96 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// describes how many times and how long to wait when retrying a request
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// statuses worth retrying, defaults to 429 and 5xx when empty
+	RetryOn []int
+	// called before every attempt, used for client side throttling
+	Wait func(ctx context.Context) error
+	// logs each retry once this many attempts have failed, zero disables logging
+	LogAfter int
+}
+
+// policy for the hypixel items endpoint which is cheap and rarely rate limited
+func HypixelPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// policy for coflnet endpoints which rate limit aggressively
+func CoflnetPolicy(wait func(ctx context.Context) error) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 6,
+		BaseDelay:   2 * time.Second,
+		MaxDelay:    60 * time.Second,
+		Wait:        wait,
+		LogAfter:    5,
+	}
+}
+
+// policy for one shot lookups made while serving a request
+func NoRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// reports whether a status code should be retried
+func (p RetryPolicy) retryable(status int) bool {
+	if len(p.RetryOn) == 0 {
+		return status == http.StatusTooManyRequests || status >= 500
+	}
+	for _, s := range p.RetryOn {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// exponential backoff capped at max delay
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// works out how long to wait before retrying, honouring server supplied hints over backoff
+func (p RetryPolicy) delayFor(resp *http.Response, attempt int) time.Duration {
+	delay := p.backoff(attempt)
+	now := time.Now()
+
+	if hint, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+		delay = hint
+	} else if hint, ok := parseRateLimitReset(resp.Header.Get("X-RateLimit-Reset"), now); ok && hint > 0 {
+		delay = hint
+	}
+
+	if delay < 0 {
+		delay = 0
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
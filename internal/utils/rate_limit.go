@@ -1,20 +1,39 @@
 package utils
 
 import (
+	"context"
+	"sync"
 	"time"
-	"yard-backend/internal/config"
 )
 
+// spaces outbound requests so they respect an upstream rate limit
+type Throttle struct {
+	mu       sync.Mutex
+	last     time.Time
+	minDelay time.Duration
+}
+
+// creates a throttle that keeps at least minDelay between requests
+func NewThrottle(minDelay time.Duration) *Throttle {
+	return &Throttle{minDelay: minDelay}
+}
+
 // waits to respect rate limits by ensuring minimum delay between requests
-func RateLimitWait() {
-	config.RateLimiterMutex.Lock()
-	defer config.RateLimiterMutex.Unlock()
-	
-	elapsed := time.Since(config.LastRequestTime)
-	if elapsed < config.MinRequestDelay {
-		sleepTime := config.MinRequestDelay - elapsed
-		time.Sleep(sleepTime)
+// gives up early when the context is cancelled
+func (t *Throttle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elapsed := time.Since(t.last)
+	if elapsed < t.minDelay {
+		timer := time.NewTimer(t.minDelay - elapsed)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
 	}
-	config.LastRequestTime = time.Now()
+	t.last = time.Now()
+	return nil
 }
-
@@ -1,11 +1,11 @@
 package utils
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
-	"yard-backend/internal/config"
 )
 
 func TestExtractObtainingFromLore_WhenLoreContainsObtained_ReturnsObtainingInfo(t *testing.T) {
@@ -47,25 +47,31 @@ func TestExtractMiningLevelFromLore_WhenLoreContainsMiningLevel_ReturnsLevel(t *
 	assert.Contains(t, result, "50")
 }
 
-func TestRateLimitWait_WhenElapsedTimeLessThanMinDelay_Waits(t *testing.T) {
+func TestThrottleWait_WhenCalledWithinMinDelay_Waits(t *testing.T) {
 	// Arrange
-	originalLastRequestTime := config.LastRequestTime
-	originalMinRequestDelay := config.MinRequestDelay
-	defer func() {
-		config.LastRequestTime = originalLastRequestTime
-		config.MinRequestDelay = originalMinRequestDelay
-	}()
-
-	config.MinRequestDelay = 50 * time.Millisecond
-	config.RateLimiterMutex.Lock()
-	config.LastRequestTime = time.Now().Add(-10 * time.Millisecond)
-	config.RateLimiterMutex.Unlock()
+	throttle := NewThrottle(50 * time.Millisecond)
+	throttle.Wait(context.Background())
 
 	// Act
 	start := time.Now()
-	RateLimitWait()
+	err := throttle.Wait(context.Background())
 	elapsed := time.Since(start)
 
 	// Assert
+	assert.NoError(t, err)
 	assert.GreaterOrEqual(t, elapsed, 35*time.Millisecond)
 }
+
+func TestThrottleWait_WhenContextCancelled_ReturnsError(t *testing.T) {
+	// Arrange
+	throttle := NewThrottle(time.Minute)
+	throttle.Wait(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	err := throttle.Wait(ctx)
+
+	// Assert
+	assert.ErrorIs(t, err, context.Canceled)
+}
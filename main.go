@@ -1,63 +1,376 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"yard-backend/internal/acl"
+	"yard-backend/internal/apikeys"
+	"yard-backend/internal/clientip"
 	"yard-backend/internal/config"
+	"yard-backend/internal/envelope"
+	"yard-backend/internal/geoip"
+	"yard-backend/internal/gql"
 	"yard-backend/internal/handlers"
+	"yard-backend/internal/jobs"
+	"yard-backend/internal/leader"
+	"yard-backend/internal/lifecycle"
 	"yard-backend/internal/metrics"
 	"yard-backend/internal/middleware"
+	"yard-backend/internal/openapi"
 	"yard-backend/internal/services"
+	"yard-backend/internal/storage"
+	"yard-backend/internal/tracing"
 )
 
 // main entry point initializes config redis resource pack and starts the http server
+// the server listens straight away and warm-up runs in the background
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a yaml config file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets masked and exit")
+	flag.Parse()
+
 	config.LoadEnv()
-	config.InitRedis()
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if *printConfig {
+		out, err := cfg.Dump()
+		if err != nil {
+			log.Fatalf("Failed to render configuration: %v", err)
+		}
+		fmt.Print(string(out))
+		return
+	}
+
+	// enabled before anything loads so startup work is recorded too
+	metrics.Init(cfg.Metrics.Enabled)
+	if cfg.Metrics.Enabled {
+		log.Println("Metrics collection enabled - Prometheus metrics available at /metrics")
+	}
+
+	// spans are only recorded and exported once a collector is configured
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.Tracing.Endpoint != "" {
+		if shutdownTracing, err = tracing.Setup(context.Background(), cfg.Tracing); err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+		log.Printf("Tracing enabled - exporting %.0f%% of new traces to %s", cfg.Tracing.SampleRatio*100, cfg.Tracing.Endpoint)
+	}
+
+	store, err := storage.Open(context.Background(), cfg)
+	if err != nil {
+		log.Fatalf("Failed to open %s storage: %v", cfg.Storage.Backend, err)
+	}
+	log.Printf("Using %s storage", cfg.Storage.Backend)
+	if redisStore, ok := store.(*storage.Redis); ok {
+		redisStore.Client().AddHook(metrics.RedisHook())
+		if cfg.Tracing.Endpoint != "" {
+			redisStore.Client().AddHook(tracing.RedisHook())
+		}
+	}
+	var market *metrics.MarketCollector
+	if cfg.Metrics.Enabled {
+		market = metrics.RegisterMarket(store, cfg.Metrics.MarketStones)
+	}
 	handlers.LoadResourcePack()
-	
-	if err := services.LoadNEUReforgeStones(); err != nil {
+
+	svc := services.New(cfg, store)
+	if err := svc.LoadNEUReforgeStones(); err != nil {
 		log.Printf("Warning: Failed to load NEU reforge stones: %v", err)
 	}
-	
-	if err := services.LoadNEUReforges(); err != nil {
+
+	if err := svc.LoadNEUReforges(); err != nil {
 		log.Printf("Warning: Failed to load NEU reforges: %v", err)
 	}
-	
-	services.StartScheduler()
 
-	metrics.Init(config.MetricsEnabled)
-	if config.MetricsEnabled {
-		log.Println("Metrics collection enabled - Prometheus metrics available at /metrics")
+	// storage may still be empty here, the first refresh rebuilds the responses again
+	if _, err := svc.RebuildReadModel(context.Background()); err != nil {
+		log.Printf("Warning: Failed to build read model: %v", err)
+	}
+
+	lc := lifecycle.NewManager()
+	h := handlers.New(cfg, svc)
+	h.ReadinessCheck = lc.IsReady
+	if h.GraphQL, err = gql.NewServer(svc, cfg.GraphQL.PersistedQueries); err != nil {
+		log.Fatalf("Failed to build GraphQL schema: %v", err)
+	}
+
+	// already checked by config validation
+	trustedProxies, _ := clientip.ParsePrefixes(cfg.Server.TrustedProxies)
+	clientip.SetTrustedProxies(trustedProxies)
+
+	// already checked by config validation
+	policy, _ := acl.ParsePolicy(cfg.ACLLists(), cfg.ACL.Groups)
+	access := middleware.NewAccessControl(policy)
+
+	geo := geoip.New(cfg.GeoIP.CacheSize)
+	defer geo.Close()
+	if err := geo.Open(cfg.GeoIP.CountryDB, cfg.GeoIP.ASNDB); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	metrics.SetGeoIP(geo)
+	metrics.SetMaxASNs(cfg.Metrics.MaxASNs)
+
+	rateLimiter := middleware.NewRateLimiter(cfg.API.RateLimitRequests, cfg.API.RateLimitWindow)
+	applyRateLimits(rateLimiter, cfg)
+
+	// keys and budgets only survive restarts and reach every replica when they live in redis
+	var keys apikeys.Store
+	if redisStore, ok := store.(*storage.Redis); ok {
+		keys = apikeys.NewRedis(redisStore.Client())
+		rateLimiter.Redis = redisStore.Client()
+	} else {
+		log.Printf("Warning: API keys are kept in memory with %s storage and are lost on restart", cfg.Storage.Backend)
+		keys = apikeys.NewMemory()
+	}
+	h.APIKeys = keys
+	rateLimiter.Keys = keys
+	stopCleanup := make(chan struct{})
+	defer close(stopCleanup)
+	go rateLimiter.RunCleanup(5*time.Minute, stopCleanup)
+	// picks up databases replaced in place, e.g. by geoipupdate
+	go geo.Watch(time.Minute, stopCleanup)
+
+	// live settings are pushed to every component that reads them, the rest is reported as needing a restart
+	reloader := config.NewReloader(*configPath, cfg)
+	reloader.OnReload(svc.Apply)
+	reloader.OnReload(h.Apply)
+	reloader.OnReload(func(cfg *config.Config) {
+		applyRateLimits(rateLimiter, cfg)
+	})
+	reloader.OnReload(func(cfg *config.Config) {
+		policy, _ := acl.ParsePolicy(cfg.ACLLists(), cfg.ACL.Groups)
+		access.Set(policy)
+	})
+	reloader.OnReload(func(cfg *config.Config) {
+		if err := geo.Open(cfg.GeoIP.CountryDB, cfg.GeoIP.ASNDB); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		metrics.SetMaxASNs(cfg.Metrics.MaxASNs)
+		if market != nil {
+			market.SetMaxStones(cfg.Metrics.MarketStones)
+		}
+	})
+	reloader.OnReload(func(cfg *config.Config) {
+		trustedProxies, _ := clientip.ParsePrefixes(cfg.Server.TrustedProxies)
+		clientip.SetTrustedProxies(trustedProxies)
+	})
+	h.ReloadConfig = reloader.Reload
+
+	server := &http.Server{
+		Addr:         cfg.Server.ListenAddr,
+		Handler:      newRouter(cfg, h, rateLimiter, access),
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	ctx, stopWaiting := context.WithCancel(context.Background())
+	defer stopWaiting()
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	h.Jobs = jobs.NewManager(jobsCtx, 100)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("YARD Backend server starting on %s", cfg.Server.ListenAddr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+			stopWaiting()
+		}
+	}()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Println("Received SIGHUP, reloading configuration...")
+				if _, err := reloader.Reload(); err != nil {
+					log.Printf("Config reload failed, keeping current configuration: %v", err)
+				}
+			}
+		}
+	}()
+
+	// every replica serves from memory, followers only learn about new data through this
+	go svc.WatchReadModel(ctx)
+
+	electionCtx, stopElection := context.WithCancel(ctx)
+	electionDone := make(chan struct{})
+	runScheduler := func(leaderCtx context.Context) {
+		scheduler := svc.StartScheduler(leaderCtx, lc.MarkReady)
+		<-leaderCtx.Done()
+		scheduler.Stop(context.Background())
+	}
+
+	if redisStore, ok := store.(*storage.Redis); ok {
+		// only the replica holding the lease runs the hypixel and price jobs, the rest serve reads
+		elector := leader.NewElector(redisStore.Client(), leader.Options{
+			LeaseTTL:      cfg.Scheduler.LeaseTTL,
+			RenewInterval: cfg.Scheduler.LeaseRenewInterval,
+		})
+		elector.OnChange(metrics.SetLeader)
+		svc.WriteFence = elector.Validate
+		redisStore.SetFence(elector.Lease)
+		h.LeaderCheck = elector.IsLeader
+
+		go func() {
+			defer close(electionDone)
+			elector.Run(electionCtx, runScheduler)
+		}()
+
+		// followers have nothing to warm up
+		go func() {
+			<-elector.Campaigned()
+			if !elector.IsLeader() {
+				lc.MarkReady()
+			}
+		}()
+	} else {
+		// local storage can't be shared so this instance always runs the scheduler
+		metrics.SetLeader(true, 0)
+		go func() {
+			defer close(electionDone)
+			runScheduler(electionCtx)
+		}()
+	}
+
+	lc.OnShutdown("stop scheduler and release leadership", func(ctx context.Context) error {
+		stopJobs()
+		stopElection()
+		select {
+		case <-electionDone:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	lc.OnShutdown("drain http connections", server.Shutdown)
+	lc.OnShutdown("close storage", func(ctx context.Context) error {
+		return store.Close()
+	})
+	lc.OnShutdown("flush traces", shutdownTracing)
+
+	if sig := lc.WaitForSignal(ctx, syscall.SIGINT, syscall.SIGTERM); sig != nil {
+		log.Printf("Received %v, shutting down...", sig)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	shutdownErr := lc.Shutdown(shutdownCtx)
+
+	select {
+	case err := <-serverErr:
+		log.Fatalf("HTTP server failed: %v", err)
+	default:
+	}
+	if shutdownErr != nil {
+		log.Fatalf("Shutdown finished with errors: %v", shutdownErr)
+	}
+	log.Println("Shutdown complete")
+}
+
+// pushes the tier budgets and route costs of cfg to the rate limiter
+func applyRateLimits(rateLimiter *middleware.RateLimiter, cfg *config.Config) {
+	rateLimiter.SetLimit(cfg.API.RateLimitRequests, cfg.API.RateLimitWindow)
+	rateLimiter.SetTierLimits(map[string]int{
+		apikeys.TierFree:    cfg.API.RateLimitFree,
+		apikeys.TierPartner: cfg.API.RateLimitPartner,
+	})
+	rateLimiter.SetRouteCosts(cfg.API.RouteCosts)
+}
+
+// registers every route on a new router
+func newRouter(cfg *config.Config, h *handlers.Handler, rateLimiter *middleware.RateLimiter, access *middleware.AccessControl) *mux.Router {
 	r := mux.NewRouter()
-	
-	if config.MetricsEnabled {
+
+	r.Use(middleware.RequestID)
+	if cfg.Tracing.Endpoint != "" {
+		// outermost after the request id so the span covers every other middleware
+		r.Use(middleware.Tracing)
+	}
+	if cfg.Metrics.Enabled {
 		r.Use(metrics.MetricsMiddleware)
 	}
-	
-	r.HandleFunc("/health", handlers.HandleHealth).Methods("GET")
-	r.HandleFunc("/api/reforge-stones", middleware.RateLimitMiddleware(handlers.HandleReforgeStones)).Methods("GET")
-	r.HandleFunc("/api/reforges", middleware.RateLimitMiddleware(handlers.HandleReforges)).Methods("GET")
-	r.HandleFunc("/api/item/{itemId}", middleware.RateLimitMiddleware(handlers.HandleItemImage)).Methods("GET")
-	r.HandleFunc("/api/item-data/{itemId}", middleware.RateLimitMiddleware(handlers.HandleItemImageByData)).Methods("GET")
-	
-	if config.MetricsEnabled {
+	// denied clients never reach the rate limiter or admin auth, metrics still counts the 403
+	r.Use(access.Middleware)
+	// registered after metrics so metrics sees the status the compressed response was sent with
+	r.Use(middleware.Compress)
+	// runs once the route is matched so parameters are checked against that route's spec
+	r.Use(middleware.ValidateParams(openapi.Spec()))
+
+	r.HandleFunc("/health", h.HandleHealth).Methods("GET")
+	r.HandleFunc("/ready", h.HandleReady).Methods("GET")
+	r.HandleFunc("/api/reforge-stones", rateLimiter.Middleware(h.HandleReforgeStones)).Methods("GET")
+	r.HandleFunc("/api/reforges", rateLimiter.Middleware(h.HandleReforges)).Methods("GET")
+	r.HandleFunc("/api/reforges/{name}/history", rateLimiter.Middleware(h.HandleReforgeHistory)).Methods("GET")
+	r.HandleFunc("/api/changes", rateLimiter.Middleware(h.HandleChanges)).Methods("GET")
+	r.HandleFunc("/api/changes.atom", rateLimiter.Middleware(h.HandleChangesFeed)).Methods("GET")
+	r.HandleFunc("/graphql", rateLimiter.Middleware(h.HandleGraphQL)).Methods("GET", "POST", "OPTIONS")
+	r.HandleFunc("/api/item/{itemId}", rateLimiter.Middleware(h.HandleItemImage)).Methods("GET")
+	r.HandleFunc("/api/item-data/{itemId}", rateLimiter.Middleware(h.HandleItemImageByData)).Methods("GET")
+
+	// v2 answers everything with the same envelope, unmatched routes included
+	// mux loses track of method mismatches on subrouters so a wrong method is reported as not found too
+	// it also skips router middleware for unmatched requests so they get their request id here
+	v2 := r.PathPrefix("/api/v2").Subrouter()
+	// routes only register GET, so cors preflights land here too and are answered before the 404
+	v2.NotFoundHandler = middleware.RequestID(h.Preflight(envelope.ErrorHandler(http.StatusNotFound, envelope.CodeRouteNotFound, "no such route")))
+	v2.HandleFunc("/reforge-stones", rateLimiter.MiddlewareV2(h.HandleV2ReforgeStones)).Methods("GET")
+	v2.HandleFunc("/reforges", rateLimiter.MiddlewareV2(h.HandleV2Reforges)).Methods("GET")
+	v2.HandleFunc("/reforges/{name}/history", rateLimiter.MiddlewareV2(h.HandleV2ReforgeHistory)).Methods("GET")
+	v2.HandleFunc("/changes", rateLimiter.MiddlewareV2(h.HandleV2Changes)).Methods("GET")
+	v2.HandleFunc("/item/{itemId}", rateLimiter.MiddlewareV2(h.HandleV2ItemImage)).Methods("GET")
+	v2.HandleFunc("/item-data/{itemId}", rateLimiter.MiddlewareV2(h.HandleV2ItemImageByData)).Methods("GET")
+
+	r.HandleFunc("/openapi.json", h.HandleOpenAPI).Methods("GET")
+	r.HandleFunc("/docs", h.HandleDocs).Methods("GET")
+
+	if cfg.Metrics.Enabled {
 		r.Handle("/metrics", metrics.GetHandler()).Methods("GET")
 	}
 
-	server := &http.Server{
-		Addr:         ":8080",
-		Handler:      r,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	// admin routes bypass the public rate limiter and are only registered when a token is configured
+	if token := cfg.Admin.Token; token != "" {
+		admin := r.PathPrefix("/admin").Subrouter()
+		admin.HandleFunc("/refresh/hypixel", middleware.AdminAuthMiddleware(token, h.HandleAdminRefreshHypixel)).Methods("POST")
+		admin.HandleFunc("/refresh/prices", middleware.AdminAuthMiddleware(token, h.HandleAdminRefreshPrices)).Methods("POST")
+		admin.HandleFunc("/refresh/prices/{stoneId}", middleware.AdminAuthMiddleware(token, h.HandleAdminRefreshPrices)).Methods("POST")
+		admin.HandleFunc("/reload/neu", middleware.AdminAuthMiddleware(token, h.HandleAdminReloadNEU)).Methods("POST")
+		admin.HandleFunc("/reload/config", middleware.AdminAuthMiddleware(token, h.HandleAdminReloadConfig)).Methods("POST")
+		admin.HandleFunc("/reload/resource-pack", middleware.AdminAuthMiddleware(token, h.HandleAdminReloadResourcePack)).Methods("POST")
+		admin.HandleFunc("/cache/purge", middleware.AdminAuthMiddleware(token, h.HandleAdminPurgeCache)).Methods("POST")
+		admin.HandleFunc("/snapshots", middleware.AdminAuthMiddleware(token, h.HandleAdminSnapshots)).Methods("GET")
+		admin.HandleFunc("/snapshots/{version}/rollback", middleware.AdminAuthMiddleware(token, h.HandleAdminRollback)).Methods("POST")
+		admin.HandleFunc("/jobs", middleware.AdminAuthMiddleware(token, h.HandleAdminJobs)).Methods("GET")
+		admin.HandleFunc("/jobs/{jobId}", middleware.AdminAuthMiddleware(token, h.HandleAdminJob)).Methods("GET")
+		if h.APIKeys != nil {
+			admin.HandleFunc("/api-keys", middleware.AdminAuthMiddleware(token, h.HandleAdminCreateAPIKey)).Methods("POST")
+			admin.HandleFunc("/api-keys", middleware.AdminAuthMiddleware(token, h.HandleAdminAPIKeys)).Methods("GET")
+			admin.HandleFunc("/api-keys/{keyId}/revoke", middleware.AdminAuthMiddleware(token, h.HandleAdminRevokeAPIKey)).Methods("POST")
+			admin.HandleFunc("/api-keys/{keyId}/usage", middleware.AdminAuthMiddleware(token, h.HandleAdminAPIKeyUsage)).Methods("GET")
+		}
+		log.Println("Admin API enabled at /admin")
 	}
 
-	log.Println("YARD Backend server starting on :8080")
-	log.Fatal(server.ListenAndServe())
+	// routes only register GET, so preflights of every other path end up here, /graphql answers its own
+	r.Methods("OPTIONS").HandlerFunc(h.HandlePreflight)
+
+	return r
 }
@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"yard-backend/internal/apikeys"
 	"yard-backend/internal/config"
+	"yard-backend/internal/gql"
 	"yard-backend/internal/handlers"
+	"yard-backend/internal/middleware"
 	"yard-backend/internal/models"
+	"yard-backend/internal/openapi"
+	"yard-backend/internal/services"
+	"yard-backend/internal/storage"
 	"yard-backend/internal/utils"
 
 	"github.com/gorilla/mux"
@@ -17,12 +25,17 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func newTestHandler() *handlers.Handler {
+	cfg := config.Default()
+	return handlers.New(cfg, services.New(cfg, storage.NewMemory()))
+}
+
 func TestHealthHandler(t *testing.T) {
 	req, err := http.NewRequest("GET", "/health", nil)
 	require.NoError(t, err)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handlers.HandleHealth)
+	handler := http.HandlerFunc(newTestHandler().HandleHealth)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
@@ -39,20 +52,20 @@ func TestHealthHandler(t *testing.T) {
 func TestEnableCORS(t *testing.T) {
 	rr := httptest.NewRecorder()
 	req := httptest.NewRequest("GET", "/", nil)
-	handlers.EnableCORS(rr, req)
+	newTestHandler().EnableCORS(rr, req)
 
 	assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
 	assert.Equal(t, "GET, OPTIONS", rr.Header().Get("Access-Control-Allow-Methods"))
-	assert.Equal(t, "Content-Type", rr.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "Content-Type, X-API-Key", rr.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After", rr.Header().Get("Access-Control-Expose-Headers"))
 }
 
 func TestRateLimitWait(t *testing.T) {
-	config.RateLimiterMutex.Lock()
-	config.LastRequestTime = time.Now().Add(-100 * time.Millisecond)
-	config.RateLimiterMutex.Unlock()
+	throttle := utils.NewThrottle(300 * time.Millisecond)
+	require.NoError(t, throttle.Wait(context.Background()))
 
 	start := time.Now()
-	utils.RateLimitWait()
+	require.NoError(t, throttle.Wait(context.Background()))
 	elapsed := time.Since(start)
 
 	assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
@@ -166,7 +179,7 @@ func TestHandleItemImage(t *testing.T) {
 			require.NoError(t, err)
 
 			router := mux.NewRouter()
-			router.HandleFunc("/api/item/{itemId}", handlers.HandleItemImage).Methods("GET")
+			router.HandleFunc("/api/item/{itemId}", newTestHandler().HandleItemImage).Methods("GET")
 			rr := httptest.NewRecorder()
 			router.ServeHTTP(rr, req)
 
@@ -180,7 +193,7 @@ func TestHandleItemImageByData(t *testing.T) {
 	require.NoError(t, err)
 
 	router := mux.NewRouter()
-	router.HandleFunc("/api/item-data/{itemId}", handlers.HandleItemImageByData).Methods("GET")
+	router.HandleFunc("/api/item-data/{itemId}", newTestHandler().HandleItemImageByData).Methods("GET")
 	rr := httptest.NewRecorder()
 	router.ServeHTTP(rr, req)
 
@@ -257,3 +270,205 @@ func TestReforgeEffect(t *testing.T) {
 	assert.Len(t, effect.RequiredRarities, 2)
 	assert.Equal(t, 1000, effect.ReforgeCosts["EPIC"])
 }
+
+// builds the real router with every optional route registered over an empty store
+func newSpecRouter(t *testing.T) *mux.Router {
+	cfg := config.Default()
+	cfg.Admin.Token = "secret"
+	cfg.Metrics.Enabled = true
+	svc := services.New(cfg, storage.NewMemory())
+	h := handlers.New(cfg, svc)
+	var err error
+	h.GraphQL, err = gql.NewServer(svc, 10)
+	require.NoError(t, err)
+	h.APIKeys = apikeys.NewMemory()
+	rateLimiter := middleware.NewRateLimiter(1000, time.Minute)
+	rateLimiter.Keys = h.APIKeys
+	return newRouter(cfg, h, rateLimiter, middleware.NewAccessControl(nil))
+}
+
+func TestOpenAPISpec_DocumentsEveryRegisteredRoute(t *testing.T) {
+	router := newSpecRouter(t)
+	spec := openapi.Spec()
+
+	registered := make(map[string]bool)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// the /admin prefix route only hands over to its subrouter
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue
+			}
+			registered[method+" "+path] = true
+			assert.NotNil(t, spec.Operation(method, path), "%s %s is not in the spec", method, path)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	for path, item := range spec.Paths {
+		for method := range item {
+			key := strings.ToUpper(method) + " " + path
+			assert.True(t, registered[key], "%s is in the spec but not registered", key)
+		}
+	}
+}
+
+func TestOpenAPISpec_MatchesHandlerResponses(t *testing.T) {
+	router := newSpecRouter(t)
+	spec := openapi.Spec()
+
+	tests := []struct {
+		method   string
+		url      string
+		template string
+		body     string
+		status   int
+	}{
+		{"GET", "/health", "/health", "", http.StatusOK},
+		{"GET", "/ready", "/ready", "", http.StatusOK},
+		{"GET", "/api/reforge-stones", "/api/reforge-stones", "", http.StatusOK},
+		{"GET", "/api/reforges", "/api/reforges", "", http.StatusOK},
+		{"GET", "/api/reforges/Nope/history", "/api/reforges/{name}/history", "", http.StatusNotFound},
+		{"GET", "/api/changes?since=0", "/api/changes", "", http.StatusOK},
+		{"GET", "/api/changes?since=yesterday", "/api/changes", "", http.StatusBadRequest},
+		{"GET", "/api/changes.atom", "/api/changes.atom", "", http.StatusOK},
+		{"GET", "/graphql?query=%7Bstats%7Bkey%7D%7D", "/graphql", "", http.StatusOK},
+		{"POST", "/graphql", "/graphql", `{"query":"{ reforges { name stone { id auctionPrice } } }"}`, http.StatusOK},
+		{"POST", "/graphql", "/graphql", `not json`, http.StatusBadRequest},
+		{"GET", "/api/item/UNKNOWN_ITEM", "/api/item/{itemId}", "", http.StatusNotFound},
+		{"GET", "/api/item/%3Cscript%3E", "/api/item/{itemId}", "", http.StatusBadRequest},
+		{"GET", "/api/item-data/UNKNOWN_ITEM", "/api/item-data/{itemId}", "", http.StatusNotFound},
+		{"GET", "/api/v2/reforge-stones", "/api/v2/reforge-stones", "", http.StatusOK},
+		{"GET", "/api/v2/reforges", "/api/v2/reforges", "", http.StatusOK},
+		{"GET", "/api/v2/reforges/Nope/history", "/api/v2/reforges/{name}/history", "", http.StatusNotFound},
+		{"GET", "/api/v2/changes", "/api/v2/changes", "", http.StatusOK},
+		{"GET", "/api/v2/changes?until=soon", "/api/v2/changes", "", http.StatusBadRequest},
+		{"GET", "/api/v2/item/UNKNOWN_ITEM", "/api/v2/item/{itemId}", "", http.StatusNotFound},
+		{"GET", "/api/v2/item-data/UNKNOWN_ITEM", "/api/v2/item-data/{itemId}", "", http.StatusNotFound},
+		{"GET", "/openapi.json", "/openapi.json", "", http.StatusOK},
+		{"GET", "/docs", "/docs", "", http.StatusOK},
+		{"GET", "/metrics", "/metrics", "", http.StatusOK},
+		{"GET", "/admin/snapshots", "/admin/snapshots", "", http.StatusOK},
+		{"GET", "/admin/jobs", "/admin/jobs", "", http.StatusOK},
+		{"GET", "/admin/jobs/missing", "/admin/jobs/{jobId}", "", http.StatusNotFound},
+		{"POST", "/admin/snapshots/0/rollback", "/admin/snapshots/{version}/rollback", "", http.StatusBadRequest},
+		{"POST", "/admin/reload/config", "/admin/reload/config", "", http.StatusNotImplemented},
+		{"POST", "/admin/api-keys", "/admin/api-keys", `{"name":"community site","tier":"free"}`, http.StatusCreated},
+		{"POST", "/admin/api-keys", "/admin/api-keys", `{"name":"community site","tier":"gold"}`, http.StatusBadRequest},
+		{"GET", "/admin/api-keys", "/admin/api-keys", "", http.StatusOK},
+		{"POST", "/admin/api-keys/missing/revoke", "/admin/api-keys/{keyId}/revoke", "", http.StatusNotFound},
+		{"GET", "/admin/api-keys/missing/usage", "/admin/api-keys/{keyId}/usage", "", http.StatusNotFound},
+		{"GET", "/admin/api-keys/missing/usage?days=0", "/admin/api-keys/{keyId}/usage", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer secret")
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			assert.NoError(t, spec.ValidateResponse(tt.method, tt.template, rr.Code, rr.Header().Get("Content-Type"), rr.Body.Bytes()))
+		})
+	}
+}
+
+func TestV2Routes_WhenRouteUnknown_AnswerWithEnvelope(t *testing.T) {
+	router := newSpecRouter(t)
+
+	tests := []struct {
+		method string
+		url    string
+		status int
+		code   string
+	}{
+		{"GET", "/api/v2/nothing-here", http.StatusNotFound, "route_not_found"},
+		{"DELETE", "/api/v2/reforges", http.StatusNotFound, "route_not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tt.method, tt.url, nil))
+
+			assert.Equal(t, tt.status, rr.Code)
+			var response models.Envelope
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+			require.NotNil(t, response.Error)
+			assert.Equal(t, tt.code, response.Error.Code)
+			assert.NotEmpty(t, response.Error.RequestID)
+			assert.Equal(t, rr.Header().Get("X-Request-ID"), response.Error.RequestID)
+		})
+	}
+}
+
+func TestPreflight_WhenBrowserAsksToSendAPIKey_AllowsIt(t *testing.T) {
+	router := newSpecRouter(t)
+
+	for _, path := range []string{"/api/reforges", "/api/item/DRAGON_CLAW", "/api/v2/reforges", "/api/v2/item-data/DRAGON_CLAW"} {
+		req := httptest.NewRequest("OPTIONS", path, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", "GET")
+		req.Header.Set("Access-Control-Request-Headers", "X-API-Key")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusNoContent, rr.Code, path)
+		assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"), path)
+		assert.Contains(t, rr.Header().Get("Access-Control-Allow-Headers"), apikeys.Header, path)
+		assert.Contains(t, rr.Header().Get("Access-Control-Allow-Methods"), "GET", path)
+	}
+}
+
+func TestAPIKeys_WhenIssuedThroughAdmin_AreAcceptedUntilRevoked(t *testing.T) {
+	router := newSpecRouter(t)
+	spec := openapi.Spec()
+	serve := func(method, url, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("POST", "/admin/api-keys", `{"name":"community site","tier":"partner"}`, "")
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var created models.APIKeyCreatedResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &created))
+
+	assert.Equal(t, http.StatusOK, serve("GET", "/api/reforges", "", created.Token).Code)
+	require.Equal(t, http.StatusOK, serve("POST", "/admin/api-keys/"+created.Key.ID+"/revoke", "", "").Code)
+
+	for _, route := range []string{"/api/reforges", "/api/v2/reforges"} {
+		rr = serve("GET", route, "", created.Token)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, route)
+		assert.NoError(t, spec.ValidateResponse("GET", route, rr.Code, rr.Header().Get("Content-Type"), rr.Body.Bytes()))
+	}
+
+	rr = serve("GET", "/admin/api-keys/"+created.Key.ID+"/usage", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, spec.ValidateResponse("GET", "/admin/api-keys/{keyId}/usage", rr.Code, rr.Header().Get("Content-Type"), rr.Body.Bytes()))
+	var report models.APIKeyUsageResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	require.Len(t, report.Days, 1)
+	assert.Equal(t, int64(1), report.Days[0].Routes["/api/reforges"].Requests)
+}
+
+func TestDefaultRouteCosts_NameDocumentedRoutes(t *testing.T) {
+	spec := openapi.Spec()
+	for route := range config.Default().API.RouteCosts {
+		assert.Contains(t, spec.Paths, route)
+	}
+}